@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"bugtracker-backend/internal/config"
+	"bugtracker-backend/internal/db"
+	"bugtracker-backend/internal/models"
+)
+
+func auditVerify(store db.Store, cfg config.Config, args []string) error {
+	entries, err := store.ListAudit(db.AuditQuery{})
+	if err != nil {
+		return err
+	}
+	if err := models.VerifyAuditChain(entries); err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		log.Println("Audit log is empty")
+		return nil
+	}
+	log.Printf("Verified %d audit entries; head hash %s", len(entries), entries[len(entries)-1].Hash)
+	return nil
+}
+
+func auditExport(store db.Store, cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("audit-export", flag.ExitOnError)
+	out := flags.String("o", "", "write to this file instead of standard output")
+	after := flags.Int("after", 0, "export only the entries after this sequence number")
+	flags.Parse(args)
+
+	entries, err := store.ListAudit(db.AuditQuery{AfterSeq: *after})
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return writeAuditJSONL(os.Stdout, entries)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := writeAuditJSONL(f, entries); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	log.Printf("Exported %d audit entries to %s", len(entries), *out)
+	return nil
+}
+
+func writeAuditJSONL(w io.Writer, entries []models.AuditEntry) error {
+	enc := json.NewEncoder(w)
+	for i := range entries {
+		if err := enc.Encode(&entries[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Command bugtracker-admin runs maintenance tasks against the configured
+// store. It reads the same DB_BACKEND and DB_PATH settings as the server and
+// should be run while the server is stopped when using the bolt backend,
+// which allows only one process to open the file.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"bugtracker-backend/internal/config"
+	"bugtracker-backend/internal/db"
+)
+
+type command struct {
+	usage string
+	run   func(store db.Store, cfg config.Config, args []string) error
+}
+
+var commands = map[string]command{
+	"audit-export": {
+		usage: "write the audit log as JSON Lines; -o names a file, -after skips earlier entries",
+		run:   auditExport,
+	},
+	"audit-verify": {
+		usage: "check the audit log's hash chain and print its head hash",
+		run:   auditVerify,
+	},
+	"purge-orphans": {
+		usage: "delete comments left behind by deleted bugs; -dry-run only lists them",
+		run:   purgeOrphans,
+	},
+	"reindex": {
+		usage: "rebuild the full-text search index from the stored bugs and comments",
+		run:   reindex,
+	},
+	"repair": {
+		usage: "report bugs that fail validation; with -fix, repair them",
+		run:   repair,
+	},
+	"user-add": {
+		usage: "create a user; the password is read from standard input, -name sets the display name",
+		run:   userAdd,
+	},
+}
+
+func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+	store, err := db.Open(cfg.StorageBackend, cfg.DatabasePath)
+	if err != nil {
+		log.Fatalf("Failed to open %s storage at %s: %v", cfg.StorageBackend, cfg.DatabasePath, err)
+	}
+	defer store.Close()
+
+	if err := cmd.run(store, cfg, os.Args[2:]); err != nil {
+		store.Close()
+		log.Fatalf("%s: %v", os.Args[1], err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: bugtracker-admin <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", name, commands[name].usage)
+	}
+}
+
+func reindex(store db.Store, cfg config.Config, args []string) error {
+	count, err := store.RebuildSearchIndex()
+	if err != nil {
+		return err
+	}
+	log.Printf("Reindexed %d bugs", count)
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"bugtracker-backend/internal/config"
+	"bugtracker-backend/internal/db"
+)
+
+func purgeOrphans(store db.Store, cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("purge-orphans", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only list the orphaned comments")
+	flags.Parse(args)
+
+	orphans, err := store.OrphanedComments()
+	if err != nil {
+		return err
+	}
+	for _, c := range orphans {
+		log.Printf("Comment %d on bug %d by %s at %s", c.ID, c.BugID, c.Author, c.CreatedAt.Format("2006-01-02 15:04:05"))
+	}
+	if len(orphans) == 0 {
+		log.Println("No orphaned comments")
+		return nil
+	}
+	if *dryRun {
+		log.Printf("Found %d orphaned comments; run without -dry-run to delete them", len(orphans))
+		return nil
+	}
+
+	count, err := store.PurgeOrphanedComments()
+	if err != nil {
+		return err
+	}
+	log.Printf("Deleted %d orphaned comments", count)
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"bugtracker-backend/internal/config"
+	"bugtracker-backend/internal/db"
+	"bugtracker-backend/internal/models"
+	"bugtracker-backend/internal/workflow"
+)
+
+func repair(store db.Store, cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("repair", flag.ExitOnError)
+	fix := flags.Bool("fix", false, "write the repaired bugs back instead of only reporting them")
+	flags.Parse(args)
+
+	wf, err := workflow.LoadOrDefault(cfg.WorkflowPath)
+	if err != nil {
+		return err
+	}
+
+	do := store.View
+	if *fix {
+		do = store.Update
+	}
+
+	var invalid, repaired int
+	err = do(func(tx db.Tx) error {
+		bugs, err := tx.GetAllBugs()
+		if err != nil {
+			return err
+		}
+		for _, bug := range bugs {
+			verr := bug.Validate(wf)
+			if verr == nil {
+				continue
+			}
+			invalid++
+			log.Printf("Bug %d: %v", bug.ID, verr)
+
+			old := *bug
+			changes := repairBug(bug, wf)
+			for _, c := range changes {
+				log.Printf("  %s", c)
+			}
+			if err := bug.Validate(wf); err != nil {
+				log.Printf("  cannot repair automatically: %v", err)
+				continue
+			}
+			if !*fix {
+				continue
+			}
+			if err := tx.UpdateBug(bug); err != nil {
+				return fmt.Errorf("bug %d: %w", bug.ID, err)
+			}
+			err := tx.AddHistory(&models.HistoryEntry{
+				BugID:   bug.ID,
+				Action:  models.HistoryUpdated,
+				Actor:   "bugtracker-admin repair",
+				At:      bug.UpdatedAt,
+				Changes: models.DiffBugs(&old, bug),
+			})
+			if err != nil {
+				return fmt.Errorf("bug %d: %w", bug.ID, err)
+			}
+			repaired++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case invalid == 0:
+		log.Println("All bugs are valid")
+	case *fix:
+		log.Printf("Repaired %d of %d invalid bugs", repaired, invalid)
+	default:
+		log.Printf("Found %d invalid bugs; run with -fix to repair them", invalid)
+	}
+	return nil
+}
+
+// repairBug brings the fields of bug that fail validation back into range and
+// describes each change. Values that differ only in case from a valid one are
+// corrected to it; other unknown priorities and statuses fall back to the
+// defaults and unknown resolutions are dropped.
+func repairBug(bug *models.Bug, wf *workflow.Workflow) []string {
+	var changes []string
+	set := func(field string, dest *string, value string) {
+		changes = append(changes, fmt.Sprintf("%s: %q -> %q", field, *dest, value))
+		*dest = value
+	}
+
+	if strings.TrimSpace(bug.Title) == "" {
+		set("title", &bug.Title, fmt.Sprintf("Untitled bug #%d", bug.ID))
+	}
+
+	if !contains(models.Priorities, bug.Priority) {
+		set("priority", &bug.Priority, matchFold(models.Priorities, bug.Priority, models.DefaultPriority))
+	}
+
+	if !wf.HasStatus(bug.Status) {
+		names := make([]string, len(wf.States))
+		for i, s := range wf.States {
+			names[i] = s.Name
+		}
+		set("status", &bug.Status, matchFold(names, bug.Status, wf.Initial))
+	}
+
+	if bug.Resolution != "" {
+		if !wf.IsResolved(bug.Status) {
+			set("resolution", &bug.Resolution, "")
+		} else if !models.IsValidResolution(bug.Resolution) {
+			set("resolution", &bug.Resolution, matchFold(models.Resolutions, bug.Resolution, ""))
+		}
+	}
+	if !wf.IsResolved(bug.Status) && bug.ResolvedAt != nil {
+		changes = append(changes, "resolved_at: cleared")
+		bug.ResolvedAt = nil
+	}
+	return changes
+}
+
+// matchFold returns the entry of values equal to s ignoring case, or
+// fallback.
+func matchFold(values []string, s, fallback string) string {
+	for _, v := range values {
+		if strings.EqualFold(v, strings.TrimSpace(s)) {
+			return v
+		}
+	}
+	return fallback
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"bugtracker-backend/internal/config"
+	"bugtracker-backend/internal/db"
+	"bugtracker-backend/internal/models"
+	"bugtracker-backend/internal/workflow"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepairBug(t *testing.T) {
+	wf := workflow.Default()
+	resolvedAt := time.Now()
+
+	tests := []struct {
+		name     string
+		bug      models.Bug
+		expected models.Bug
+		changes  []string
+	}{
+		{
+			name:     "Valid bug is unchanged",
+			bug:      models.Bug{ID: 1, Title: "Crash", Status: "Open", Priority: "High"},
+			expected: models.Bug{ID: 1, Title: "Crash", Status: "Open", Priority: "High"},
+		},
+		{
+			name:     "Case is corrected",
+			bug:      models.Bug{ID: 2, Title: "Crash", Status: "in progress", Priority: "HIGH"},
+			expected: models.Bug{ID: 2, Title: "Crash", Status: "In Progress", Priority: "High"},
+			changes:  []string{`priority: "HIGH" -> "High"`, `status: "in progress" -> "In Progress"`},
+		},
+		{
+			name:     "Unknown values fall back to defaults",
+			bug:      models.Bug{ID: 3, Status: "Triaged", Priority: "urgent"},
+			expected: models.Bug{ID: 3, Title: "Untitled bug #3", Status: "Open", Priority: "Medium"},
+			changes: []string{
+				`title: "" -> "Untitled bug #3"`,
+				`priority: "urgent" -> "Medium"`,
+				`status: "Triaged" -> "Open"`,
+			},
+		},
+		{
+			name: "Resolution on unresolved bug is cleared",
+			bug: models.Bug{
+				ID: 4, Title: "Crash", Status: "Open", Priority: "Low",
+				Resolution: models.ResolutionFixed, ResolvedAt: &resolvedAt,
+			},
+			expected: models.Bug{ID: 4, Title: "Crash", Status: "Open", Priority: "Low"},
+			changes:  []string{`resolution: "Fixed" -> ""`, "resolved_at: cleared"},
+		},
+		{
+			name: "Resolution case is corrected",
+			bug: models.Bug{
+				ID: 5, Title: "Crash", Status: "Closed", Priority: "Low",
+				Resolution: "won't fix", ResolvedAt: &resolvedAt,
+			},
+			expected: models.Bug{
+				ID: 5, Title: "Crash", Status: "Closed", Priority: "Low",
+				Resolution: models.ResolutionWontFix, ResolvedAt: &resolvedAt,
+			},
+			changes: []string{`resolution: "won't fix" -> "Won't Fix"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bug := tt.bug
+			changes := repairBug(&bug, wf)
+			assert.Equal(t, tt.changes, changes)
+			assert.Equal(t, tt.expected, bug)
+			assert.NoError(t, bug.Validate(wf))
+		})
+	}
+}
+
+func TestRepair(t *testing.T) {
+	store, cleanup := db.SetupTestStore(t, config.BackendMemory)
+	defer cleanup()
+
+	assert.NoError(t, store.CreateBug(&models.Bug{Title: "Valid", Status: "Open", Priority: "Low"}))
+	assert.NoError(t, store.CreateBug(&models.Bug{Title: "Invalid", Status: "open", Priority: "urgent"}))
+
+	// A report leaves the data alone.
+	assert.NoError(t, repair(store, config.Config{}, nil))
+	bug, err := store.GetBug(2)
+	assert.NoError(t, err)
+	assert.Equal(t, "urgent", bug.Priority)
+
+	assert.NoError(t, repair(store, config.Config{}, []string{"-fix"}))
+	bug, err = store.GetBug(2)
+	assert.NoError(t, err)
+	assert.Equal(t, "Open", bug.Status)
+	assert.Equal(t, models.DefaultPriority, bug.Priority)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"bugtracker-backend/internal/auth"
+	"bugtracker-backend/internal/config"
+	"bugtracker-backend/internal/db"
+	"bugtracker-backend/internal/models"
+)
+
+// userAdd creates a user account, reading its password from the first line
+// of standard input so that it does not appear in the process list.
+func userAdd(store db.Store, cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("user-add", flag.ExitOnError)
+	name := flags.String("name", "", "display name")
+	role := flags.String("role", models.DefaultRole, "role: "+strings.Join(models.Roles, ", "))
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("usage: user-add [-name display-name] [-role role] username < password")
+	}
+
+	password, err := readPassword(os.Stdin)
+	if err != nil {
+		return err
+	}
+	user, err := addUser(store, flags.Arg(0), *name, *role, password)
+	if err != nil {
+		return err
+	}
+	log.Printf("Created %s %d %q", user.Role, user.ID, user.Username)
+	return nil
+}
+
+func readPassword(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// addUser validates and stores a new user as POST /api/users would.
+func addUser(store db.Store, username, displayName, role, password string) (*models.User, error) {
+	req := models.CreateUserRequest{Username: username, DisplayName: displayName, Role: role, Password: password}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.User{Username: req.Username, DisplayName: req.DisplayName, Role: req.Role, PasswordHash: hash}
+	return user, store.CreateUser(user)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"bugtracker-backend/internal/auth"
+	"bugtracker-backend/internal/config"
+	"bugtracker-backend/internal/db"
+	"bugtracker-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddUser(t *testing.T) {
+	store, cleanup := db.SetupTestStore(t, config.BackendMemory)
+	defer cleanup()
+
+	password, err := readPassword(strings.NewReader("correct horse\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "correct horse", password)
+
+	user, err := addUser(store, "Root", "Administrator", models.RoleAdmin, password)
+	assert.NoError(t, err)
+	assert.Equal(t, "root", user.Username)
+
+	stored, err := store.GetUserByName("root")
+	assert.NoError(t, err)
+	assert.Equal(t, "Administrator", stored.DisplayName)
+	assert.Equal(t, models.RoleAdmin, stored.Role)
+	assert.True(t, auth.CheckPassword(stored.PasswordHash, "correct horse"))
+
+	_, err = addUser(store, "root", "", models.DefaultRole, password)
+	assert.ErrorIs(t, err, db.ErrUserExists)
+	_, err = addUser(store, "short", "", models.DefaultRole, "pw")
+	assert.Error(t, err)
+	_, err = addUser(store, "owner", "", "owner", password)
+	assert.Error(t, err)
+}
@@ -6,11 +6,16 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
+	"bugtracker-backend/internal/config"
 	"bugtracker-backend/internal/db"
 	"bugtracker-backend/internal/handlers"
+	"bugtracker-backend/internal/models"
+	"bugtracker-backend/internal/oidc"
+	"bugtracker-backend/internal/workflow"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
@@ -20,13 +25,27 @@ func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 	log.Println("Starting Bug Tracker backend server...")
 
-	if err := db.Init(); err != nil {
+	cfg := config.Load()
+	log.Printf("Using %s storage at %s", cfg.StorageBackend, cfg.DatabasePath)
+
+	store, err := db.Open(cfg.StorageBackend, cfg.DatabasePath)
+	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
-	defer db.Cleanup()
+	defer store.Close()
+
+	wf, err := workflow.LoadOrDefault(cfg.WorkflowPath)
+	if err != nil {
+		log.Fatalf("Failed to load workflow: %v", err)
+	}
+
+	// Purge the trash in the background until shutdown
+	purgeCtx, stopPurging := context.WithCancel(context.Background())
+	defer stopPurging()
+	go purgeTrashPeriodically(purgeCtx, store, cfg.TrashRetention, trashPurgeInterval)
 
 	// Create the production server
-	srv := createServer()
+	srv := createServer(store, wf, cfg)
 
 	// Channel to listen for errors coming from the listener
 	serverErrors := make(chan error, 1)
@@ -62,8 +81,37 @@ func main() {
 	}
 }
 
+// trashPurgeInterval is how often expired trash is purged.
+const trashPurgeInterval = time.Hour
+
+// purgeTrashPeriodically permanently deletes bugs and comment contents that
+// have been in the trash for longer than retention, once at startup and then every interval,
+// until ctx is cancelled. A zero retention keeps the trash forever.
+func purgeTrashPeriodically(ctx context.Context, store db.Store, retention, interval time.Duration) {
+	if retention == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		count, err := store.PurgeTrash(time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Failed to purge trash: %v", err)
+		} else if count > 0 {
+			log.Printf("Purged %d bugs and comments from the trash", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // Production server creation
-func createServer() *http.Server {
+func createServer(store db.Store, wf *workflow.Workflow, cfg config.Config) *http.Server {
 	r := mux.NewRouter()
 
 	// Apply CORS middleware to all routes
@@ -73,9 +121,9 @@ func createServer() *http.Server {
 			"https://bugtracker-staging-jameswillett.fly.dev",
 			"https://bugtracker-jameswillett.fly.dev",
 		},
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders: []string{"*"},
-		ExposedHeaders: []string{"Content-Length"},
+		ExposedHeaders: []string{"Content-Length", "ETag", "X-Next-Cursor"},
 		AllowCredentials: true,
 	})
 
@@ -85,7 +133,18 @@ func createServer() *http.Server {
 	// Register all routes
 	r.HandleFunc("/api/health", handlers.HealthCheck).Methods("GET")
 	apiRouter := r.PathPrefix("/api").Subrouter()
-	handlers.RegisterRoutes(apiRouter)
+	h := handlers.NewHandler(store, wf)
+	h.SetAdmins(cfg.Admins)
+	h.SetSessionTTL(cfg.SessionTTL)
+	if cfg.OIDC.Issuer != "" {
+		h.SetSSO(newSSO(cfg.OIDC))
+		log.Printf("Single sign-on enabled with %s", cfg.OIDC.Issuer)
+	}
+	// Middleware runs in the order given: writes are audited first, so
+	// that requests refused by Authenticate, or by Authorize checking the
+	// user's role against the permission matrix, are audited too.
+	apiRouter.Use(h.AuditWrites, h.Authenticate, h.Authorize)
+	h.RegisterRoutes(apiRouter)
 
 	log.Printf("Starting server on :8080")
 	return &http.Server{
@@ -93,3 +152,30 @@ func createServer() *http.Server {
 		Handler: handler,
 	}
 }
+
+// newSSO builds the single sign-on settings, dropping group mappings to
+// roles that do not exist.
+func newSSO(cfg config.OIDCConfig) *handlers.SSO {
+	roleGroups := make(map[string]string)
+	for group, role := range cfg.RoleGroups {
+		if !slices.Contains(models.Roles, role) {
+			log.Printf("Ignoring OIDC_ROLE_GROUPS entry %s=%s: unknown role", group, role)
+			continue
+		}
+		roleGroups[group] = role
+	}
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       cfg.Issuer,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.Scopes,
+	}, &http.Client{Timeout: 10 * time.Second})
+	return &handlers.SSO{
+		Provider:      provider,
+		UsernameClaim: cfg.UsernameClaim,
+		GroupsClaim:   cfg.GroupsClaim,
+		RoleGroups:    roleGroups,
+		LoginRedirect: cfg.LoginRedirect,
+	}
+}
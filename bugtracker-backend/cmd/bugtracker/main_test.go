@@ -4,12 +4,18 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"bugtracker-backend/internal/config"
+	"bugtracker-backend/internal/db"
 	"bugtracker-backend/internal/handlers"
+	"bugtracker-backend/internal/models"
 	"bugtracker-backend/internal/testutil"
+	"bugtracker-backend/internal/workflow"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
@@ -226,3 +232,44 @@ func createTestServer() *http.Server {
 		Handler: handler,
 	}
 }
+
+func TestPurgeTrashPeriodically(t *testing.T) {
+	store := db.NewMemoryStore()
+	defer store.Close()
+
+	now := time.Now()
+	for i, age := range []time.Duration{48 * time.Hour, time.Hour} {
+		bug := &models.Bug{Title: fmt.Sprintf("Bug %d", i)}
+		assert.NoError(t, store.CreateBug(bug))
+		assert.NoError(t, store.TrashBug(bug.ID, "", now.Add(-age)))
+	}
+
+	// With the context already cancelled it purges once and returns.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	purgeTrashPeriodically(ctx, store, 24*time.Hour, time.Hour)
+
+	trash, _, err := store.ListBugs(db.BugQuery{Trashed: true})
+	assert.NoError(t, err)
+	if assert.Len(t, trash, 1) {
+		assert.Equal(t, 2, trash[0].ID)
+	}
+}
+
+func TestServerAuditsRefusedWrites(t *testing.T) {
+	store := db.NewMemoryStore()
+	defer store.Close()
+	server := createServer(store, workflow.Default(), config.Config{})
+
+	req := httptest.NewRequest("POST", "/api/bugs", strings.NewReader(`{"title": "Crash"}`))
+	w := httptest.NewRecorder()
+	server.Handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	entries, err := store.ListAudit(db.AuditQuery{Action: "POST /api/bugs"})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "", entries[0].Actor)
+		assert.Equal(t, http.StatusUnauthorized, entries[0].Status)
+	}
+}
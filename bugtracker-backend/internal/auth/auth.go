@@ -0,0 +1,101 @@
+// Package auth hashes passwords and session tokens and carries the
+// authenticated user through a request's context.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"bugtracker-backend/internal/models"
+)
+
+// HashPassword returns the bcrypt hash of password.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches a hash made by
+// HashPassword.
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// dummyHash is compared against when a login names an unknown user, so
+// that the response takes as long as for a wrong password.
+var dummyHash, _ = HashPassword("not a password")
+
+// CheckNoPassword spends the time of a failed CheckPassword.
+func CheckNoPassword(password string) {
+	CheckPassword(dummyHash, password)
+}
+
+// NewToken returns a random token to hand to a client. Only its HashToken
+// is stored.
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// APITokenPrefix starts every API token, telling them apart from session
+// tokens.
+const APITokenPrefix = "btk_"
+
+// NewAPIToken returns a random API token.
+func NewAPIToken() (string, error) {
+	token, err := NewToken()
+	if err != nil {
+		return "", err
+	}
+	return APITokenPrefix + token, nil
+}
+
+// HashToken returns the form in which a token is stored and looked up.
+// Tokens are random, so a fast hash is enough.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+type (
+	userKey  struct{}
+	scopeKey struct{}
+)
+
+// WithUser returns a context carrying the authenticated user.
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the user stored by WithUser, or nil for an
+// anonymous request.
+func UserFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(userKey{}).(*models.User)
+	return user
+}
+
+// WithScope returns a context limited to the scope of the API token a
+// request was made with.
+func WithScope(ctx context.Context, scope string) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFromContext returns the scope stored by WithScope. Requests made
+// with a login session are not limited and have the admin scope.
+func ScopeFromContext(ctx context.Context) string {
+	if scope, ok := ctx.Value(scopeKey{}).(string); ok {
+		return scope
+	}
+	return models.ScopeAdmin
+}
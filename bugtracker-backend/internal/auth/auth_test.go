@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"bugtracker-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswords(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "correct horse", hash)
+	assert.True(t, CheckPassword(hash, "correct horse"))
+	assert.False(t, CheckPassword(hash, "battery staple"))
+	assert.False(t, CheckPassword("not a hash", "correct horse"))
+}
+
+func TestTokens(t *testing.T) {
+	a, err := NewToken()
+	assert.NoError(t, err)
+	b, err := NewToken()
+	assert.NoError(t, err)
+	assert.NotEqual(t, a, b)
+	assert.Len(t, a, 43)
+
+	assert.Equal(t, HashToken(a), HashToken(a))
+	assert.NotEqual(t, HashToken(a), HashToken(b))
+	assert.NotContains(t, HashToken(a), a)
+}
+
+func TestUserContext(t *testing.T) {
+	assert.Nil(t, UserFromContext(context.Background()))
+
+	user := &models.User{ID: 1, Username: "alice"}
+	assert.Equal(t, user, UserFromContext(WithUser(context.Background(), user)))
+}
@@ -0,0 +1,270 @@
+package bugquery
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"bugtracker-backend/internal/models"
+)
+
+// fieldParser turns the value of a field:value term into an Expr. The
+// comparison operator, if any, has already been split off.
+type fieldParser func(p *parser, tok token, op, value string) (Expr, error)
+
+var fields map[string]fieldParser
+
+func init() {
+	fields = map[string]fieldParser{
+		"id":          parseIntField(func(b *models.Bug) int { return b.ID }, strconv.Atoi),
+		"project":     parseListField(func(b *models.Bug) string { return b.Project }),
+		"status":      parseListField(func(b *models.Bug) string { return b.Status }),
+		"priority":    parseIntField(func(b *models.Bug) int { return models.PriorityRank(b.Priority) }, parsePriority),
+		"title":       parseTextField(func(b *models.Bug) string { return b.Title }),
+		"description": parseTextField(func(b *models.Bug) string { return b.Description }),
+		"resolution":  parseListField(func(b *models.Bug) string { return b.Resolution }),
+		"reopens":     parseIntField(func(b *models.Bug) int { return b.ReopenCount }, strconv.Atoi),
+		"created":     parseDateField(func(b *models.Bug) *time.Time { return &b.CreatedAt }),
+		"updated":     parseDateField(func(b *models.Bug) *time.Time { return &b.UpdatedAt }),
+		"resolved":    parseDateField(func(b *models.Bug) *time.Time { return b.ResolvedAt }),
+	}
+	fields["created_at"] = fields["created"]
+	fields["updated_at"] = fields["updated"]
+	fields["resolved_at"] = fields["resolved"]
+	fields["reopen_count"] = fields["reopens"]
+}
+
+// comparisonOps are checked in order, so two-character operators come
+// first.
+var comparisonOps = []string{">=", "<=", ">", "<"}
+
+func (p *parser) parseTerm(tok token) (Expr, error) {
+	if tok.field == "" {
+		return textMatch(tok.value, func(b *models.Bug) string { return b.Title + "\n" + b.Description }), nil
+	}
+
+	parse, ok := fields[strings.ToLower(tok.field)]
+	if !ok {
+		return nil, errorAt(p.input, tok, "unknown field %q", tok.field)
+	}
+
+	value, op := tok.value, ""
+	for _, candidate := range comparisonOps {
+		if strings.HasPrefix(value, candidate) {
+			value, op = value[len(candidate):], candidate
+			break
+		}
+	}
+	if value == "" {
+		return nil, errorAt(p.input, tok, "missing value for %s", tok.field)
+	}
+	if op != "" && strings.Contains(value, "..") {
+		return nil, errorAt(p.input, tok, "a range cannot be combined with %s", op)
+	}
+	return parse(p, tok, op, value)
+}
+
+func textMatch(needle string, text func(b *models.Bug) string) Expr {
+	needle = strings.ToLower(needle)
+	return matchFunc(func(b *models.Bug) bool {
+		return strings.Contains(strings.ToLower(text(b)), needle)
+	})
+}
+
+// parseTextField matches a case-insensitive substring.
+func parseTextField(text func(b *models.Bug) string) fieldParser {
+	return func(p *parser, tok token, op, value string) (Expr, error) {
+		// Comparisons make no sense for text, so treat them literally.
+		return textMatch(op+value, text), nil
+	}
+}
+
+// parseListField matches one of a comma separated list of values, ignoring
+// case.
+func parseListField(field func(b *models.Bug) string) fieldParser {
+	return func(p *parser, tok token, op, value string) (Expr, error) {
+		if op != "" || strings.Contains(value, "..") {
+			return nil, errorAt(p.input, tok, "%s does not support comparisons", strings.ToLower(tok.field))
+		}
+		values := strings.Split(value, ",")
+		return matchFunc(func(b *models.Bug) bool {
+			for _, v := range values {
+				if strings.EqualFold(field(b), v) {
+					return true
+				}
+			}
+			return false
+		}), nil
+	}
+}
+
+func parsePriority(s string) (int, error) {
+	for _, name := range models.Priorities {
+		if strings.EqualFold(name, s) {
+			return models.PriorityRank(name), nil
+		}
+	}
+	return 0, strconv.ErrSyntax
+}
+
+// parseIntField handles fields with ordered integer keys: a list of values
+// (a,b,c), a comparison (>=a) or an inclusive range (a..b).
+func parseIntField(key func(b *models.Bug) int, parseValue func(string) (int, error)) fieldParser {
+	return func(p *parser, tok token, op, value string) (Expr, error) {
+		parseOne := func(s string) (int, error) {
+			n, err := parseValue(s)
+			if err != nil {
+				return 0, errorAt(p.input, tok, "invalid %s %q", strings.ToLower(tok.field), s)
+			}
+			return n, nil
+		}
+
+		if lo, hi, ok := strings.Cut(value, ".."); ok {
+			from, err := parseOne(lo)
+			if err != nil {
+				return nil, err
+			}
+			to, err := parseOne(hi)
+			if err != nil {
+				return nil, err
+			}
+			return matchFunc(func(b *models.Bug) bool {
+				k := key(b)
+				return k >= from && k <= to
+			}), nil
+		}
+
+		if op == "" {
+			var values []int
+			for _, s := range strings.Split(value, ",") {
+				n, err := parseOne(s)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, n)
+			}
+			return matchFunc(func(b *models.Bug) bool {
+				k := key(b)
+				for _, v := range values {
+					if k == v {
+						return true
+					}
+				}
+				return false
+			}), nil
+		}
+
+		n, err := parseOne(value)
+		if err != nil {
+			return nil, err
+		}
+		return matchFunc(func(b *models.Bug) bool {
+			k := key(b)
+			switch op {
+			case ">":
+				return k > n
+			case ">=":
+				return k >= n
+			case "<":
+				return k < n
+			default:
+				return k <= n
+			}
+		}), nil
+	}
+}
+
+var relativeDate = regexp.MustCompile(`^(\d+)([hdw])$`)
+
+var relativeUnits = map[string]time.Duration{
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// parseDate resolves a date value to the half-open interval [from, to) it
+// denotes. Days (YYYY-MM-DD, "today", "yesterday") are UTC days; timestamps
+// and relative ages such as "7d" are single instants.
+func parseDate(s string, now time.Time) (from, to time.Time, relative bool, ok bool) {
+	if m := relativeDate.FindStringSubmatch(s); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return from, to, false, false
+		}
+		from = now.Add(-time.Duration(n) * relativeUnits[m[2]])
+		return from, from.Add(time.Nanosecond), true, true
+	}
+
+	today := now.UTC().Truncate(24 * time.Hour)
+	switch strings.ToLower(s) {
+	case "today":
+		return today, today.AddDate(0, 0, 1), false, true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), today, false, true
+	}
+
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, t.AddDate(0, 0, 1), false, true
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, t.Add(time.Nanosecond), false, true
+	}
+	return from, to, false, false
+}
+
+// parseDateField handles created, updated and resolved. A bare relative age
+// such as updated:7d means "within the last seven days". Bugs without the
+// date, such as unresolved ones, never match.
+func parseDateField(key func(b *models.Bug) *time.Time) fieldParser {
+	return func(p *parser, tok token, op, value string) (Expr, error) {
+		parseOne := func(s string) (time.Time, time.Time, bool, error) {
+			from, to, relative, ok := parseDate(s, p.now)
+			if !ok {
+				return from, to, false, errorAt(p.input, tok, "invalid date %q", s)
+			}
+			return from, to, relative, nil
+		}
+
+		if lo, hi, ok := strings.Cut(value, ".."); ok {
+			from, _, _, err := parseOne(lo)
+			if err != nil {
+				return nil, err
+			}
+			_, to, _, err := parseOne(hi)
+			if err != nil {
+				return nil, err
+			}
+			return matchFunc(func(b *models.Bug) bool {
+				t := key(b)
+				return t != nil && !t.Before(from) && t.Before(to)
+			}), nil
+		}
+
+		from, to, relative, err := parseOne(value)
+		if err != nil {
+			return nil, err
+		}
+		if op == "" && relative {
+			op = ">="
+		}
+		return matchFunc(func(b *models.Bug) bool {
+			t := key(b)
+			if t == nil {
+				return false
+			}
+			switch op {
+			case ">":
+				return !t.Before(to)
+			case ">=":
+				return !t.Before(from)
+			case "<":
+				return t.Before(from)
+			case "<=":
+				return t.Before(to)
+			default:
+				return !t.Before(from) && t.Before(to)
+			}
+		}), nil
+	}
+}
@@ -0,0 +1,140 @@
+// Package bugquery parses and evaluates the compact query language accepted
+// by GET /bugs?q=, for example
+//
+//	status:Open priority:>=Medium updated:>7d "login page"
+//	(status:Open OR status:"In Progress") AND NOT title:flaky
+//
+// Terms are either field:value predicates or free text matched against the
+// title and description. Adjacent terms are ANDed; AND, OR, NOT (or a
+// leading "-") and parentheses combine them explicitly, with NOT binding
+// tightest and OR loosest.
+package bugquery
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokTerm
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+// token is one lexical element of a query. For terms, field is the part
+// before the first unquoted colon (empty for free text) and value the rest
+// with quotes removed.
+type token struct {
+	kind  tokenKind
+	pos   int
+	text  string
+	field string
+	value string
+}
+
+// Error describes a query that could not be parsed. Column is the 1-based
+// character position of Token within the query.
+type Error struct {
+	Column  int
+	Token   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("%s at column %d", e.Message, e.Column)
+	}
+	return fmt.Sprintf("%s at column %d (%q)", e.Message, e.Column, e.Token)
+}
+
+func errorAt(input string, tok token, format string, args ...interface{}) *Error {
+	return &Error{
+		Column:  utf8.RuneCountInString(input[:tok.pos]) + 1,
+		Token:   tok.text,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func isDelimiter(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '(' || c == ')'
+}
+
+// lex splits input into tokens, ending with a tokEOF token.
+func lex(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, pos: i, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, pos: i, text: ")"})
+			i++
+		case c == '-' && i+1 < len(input) && !isDelimiter(input[i+1]):
+			tokens = append(tokens, token{kind: tokNot, pos: i, text: "-"})
+			i++
+		default:
+			tok, end, err := lexTerm(input, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			i = end
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(input)}), nil
+}
+
+// lexTerm reads the term starting at start and returns it with the offset
+// just past its end.
+func lexTerm(input string, start int) (token, int, error) {
+	tok := token{kind: tokTerm, pos: start}
+	var value strings.Builder
+	quoted := false
+
+	i := start
+	for i < len(input) && !isDelimiter(input[i]) {
+		switch c := input[i]; {
+		case c == '"':
+			end := strings.IndexByte(input[i+1:], '"')
+			if end < 0 {
+				return tok, 0, errorAt(input, token{pos: i, text: input[i:]}, "unterminated quote")
+			}
+			value.WriteString(input[i+1 : i+1+end])
+			quoted = true
+			i += end + 2
+		case c == ':' && tok.field == "" && !quoted && value.Len() > 0:
+			tok.field = value.String()
+			value.Reset()
+			i++
+		default:
+			value.WriteByte(c)
+			i++
+		}
+	}
+	tok.text = input[start:i]
+	tok.value = value.String()
+
+	if tok.field == "" && !quoted {
+		switch tok.value {
+		case "AND":
+			tok.kind = tokAnd
+		case "OR":
+			tok.kind = tokOr
+		case "NOT":
+			tok.kind = tokNot
+		}
+	}
+	return tok, i, nil
+}
@@ -0,0 +1,169 @@
+package bugquery
+
+import (
+	"time"
+
+	"bugtracker-backend/internal/models"
+)
+
+// Expr is a parsed query.
+type Expr interface {
+	// Match reports whether bug satisfies the query.
+	Match(bug *models.Bug) bool
+}
+
+type andExpr []Expr
+
+func (e andExpr) Match(bug *models.Bug) bool {
+	for _, sub := range e {
+		if !sub.Match(bug) {
+			return false
+		}
+	}
+	return true
+}
+
+type orExpr []Expr
+
+func (e orExpr) Match(bug *models.Bug) bool {
+	for _, sub := range e {
+		if sub.Match(bug) {
+			return true
+		}
+	}
+	return false
+}
+
+type notExpr struct{ Expr }
+
+func (e notExpr) Match(bug *models.Bug) bool {
+	return !e.Expr.Match(bug)
+}
+
+// matchFunc adapts a predicate on a single bug to an Expr.
+type matchFunc func(bug *models.Bug) bool
+
+func (f matchFunc) Match(bug *models.Bug) bool {
+	return f(bug)
+}
+
+// Parse parses a query. Relative dates such as "7d" are resolved against
+// now. A blank query parses to nil, which callers treat as matching every
+// bug. Syntax errors are returned as *Error.
+func Parse(input string, now time.Time) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{input: input, tokens: tokens, now: now}
+	if p.peek().kind == tokEOF {
+		return nil, nil
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		if tok.kind == tokRParen {
+			return nil, errorAt(input, tok, "unmatched closing parenthesis")
+		}
+		return nil, errorAt(input, tok, "unexpected token")
+	}
+	return expr, nil
+}
+
+type parser struct {
+	input  string
+	tokens []token
+	pos    int
+	now    time.Time
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// parseOr parses: and ("OR" and)*
+func (p *parser) parseOr() (Expr, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	exprs := orExpr{first}
+	for p.peek().kind == tokOr {
+		p.next()
+		e, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+	}
+	if len(exprs) == 1 {
+		return first, nil
+	}
+	return exprs, nil
+}
+
+// parseAnd parses: unary (["AND"] unary)*
+func (p *parser) parseAnd() (Expr, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	exprs := andExpr{first}
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			p.next()
+		case tokTerm, tokNot, tokLParen:
+		default:
+			if len(exprs) == 1 {
+				return first, nil
+			}
+			return exprs, nil
+		}
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+	}
+}
+
+// parseUnary parses: "NOT" unary | "(" or ")" | term
+func (p *parser) parseUnary() (Expr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNot:
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{e}, nil
+	case tokLParen:
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, errorAt(p.input, tok, "missing closing parenthesis")
+		}
+		p.next()
+		return e, nil
+	case tokTerm:
+		return p.parseTerm(tok)
+	case tokEOF:
+		return nil, errorAt(p.input, tok, "unexpected end of query")
+	default:
+		return nil, errorAt(p.input, tok, "expected a search term")
+	}
+}
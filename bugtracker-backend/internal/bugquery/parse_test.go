@@ -0,0 +1,137 @@
+package bugquery
+
+import (
+	"testing"
+	"time"
+
+	"bugtracker-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testNow = time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+var testResolvedAt = testNow.AddDate(0, 0, -10)
+
+var testBugs = []*models.Bug{
+	{
+		ID: 1, Project: "WEB", Title: "Login page crashes", Description: "Blank screen after submit",
+		Status: "Open", Priority: "High",
+		CreatedAt: testNow.AddDate(0, 0, -30), UpdatedAt: testNow.AddDate(0, 0, -2),
+	},
+	{
+		ID: 2, Project: "WEB", Title: "Typo in footer", Description: "Login link misspelled",
+		Status: "Closed", Priority: "Low",
+		CreatedAt: testNow.AddDate(0, 0, -20), UpdatedAt: testNow.AddDate(0, 0, -10),
+		Resolution: models.ResolutionWontFix, ResolvedAt: &testResolvedAt,
+	},
+	{
+		ID: 3, Project: "API", Title: "Slow dashboard", Description: "Charts take a minute to load",
+		Status: "In Progress", Priority: "Medium",
+		CreatedAt: time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC), UpdatedAt: testNow.Add(-time.Hour),
+		ReopenCount: 2,
+	},
+}
+
+func matchingIDs(expr Expr) []int {
+	ids := []int{}
+	for _, bug := range testBugs {
+		if expr.Match(bug) {
+			ids = append(ids, bug.ID)
+		}
+	}
+	return ids
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []int
+	}{
+		{"status:Open", []int{1}},
+		{"project:web", []int{1, 2}},
+		{"status:open,closed", []int{1, 2}},
+		{`status:"In Progress"`, []int{3}},
+		{"priority:High", []int{1}},
+		{"priority:>=medium", []int{1, 3}},
+		{"priority:Low..Medium", []int{2, 3}},
+		{"id:>1", []int{2, 3}},
+		{"id:1,3", []int{1, 3}},
+		{"login", []int{1, 2}},
+		{`"login page"`, []int{1}},
+		{"title:login", []int{1}},
+		{"description:LOGIN", []int{2}},
+		{"updated:>7d", []int{1, 3}},
+		{"updated:7d", []int{1, 3}},
+		{"updated:<7d", []int{2}},
+		{"updated:today", []int{3}},
+		{"created:2025-03-01", []int{3}},
+		{"created:<2025-03-01", []int{1, 2}},
+		{"created:>2025-02-10", []int{2, 3}},
+		{"created:2025-02-08..2025-02-18", []int{1, 2}},
+		{"created_at:>=2025-03-01T09:00:00Z", []int{3}},
+		{`resolution:"won't fix"`, []int{2}},
+		{"resolved:<7d", []int{2}},
+		{"-resolved:<1w", []int{1, 3}},
+		{"reopens:>0", []int{3}},
+		{"reopen_count:0", []int{1, 2}},
+		{"login status:Open", []int{1}},
+		{"login AND status:Open", []int{1}},
+		{"status:Open OR priority:Low", []int{1, 2}},
+		{"NOT status:Open", []int{2, 3}},
+		{"-status:Open -priority:Low", []int{3}},
+		{"login -title:login", []int{2}},
+		{"(status:Open OR status:Closed) priority:Low", []int{2}},
+		{"status:Open OR status:Closed priority:Low", []int{1, 2}},
+		{"NOT (id:1 OR id:2)", []int{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			expr, err := Parse(tt.query, testNow)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, matchingIDs(expr))
+		})
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	expr, err := Parse("   ", testNow)
+	assert.NoError(t, err)
+	assert.Nil(t, expr)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query   string
+		message string
+		column  int
+		token   string
+	}{
+		{"status:Open severity:High", `unknown field "severity"`, 13, "severity:High"},
+		{"priority:Urgent", `invalid priority "Urgent"`, 1, "priority:Urgent"},
+		{"updated:>soon", `invalid date "soon"`, 1, "updated:>soon"},
+		{"id:1..x", `invalid id "x"`, 1, "id:1..x"},
+		{"status:>Open", "status does not support comparisons", 1, "status:>Open"},
+		{"id:>1..3", "a range cannot be combined with >", 1, "id:>1..3"},
+		{"title:", "missing value for title", 1, "title:"},
+		{`login "page`, "unterminated quote", 7, `"page`},
+		{"(status:Open", "missing closing parenthesis", 1, "("},
+		{"status:Open)", "unmatched closing parenthesis", 12, ")"},
+		{"OR login", "expected a search term", 1, "OR"},
+		{"login AND", "unexpected end of query", 10, ""},
+		{"état x:1", `unknown field "x"`, 6, "x:1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := Parse(tt.query, testNow)
+			if assert.IsType(t, &Error{}, err) {
+				qerr := err.(*Error)
+				assert.Equal(t, tt.message, qerr.Message)
+				assert.Equal(t, tt.column, qerr.Column)
+				assert.Equal(t, tt.token, qerr.Token)
+			}
+		})
+	}
+}
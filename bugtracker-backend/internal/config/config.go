@@ -0,0 +1,143 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	BackendBolt   = "bolt"
+	BackendSQLite = "sqlite"
+	BackendMemory = "memory"
+)
+
+// Config holds the runtime settings read from the environment.
+type Config struct {
+	StorageBackend string
+	DatabasePath   string
+	// WorkflowPath names a JSON workflow definition; empty means the
+	// built-in workflow.
+	WorkflowPath string
+	// TrashRetention is how long deleted bugs stay in the trash before
+	// they are purged; zero keeps them forever.
+	TrashRetention time.Duration
+	// Admins are the usernames of the users who have the admin role
+	// whatever role is stored for them.
+	Admins []string
+	// SessionTTL is how long a login stays valid.
+	SessionTTL time.Duration
+	// OIDC configures single sign-on, which is off unless OIDC.Issuer is
+	// set.
+	OIDC OIDCConfig
+}
+
+// OIDCConfig holds the OpenID Connect provider settings.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the tracker's /api/auth/oidc/callback URL as
+	// registered with the provider.
+	RedirectURL string
+	// Scopes are requested in addition to "openid".
+	Scopes []string
+	// UsernameClaim names the ID token claim that holds the username.
+	UsernameClaim string
+	// GroupsClaim names the ID token claim that lists the user's groups.
+	GroupsClaim string
+	// RoleGroups maps provider groups to the role they grant.
+	RoleGroups map[string]string
+	// LoginRedirect is where the browser is sent once signed in, with the
+	// session token in the URL fragment; empty answers with JSON instead.
+	LoginRedirect string
+}
+
+// DefaultTrashRetention keeps deleted bugs for 30 days.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// DefaultSessionTTL keeps users signed in for a day.
+const DefaultSessionTTL = 24 * time.Hour
+
+// Load reads the configuration from environment variables, falling back to
+// defaults suitable for local development.
+func Load() Config {
+	cfg := Config{
+		StorageBackend: getEnv("DB_BACKEND", BackendBolt),
+	}
+
+	defaultPath := "bugs.db"
+	if cfg.StorageBackend == BackendSQLite {
+		defaultPath = "bugs.sqlite"
+	}
+	cfg.DatabasePath = getEnv("DB_PATH", defaultPath)
+	cfg.WorkflowPath = os.Getenv("WORKFLOW_FILE")
+	cfg.TrashRetention = getDuration("TRASH_RETENTION", DefaultTrashRetention)
+	cfg.Admins = getList("ADMIN_USERS")
+	cfg.SessionTTL = getDuration("SESSION_TTL", DefaultSessionTTL)
+	cfg.OIDC = OIDCConfig{
+		Issuer:        os.Getenv("OIDC_ISSUER"),
+		ClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:        getList("OIDC_SCOPES"),
+		UsernameClaim: getEnv("OIDC_USERNAME_CLAIM", "preferred_username"),
+		GroupsClaim:   getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		RoleGroups:    getMap("OIDC_ROLE_GROUPS"),
+		LoginRedirect: os.Getenv("OIDC_LOGIN_REDIRECT"),
+	}
+	if cfg.OIDC.Scopes == nil {
+		cfg.OIDC.Scopes = []string{"profile", "email"}
+	}
+
+	return cfg
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// getList splits a comma separated variable, dropping empty entries.
+func getList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// getMap reads a comma separated list of key=value pairs, ignoring entries
+// without a "=".
+func getMap(key string) map[string]string {
+	m := make(map[string]string)
+	for _, entry := range getList(key) {
+		k, v, ok := strings.Cut(entry, "=")
+		if !ok {
+			log.Printf("Ignoring %s entry %q without '='", key, entry)
+			continue
+		}
+		m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return m
+}
+
+// getDuration parses a Go duration such as "720h", falling back to the
+// default when the variable is unset or invalid.
+func getDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.Printf("Ignoring invalid %s %q, using %v", key, v, fallback)
+		return fallback
+	}
+	return d
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"time"
+
+	"bugtracker-backend/internal/models"
+)
+
+const apiTokenCounter = "lastAPITokenID"
+
+// apiTokenStore is implemented by every backend's transaction type to store
+// API tokens.
+type apiTokenStore interface {
+	putAPIToken(token *models.APIToken) error
+}
+
+// createAPIToken numbers a new API token and stores it.
+func createAPIToken(tx Tx, ts apiTokenStore, token *models.APIToken) error {
+	id, err := tx.NextID(apiTokenCounter)
+	if err != nil {
+		return err
+	}
+	token.ID = id
+	token.CreatedAt = time.Now()
+	return ts.putAPIToken(token)
+}
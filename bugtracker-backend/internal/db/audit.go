@@ -0,0 +1,72 @@
+package db
+
+import (
+	"time"
+
+	"bugtracker-backend/internal/models"
+)
+
+// AuditQuery selects audit log entries. Zero values mean "no constraint";
+// a zero Limit returns every matching entry.
+type AuditQuery struct {
+	Actor  string
+	Action string
+	Since  time.Time
+	Until  time.Time
+	// AfterSeq continues a listing after the entry with that sequence
+	// number.
+	AfterSeq int
+	Limit    int
+}
+
+func (q *AuditQuery) matches(e *models.AuditEntry) bool {
+	if q.Actor != "" && e.Actor != q.Actor {
+		return false
+	}
+	if q.Action != "" && e.Action != q.Action {
+		return false
+	}
+	if !q.Since.IsZero() && e.At.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.At.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// auditLog is implemented by every backend's transaction type to store the
+// audit log. It only ever appends; there is no way to change or remove an
+// entry.
+type auditLog interface {
+	lastAudit() (*models.AuditEntry, error)
+	putAudit(entry *models.AuditEntry) error
+	// scanAudit calls fn for the entries after afterSeq in sequence order
+	// until it returns false.
+	scanAudit(afterSeq int, fn func(entry models.AuditEntry) bool) error
+}
+
+// appendAudit numbers entry and links it to the end of the chain.
+func appendAudit(log auditLog, entry *models.AuditEntry) error {
+	last, err := log.lastAudit()
+	if err != nil {
+		return err
+	}
+	entry.Seq, entry.PrevHash = 1, ""
+	if last != nil {
+		entry.Seq, entry.PrevHash = last.Seq+1, last.Hash
+	}
+	entry.Hash = entry.ComputeHash()
+	return log.putAudit(entry)
+}
+
+func listAudit(log auditLog, q AuditQuery) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	err := log.scanAudit(q.AfterSeq, func(e models.AuditEntry) bool {
+		if q.matches(&e) {
+			entries = append(entries, e)
+		}
+		return q.Limit == 0 || len(entries) < q.Limit
+	})
+	return entries, err
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"bugtracker-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLog(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		at := time.Date(2025, 2, 12, 16, 0, 0, 0, time.UTC)
+		appends := []models.AuditEntry{
+			{At: at, Actor: "alice", Action: "POST /bugs", Method: "POST", Path: "/api/bugs", Status: 201},
+			{At: at.Add(time.Minute), Actor: "bob", Action: "DELETE /bugs", Method: "DELETE", Path: "/api/bugs", Status: 200},
+			{At: at.Add(2 * time.Minute), Actor: "alice", Action: "DELETE /bugs", Method: "DELETE", Path: "/api/bugs", Status: 200},
+		}
+		for i := range appends {
+			assert.NoError(t, store.AppendAudit(&appends[i]))
+			assert.Equal(t, i+1, appends[i].Seq)
+		}
+		assert.Empty(t, appends[0].PrevHash)
+		assert.Equal(t, appends[0].Hash, appends[1].PrevHash)
+
+		// Deleting bugs leaves the audit log alone.
+		bug := &models.Bug{Title: "Crash"}
+		assert.NoError(t, store.CreateBug(bug))
+		assert.NoError(t, store.DeleteBug(bug.ID))
+
+		entries, err := store.ListAudit(AuditQuery{})
+		assert.NoError(t, err)
+		assert.Equal(t, appends, entries)
+		assert.NoError(t, models.VerifyAuditChain(entries))
+
+		seqs := func(q AuditQuery) []int {
+			entries, err := store.ListAudit(q)
+			assert.NoError(t, err)
+			seqs := []int{}
+			for _, e := range entries {
+				seqs = append(seqs, e.Seq)
+			}
+			return seqs
+		}
+		assert.Equal(t, []int{1, 3}, seqs(AuditQuery{Actor: "alice"}))
+		assert.Equal(t, []int{2, 3}, seqs(AuditQuery{Action: "DELETE /bugs"}))
+		assert.Equal(t, []int{2}, seqs(AuditQuery{Since: at.Add(time.Minute), Until: at.Add(2 * time.Minute)}))
+		assert.Equal(t, []int{2}, seqs(AuditQuery{AfterSeq: 1, Limit: 1}))
+		assert.Equal(t, []int{3}, seqs(AuditQuery{Actor: "alice", AfterSeq: 1, Limit: 1}))
+	})
+}
@@ -0,0 +1,828 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"bugtracker-backend/internal/models"
+
+	"go.etcd.io/bbolt"
+)
+
+var (
+	bugsBucket     = []byte("bugs")
+	commentsBucket = []byte("comments")
+	counterBucket  = []byte("counter")
+	bugIndexBucket = []byte("bug_index")
+)
+
+const bugCounter = "lastBugID"
+
+// BoltStore is a Store backed by a single bbolt database file.
+type BoltStore struct {
+	db *bbolt.DB
+}
+
+type boltTx struct {
+	tx *bbolt.Tx
+}
+
+func OpenBolt(path string) (*BoltStore, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bugsBucket)
+		if err != nil {
+			return fmt.Errorf("create bugs bucket: %w", err)
+		}
+
+		_, err = tx.CreateBucketIfNotExists(commentsBucket)
+		if err != nil {
+			return fmt.Errorf("create comments bucket: %w", err)
+		}
+
+		_, err = tx.CreateBucketIfNotExists(commentRevisionsBucket)
+		if err != nil {
+			return fmt.Errorf("create comment revisions bucket: %w", err)
+		}
+
+		_, err = tx.CreateBucketIfNotExists(counterBucket)
+		if err != nil {
+			return fmt.Errorf("create counter bucket: %w", err)
+		}
+
+		_, err = tx.CreateBucketIfNotExists(historyBucket)
+		if err != nil {
+			return fmt.Errorf("create history bucket: %w", err)
+		}
+
+		_, err = tx.CreateBucketIfNotExists(auditBucket)
+		if err != nil {
+			return fmt.Errorf("create audit bucket: %w", err)
+		}
+
+		for _, bucket := range [][]byte{usersBucket, userNamesBucket, sessionsBucket, identitiesBucket, apiTokensBucket, apiTokenHashesBucket,
+			projectsBucket, projectKeysBucket, bugKeysBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return fmt.Errorf("create %s bucket: %w", bucket, err)
+			}
+		}
+
+		if err := migrateBoltCommentKeys(tx); err != nil {
+			return fmt.Errorf("migrate comment keys: %w", err)
+		}
+
+		if err := renderBoltMarkdown(tx); err != nil {
+			return fmt.Errorf("render markdown: %w", err)
+		}
+
+		if err := assignBoltProjects(tx); err != nil {
+			return fmt.Errorf("assign projects: %w", err)
+		}
+
+		if err := ensureBugIndexes(tx); err != nil {
+			return fmt.Errorf("create bug indexes: %w", err)
+		}
+
+		if err := ensureSearchIndex(tx); err != nil {
+			return fmt.Errorf("create search index: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets: %w", err)
+	}
+
+	log.Println("Database initialized successfully.")
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) View(fn func(tx Tx) error) error {
+	if s.db == nil {
+		return ErrNotInitialized
+	}
+	return s.db.View(func(tx *bbolt.Tx) error {
+		return fn(&boltTx{tx: tx})
+	})
+}
+
+func (s *BoltStore) Update(fn func(tx Tx) error) error {
+	if s.db == nil {
+		return ErrNotInitialized
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return fn(&boltTx{tx: tx})
+	})
+}
+
+func (s *BoltStore) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
+
+func (s *BoltStore) CreateBug(bug *models.Bug) error {
+	return s.Update(func(tx Tx) error { return tx.CreateBug(bug) })
+}
+
+func (s *BoltStore) GetBug(id int) (bug *models.Bug, err error) {
+	err = s.View(func(tx Tx) error {
+		bug, err = tx.GetBug(id)
+		return err
+	})
+	return bug, err
+}
+
+func (s *BoltStore) GetAllBugs() (bugs []*models.Bug, err error) {
+	err = s.View(func(tx Tx) error {
+		bugs, err = tx.GetAllBugs()
+		return err
+	})
+	return bugs, err
+}
+
+func (s *BoltStore) ListBugs(q BugQuery) (bugs []*models.Bug, next string, err error) {
+	err = s.View(func(tx Tx) error {
+		bugs, next, err = tx.ListBugs(q)
+		return err
+	})
+	return bugs, next, err
+}
+
+func (s *BoltStore) UpdateBug(bug *models.Bug) error {
+	return s.Update(func(tx Tx) error { return tx.UpdateBug(bug) })
+}
+
+func (s *BoltStore) DeleteBug(id int) error {
+	return s.Update(func(tx Tx) error { return tx.DeleteBug(id) })
+}
+
+func (s *BoltStore) TrashBug(id int, by string, at time.Time) error {
+	return s.Update(func(tx Tx) error { return tx.TrashBug(id, by, at) })
+}
+
+func (s *BoltStore) RestoreBug(id int) (bug *models.Bug, err error) {
+	err = s.Update(func(tx Tx) error {
+		bug, err = tx.RestoreBug(id)
+		return err
+	})
+	return bug, err
+}
+
+func (s *BoltStore) PurgeTrash(before time.Time) (count int, err error) {
+	err = s.Update(func(tx Tx) error {
+		count, err = tx.PurgeTrash(before)
+		return err
+	})
+	return count, err
+}
+
+func (s *BoltStore) CreateComment(bugID int, comment *models.Comment) error {
+	return s.Update(func(tx Tx) error { return tx.CreateComment(bugID, comment) })
+}
+
+func (s *BoltStore) GetComments(bugID int) (comments []models.Comment, err error) {
+	err = s.View(func(tx Tx) error {
+		comments, err = tx.GetComments(bugID)
+		return err
+	})
+	return comments, err
+}
+
+func (s *BoltStore) ListComments(bugID int, q CommentQuery) (comments []models.Comment, next string, err error) {
+	err = s.View(func(tx Tx) error {
+		comments, next, err = tx.ListComments(bugID, q)
+		return err
+	})
+	return comments, next, err
+}
+
+func (s *BoltStore) GetComment(bugID, id int) (comment *models.Comment, err error) {
+	err = s.View(func(tx Tx) error {
+		comment, err = tx.GetComment(bugID, id)
+		return err
+	})
+	return comment, err
+}
+
+func (s *BoltStore) EditComment(bugID, id int, content string, at time.Time) (comment *models.Comment, err error) {
+	err = s.Update(func(tx Tx) error {
+		comment, err = tx.EditComment(bugID, id, content, at)
+		return err
+	})
+	return comment, err
+}
+
+func (s *BoltStore) DeleteComment(bugID, id int, by string, at time.Time) (comment *models.Comment, err error) {
+	err = s.Update(func(tx Tx) error {
+		comment, err = tx.DeleteComment(bugID, id, by, at)
+		return err
+	})
+	return comment, err
+}
+
+func (s *BoltStore) RestoreComment(bugID, id int) (comment *models.Comment, err error) {
+	err = s.Update(func(tx Tx) error {
+		comment, err = tx.RestoreComment(bugID, id)
+		return err
+	})
+	return comment, err
+}
+
+func (s *BoltStore) GetCommentRevisions(bugID, id int) (revs []models.CommentRevision, err error) {
+	err = s.View(func(tx Tx) error {
+		revs, err = tx.GetCommentRevisions(bugID, id)
+		return err
+	})
+	return revs, err
+}
+
+func (s *BoltStore) OrphanedComments() (comments []models.Comment, err error) {
+	err = s.View(func(tx Tx) error {
+		comments, err = tx.OrphanedComments()
+		return err
+	})
+	return comments, err
+}
+
+func (s *BoltStore) PurgeOrphanedComments() (count int, err error) {
+	err = s.Update(func(tx Tx) error {
+		count, err = tx.PurgeOrphanedComments()
+		return err
+	})
+	return count, err
+}
+
+func (s *BoltStore) AddHistory(entry *models.HistoryEntry) error {
+	return s.Update(func(tx Tx) error { return tx.AddHistory(entry) })
+}
+
+func (s *BoltStore) GetHistory(bugID int) (entries []models.HistoryEntry, err error) {
+	err = s.View(func(tx Tx) error {
+		entries, err = tx.GetHistory(bugID)
+		return err
+	})
+	return entries, err
+}
+
+func (s *BoltStore) AppendAudit(entry *models.AuditEntry) error {
+	return s.Update(func(tx Tx) error { return tx.AppendAudit(entry) })
+}
+
+func (s *BoltStore) ListAudit(q AuditQuery) (entries []models.AuditEntry, err error) {
+	err = s.View(func(tx Tx) error {
+		entries, err = tx.ListAudit(q)
+		return err
+	})
+	return entries, err
+}
+
+func (s *BoltStore) CreateUser(user *models.User) error {
+	return s.Update(func(tx Tx) error { return tx.CreateUser(user) })
+}
+
+func (s *BoltStore) GetUser(id int) (user *models.User, err error) {
+	err = s.View(func(tx Tx) error {
+		user, err = tx.GetUser(id)
+		return err
+	})
+	return user, err
+}
+
+func (s *BoltStore) GetUserByName(username string) (user *models.User, err error) {
+	err = s.View(func(tx Tx) error {
+		user, err = tx.GetUserByName(username)
+		return err
+	})
+	return user, err
+}
+
+func (s *BoltStore) ListUsers() (users []models.User, err error) {
+	err = s.View(func(tx Tx) error {
+		users, err = tx.ListUsers()
+		return err
+	})
+	return users, err
+}
+
+func (s *BoltStore) UpdateUser(user *models.User) error {
+	return s.Update(func(tx Tx) error { return tx.UpdateUser(user) })
+}
+
+func (s *BoltStore) CreateIdentity(identity *models.Identity) error {
+	return s.Update(func(tx Tx) error { return tx.CreateIdentity(identity) })
+}
+
+func (s *BoltStore) GetIdentity(issuer, subject string) (identity *models.Identity, err error) {
+	err = s.View(func(tx Tx) error {
+		identity, err = tx.GetIdentity(issuer, subject)
+		return err
+	})
+	return identity, err
+}
+
+func (s *BoltStore) CreateSession(session *models.Session) error {
+	return s.Update(func(tx Tx) error { return tx.CreateSession(session) })
+}
+
+func (s *BoltStore) GetSession(tokenHash string) (session *models.Session, err error) {
+	err = s.View(func(tx Tx) error {
+		session, err = tx.GetSession(tokenHash)
+		return err
+	})
+	return session, err
+}
+
+func (s *BoltStore) DeleteSession(tokenHash string) error {
+	return s.Update(func(tx Tx) error { return tx.DeleteSession(tokenHash) })
+}
+
+func (s *BoltStore) DeleteExpiredSessions(now time.Time) (count int, err error) {
+	err = s.Update(func(tx Tx) error {
+		count, err = tx.DeleteExpiredSessions(now)
+		return err
+	})
+	return count, err
+}
+
+func (s *BoltStore) CreateAPIToken(token *models.APIToken) error {
+	return s.Update(func(tx Tx) error { return tx.CreateAPIToken(token) })
+}
+
+func (s *BoltStore) GetAPIToken(tokenHash string) (token *models.APIToken, err error) {
+	err = s.View(func(tx Tx) error {
+		token, err = tx.GetAPIToken(tokenHash)
+		return err
+	})
+	return token, err
+}
+
+func (s *BoltStore) ListAPITokens(userID int) (tokens []models.APIToken, err error) {
+	err = s.View(func(tx Tx) error {
+		tokens, err = tx.ListAPITokens(userID)
+		return err
+	})
+	return tokens, err
+}
+
+func (s *BoltStore) DeleteAPIToken(userID, id int) error {
+	return s.Update(func(tx Tx) error { return tx.DeleteAPIToken(userID, id) })
+}
+
+func (s *BoltStore) TouchAPIToken(id int, at time.Time) error {
+	return s.Update(func(tx Tx) error { return tx.TouchAPIToken(id, at) })
+}
+
+func (s *BoltStore) GetBugID(project string, number int) (id int, err error) {
+	err = s.View(func(tx Tx) error {
+		id, err = tx.GetBugID(project, number)
+		return err
+	})
+	return id, err
+}
+
+func (s *BoltStore) CreateProject(project *models.Project) error {
+	return s.Update(func(tx Tx) error { return tx.CreateProject(project) })
+}
+
+func (s *BoltStore) GetProject(key string) (project *models.Project, err error) {
+	err = s.View(func(tx Tx) error {
+		project, err = tx.GetProject(key)
+		return err
+	})
+	return project, err
+}
+
+func (s *BoltStore) ListProjects() (projects []models.Project, err error) {
+	err = s.View(func(tx Tx) error {
+		projects, err = tx.ListProjects()
+		return err
+	})
+	return projects, err
+}
+
+func (s *BoltStore) SearchBugs(query string, limit int) (hits []SearchHit, err error) {
+	err = s.View(func(tx Tx) error {
+		hits, err = tx.SearchBugs(query, limit)
+		return err
+	})
+	return hits, err
+}
+
+func (s *BoltStore) RebuildSearchIndex() (count int, err error) {
+	err = s.Update(func(tx Tx) error {
+		count, err = tx.RebuildSearchIndex()
+		return err
+	})
+	return count, err
+}
+
+func (s *BoltStore) NextID(counter string) (id int, err error) {
+	err = s.Update(func(tx Tx) error {
+		id, err = tx.NextID(counter)
+		return err
+	})
+	return id, err
+}
+
+func (t *boltTx) CreateBug(bug *models.Bug) error {
+	b := t.tx.Bucket(bugsBucket)
+
+	if err := numberBug(t, bug); err != nil {
+		return err
+	}
+	nextID, err := t.NextID(bugCounter)
+	if err != nil {
+		return err
+	}
+
+	bug.ID = nextID
+	bug.Version = 1
+	bug.RenderDescription()
+
+	encoded, err := json.Marshal(bug)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bug: %w", err)
+	}
+
+	if err := b.Put(itob(bug.ID), encoded); err != nil {
+		return err
+	}
+	if err := t.tx.Bucket(bugKeysBucket).Put([]byte(bug.Key), itob(bug.ID)); err != nil {
+		return err
+	}
+	if err := t.indexBug(nil, bug); err != nil {
+		return err
+	}
+	return indexBugForSearch(t, t, bug.ID)
+}
+
+func (t *boltTx) GetBug(id int) (*models.Bug, error) {
+	bug, err := t.loadBug(id)
+	if err != nil {
+		return nil, err
+	}
+	if bug.DeletedAt != nil {
+		return nil, ErrBugNotFound
+	}
+	return bug, fillCommentCounts(t, bug)
+}
+
+func (t *boltTx) loadBug(id int) (*models.Bug, error) {
+	data := t.tx.Bucket(bugsBucket).Get(itob(id))
+	if data == nil {
+		return nil, ErrBugNotFound
+	}
+
+	bug, err := decodeBoltBug(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bug %d: %w", id, err)
+	}
+	return bug, nil
+}
+
+// decodeBoltBug decodes a stored bug. Bugs saved before versions existed
+// are at version 1.
+func decodeBoltBug(data []byte) (*models.Bug, error) {
+	var bug models.Bug
+	if err := json.Unmarshal(data, &bug); err != nil {
+		return nil, err
+	}
+	if bug.Version == 0 {
+		bug.Version = 1
+	}
+	return &bug, nil
+}
+
+func (t *boltTx) GetAllBugs() ([]*models.Bug, error) {
+	var bugs []*models.Bug
+
+	err := t.tx.Bucket(bugsBucket).ForEach(func(k, v []byte) error {
+		bug, err := decodeBoltBug(v)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal bug %d: %w", btoi(k), err)
+		}
+		if bug.DeletedAt == nil {
+			bugs = append(bugs, bug)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bugs, fillCommentCounts(t, bugs...)
+}
+
+func (t *boltTx) ListBugs(q BugQuery) ([]*models.Bug, string, error) {
+	if err := q.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	// Bugs are walked in sort order either directly through the bugs bucket
+	// or through the secondary index for the sort field. Both kinds of key
+	// end with the bug ID.
+	var c *bbolt.Cursor
+	if q.SortBy == SortByID {
+		c = t.tx.Bucket(bugsBucket).Cursor()
+	} else {
+		c = t.tx.Bucket(bugIndexBucket).Bucket([]byte(q.SortBy)).Cursor()
+	}
+
+	var seek []byte
+	if q.Cursor != "" {
+		cur, _ := decodeBugCursor(q.Cursor)
+		if q.SortBy == SortByID {
+			seek = itob(cur.ID)
+		} else {
+			seek = bugIndexKey(cur.Key, cur.ID)
+		}
+	}
+
+	var bugs []*models.Bug
+	for k := seekBoltCursor(c, seek, q.Descending); k != nil; k = stepBoltCursor(c, q.Descending) {
+		bug, err := t.loadBug(btoi(k[len(k)-8:]))
+		if err != nil {
+			return nil, "", err
+		}
+		if !q.matches(bug) {
+			continue
+		}
+		bugs = append(bugs, bug)
+		if q.Limit > 0 && len(bugs) > q.Limit {
+			break
+		}
+	}
+
+	bugs, next := q.page(bugs)
+	if err := fillCommentCounts(t, bugs...); err != nil {
+		return nil, "", err
+	}
+	return bugs, next, nil
+}
+
+func (t *boltTx) UpdateBug(bug *models.Bug) error {
+	b := t.tx.Bucket(bugsBucket)
+
+	old, err := t.loadBug(bug.ID)
+	if err != nil {
+		return err
+	}
+	if err := checkBugVersion(old, bug); err != nil {
+		return err
+	}
+
+	bug.UpdatedAt = time.Now()
+	bug.Version++
+	bug.RenderDescription()
+
+	encoded, err := json.Marshal(bug)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bug: %w", err)
+	}
+
+	if err := b.Put(itob(bug.ID), encoded); err != nil {
+		return err
+	}
+	if err := t.indexBug(old, bug); err != nil {
+		return err
+	}
+	return syncBugSearchDoc(t, t, bug)
+}
+
+func (t *boltTx) DeleteBug(id int) error {
+	b := t.tx.Bucket(bugsBucket)
+
+	old, err := t.loadBug(id)
+	if err != nil {
+		return err
+	}
+
+	if err := b.Delete(itob(id)); err != nil {
+		return err
+	}
+	if err := t.tx.Bucket(bugKeysBucket).Delete([]byte(old.Key)); err != nil {
+		return err
+	}
+	if err := t.indexBug(old, nil); err != nil {
+		return err
+	}
+	if err := t.deleteBugComments(id); err != nil {
+		return err
+	}
+	if err := t.deleteBugHistory(id); err != nil {
+		return err
+	}
+	return t.deleteSearchDoc(id)
+}
+
+func (t *boltTx) TrashBug(id int, by string, at time.Time) error {
+	return trashBug(t, id, by, at)
+}
+
+func (t *boltTx) RestoreBug(id int) (*models.Bug, error) {
+	return restoreBug(t, t, id)
+}
+
+func (t *boltTx) PurgeTrash(before time.Time) (int, error) {
+	return purgeTrash(t, t, before)
+}
+
+func (t *boltTx) NextID(counter string) (int, error) {
+	b := t.tx.Bucket(counterBucket)
+	id := b.Get([]byte(counter))
+
+	var nextID int
+	if id == nil {
+		nextID = 1
+	} else {
+		nextID = btoi(id) + 1
+	}
+
+	if err := b.Put([]byte(counter), itob(nextID)); err != nil {
+		return 0, err
+	}
+
+	return nextID, nil
+}
+
+// ensureBugIndexes creates the secondary index bucket for every sortable
+// field, populating any index that did not exist yet from the bugs bucket.
+func ensureBugIndexes(tx *bbolt.Tx) error {
+	idx, err := tx.CreateBucketIfNotExists(bugIndexBucket)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range indexedSortFields {
+		if idx.Bucket([]byte(field)) != nil {
+			continue
+		}
+		b, err := idx.CreateBucket([]byte(field))
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket(bugsBucket).ForEach(func(k, v []byte) error {
+			var bug models.Bug
+			if err := json.Unmarshal(v, &bug); err != nil {
+				return fmt.Errorf("failed to unmarshal bug %d: %w", btoi(k), err)
+			}
+			return b.Put(bugIndexKey(bugSortKey(&bug, field), bug.ID), []byte{})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markdownRenderedKey is set in the counter bucket once bugs and comments
+// written before rendered HTML was stored have been rendered.
+var markdownRenderedKey = []byte("markdownRendered")
+
+// renderBoltMarkdown fills in the rendered HTML of bugs and comments written
+// by older versions.
+func renderBoltMarkdown(tx *bbolt.Tx) error {
+	counters := tx.Bucket(counterBucket)
+	if counters.Get(markdownRenderedKey) != nil {
+		return nil
+	}
+
+	err := rewriteBoltValues(tx.Bucket(bugsBucket), func(v []byte) (interface{}, error) {
+		var bug models.Bug
+		if err := json.Unmarshal(v, &bug); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal bug: %w", err)
+		}
+		bug.RenderDescription()
+		return &bug, nil
+	})
+	if err != nil {
+		return err
+	}
+	err = rewriteBoltValues(tx.Bucket(commentsBucket), func(v []byte) (interface{}, error) {
+		var comment models.Comment
+		if err := json.Unmarshal(v, &comment); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal comment: %w", err)
+		}
+		comment.RenderContent()
+		return &comment, nil
+	})
+	if err != nil {
+		return err
+	}
+	return counters.Put(markdownRenderedKey, itob(1))
+}
+
+// rewriteBoltValues replaces every value in b with the JSON encoding of what
+// fn makes of it.
+func rewriteBoltValues(b *bbolt.Bucket, fn func(v []byte) (interface{}, error)) error {
+	updated := make(map[string][]byte)
+	err := b.ForEach(func(k, v []byte) error {
+		doc, err := fn(v)
+		if err != nil {
+			return err
+		}
+		encoded, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		updated[string(k)] = encoded
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Buckets must not be modified while they are iterated.
+	for k, v := range updated {
+		if err := b.Put([]byte(k), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexBug moves a bug's secondary index entries from their old to their new
+// positions. Either bug may be nil when the bug is being created or deleted.
+func (t *boltTx) indexBug(old, bug *models.Bug) error {
+	idx := t.tx.Bucket(bugIndexBucket)
+	for _, field := range indexedSortFields {
+		b := idx.Bucket([]byte(field))
+		if old != nil {
+			if err := b.Delete(bugIndexKey(bugSortKey(old, field), old.ID)); err != nil {
+				return err
+			}
+		}
+		if bug != nil {
+			if err := b.Put(bugIndexKey(bugSortKey(bug, field), bug.ID), []byte{}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// bugIndexKey orders index entries by sort key, then by bug ID.
+func bugIndexKey(key string, id int) []byte {
+	k := make([]byte, 0, len(key)+9)
+	k = append(k, key...)
+	k = append(k, 0)
+	return append(k, itob(id)...)
+}
+
+// seekBoltCursor positions c on the first key strictly after seek in the
+// requested direction, or on the first key overall when seek is nil.
+func seekBoltCursor(c *bbolt.Cursor, seek []byte, desc bool) []byte {
+	if seek == nil {
+		if desc {
+			k, _ := c.Last()
+			return k
+		}
+		k, _ := c.First()
+		return k
+	}
+
+	k, _ := c.Seek(seek)
+	if desc {
+		if k == nil {
+			k, _ = c.Last()
+			return k
+		}
+		k, _ = c.Prev()
+		return k
+	}
+	if k != nil && bytes.Equal(k, seek) {
+		k, _ = c.Next()
+	}
+	return k
+}
+
+func stepBoltCursor(c *bbolt.Cursor, desc bool) []byte {
+	if desc {
+		k, _ := c.Prev()
+		return k
+	}
+	k, _ := c.Next()
+	return k
+}
+
+func itob(v int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(v))
+	return b
+}
+
+func btoi(b []byte) int {
+	return int(binary.BigEndian.Uint64(b))
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"bugtracker-backend/internal/models"
+)
+
+// auditBucket maps sequence numbers to audit entries.
+var auditBucket = []byte("audit")
+
+func (t *boltTx) AppendAudit(entry *models.AuditEntry) error {
+	return appendAudit(t, entry)
+}
+
+func (t *boltTx) ListAudit(q AuditQuery) ([]models.AuditEntry, error) {
+	return listAudit(t, q)
+}
+
+func (t *boltTx) lastAudit() (*models.AuditEntry, error) {
+	_, v := t.tx.Bucket(auditBucket).Cursor().Last()
+	if v == nil {
+		return nil, nil
+	}
+	var entry models.AuditEntry
+	if err := json.Unmarshal(v, &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal audit entry: %w", err)
+	}
+	return &entry, nil
+}
+
+func (t *boltTx) putAudit(entry *models.AuditEntry) error {
+	encoded, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	return t.tx.Bucket(auditBucket).Put(itob(entry.Seq), encoded)
+}
+
+func (t *boltTx) scanAudit(afterSeq int, fn func(entry models.AuditEntry) bool) error {
+	c := t.tx.Bucket(auditBucket).Cursor()
+	for k, v := c.Seek(itob(afterSeq + 1)); k != nil; k, v = c.Next() {
+		var entry models.AuditEntry
+		if err := json.Unmarshal(v, &entry); err != nil {
+			return fmt.Errorf("failed to unmarshal audit entry %d: %w", btoi(k), err)
+		}
+		if !fn(entry) {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"bugtracker-backend/internal/models"
+
+	"go.etcd.io/bbolt"
+)
+
+// commentRevisionsBucket maps a comment ID followed by a revision number to
+// the revision.
+var commentRevisionsBucket = []byte("comment_revisions")
+
+// Comments are keyed by their bug's ID followed by their own, so a bug's
+// comments are adjacent and in the order they were written.
+func commentKey(bugID, id int) []byte {
+	return append(itob(bugID), itob(id)...)
+}
+
+func (t *boltTx) CreateComment(bugID int, comment *models.Comment) error {
+	if _, err := t.GetBug(bugID); err != nil {
+		return err
+	}
+	if err := setCommentParent(t, bugID, comment); err != nil {
+		return err
+	}
+
+	id, err := t.NextID(commentCounter)
+	if err != nil {
+		return err
+	}
+	comment.CreatedAt = time.Now()
+	comment.ID = id
+	comment.BugID = bugID
+	comment.RenderContent()
+
+	encoded, err := json.Marshal(comment)
+	if err != nil {
+		return fmt.Errorf("failed to marshal comment: %v", err)
+	}
+	if err := t.tx.Bucket(commentsBucket).Put(commentKey(bugID, comment.ID), encoded); err != nil {
+		return err
+	}
+	return indexBugForSearch(t, t, bugID)
+}
+
+func (t *boltTx) GetComments(bugID int) ([]models.Comment, error) {
+	if _, err := t.GetBug(bugID); err != nil {
+		return nil, err
+	}
+	return t.bugComments(bugID)
+}
+
+func (t *boltTx) ListComments(bugID int, q CommentQuery) ([]models.Comment, string, error) {
+	return listComments(t, bugID, q)
+}
+
+func (t *boltTx) GetComment(bugID, id int) (*models.Comment, error) {
+	return getLiveComment(t, bugID, id)
+}
+
+func (t *boltTx) EditComment(bugID, id int, content string, at time.Time) (*models.Comment, error) {
+	return editComment(t, t, t, bugID, id, content, at)
+}
+
+func (t *boltTx) DeleteComment(bugID, id int, by string, at time.Time) (*models.Comment, error) {
+	return trashComment(t, t, t, bugID, id, by, at)
+}
+
+func (t *boltTx) RestoreComment(bugID, id int) (*models.Comment, error) {
+	return restoreComment(t, t, t, bugID, id)
+}
+
+func (t *boltTx) GetCommentRevisions(bugID, id int) ([]models.CommentRevision, error) {
+	return getCommentRevisions(t, bugID, id)
+}
+
+func (t *boltTx) OrphanedComments() ([]models.Comment, error) {
+	return orphanedComments(t)
+}
+
+func (t *boltTx) PurgeOrphanedComments() (int, error) {
+	return purgeOrphanedComments(t, t, t)
+}
+
+// bugComments returns the comments stored under bugID, whether or not the
+// bug exists.
+func (t *boltTx) bugComments(bugID int) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := t.scanBugComments(bugID, 0, false, func(c models.Comment) bool {
+		comments = append(comments, c)
+		return true
+	})
+	return comments, err
+}
+
+func (t *boltTx) scanBugComments(bugID, afterID int, descending bool, fn func(comment models.Comment) bool) error {
+	prefix := itob(bugID)
+	c := t.tx.Bucket(commentsBucket).Cursor()
+
+	var k, v []byte
+	if descending {
+		// Seek to the first key past the page and step back from it.
+		end := itob(bugID + 1)
+		if afterID > 0 {
+			end = commentKey(bugID, afterID)
+		}
+		if k, _ = c.Seek(end); k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+	} else {
+		k, v = c.Seek(commentKey(bugID, afterID+1))
+	}
+
+	for ; k != nil && bytes.HasPrefix(k, prefix); k, v = stepComment(c, descending) {
+		var comment models.Comment
+		if err := json.Unmarshal(v, &comment); err != nil {
+			return err
+		}
+		if !fn(comment) {
+			break
+		}
+	}
+	return nil
+}
+
+func stepComment(c *bbolt.Cursor, descending bool) ([]byte, []byte) {
+	if descending {
+		return c.Prev()
+	}
+	return c.Next()
+}
+
+func (t *boltTx) commentCounts(bugIDs []int) (map[int]int, error) {
+	counts := make(map[int]int, len(bugIDs))
+	c := t.tx.Bucket(commentsBucket).Cursor()
+	for _, id := range bugIDs {
+		prefix := itob(id)
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			counts[id]++
+		}
+	}
+	return counts, nil
+}
+
+func (t *boltTx) allComments() ([]models.Comment, error) {
+	var comments []models.Comment
+	err := t.tx.Bucket(commentsBucket).ForEach(func(k, v []byte) error {
+		var comment models.Comment
+		if err := json.Unmarshal(v, &comment); err != nil {
+			return err
+		}
+		comments = append(comments, comment)
+		return nil
+	})
+	return comments, err
+}
+
+func (t *boltTx) getComment(bugID, id int) (*models.Comment, error) {
+	data := t.tx.Bucket(commentsBucket).Get(commentKey(bugID, id))
+	if data == nil {
+		return nil, ErrCommentNotFound
+	}
+	var comment models.Comment
+	if err := json.Unmarshal(data, &comment); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal comment %d: %w", id, err)
+	}
+	return &comment, nil
+}
+
+func (t *boltTx) putComment(comment *models.Comment) error {
+	encoded, err := json.Marshal(comment)
+	if err != nil {
+		return fmt.Errorf("failed to marshal comment: %w", err)
+	}
+	return t.tx.Bucket(commentsBucket).Put(commentKey(comment.BugID, comment.ID), encoded)
+}
+
+func (t *boltTx) putCommentRevision(rev *models.CommentRevision) error {
+	encoded, err := json.Marshal(rev)
+	if err != nil {
+		return fmt.Errorf("failed to marshal comment revision: %w", err)
+	}
+	return t.tx.Bucket(commentRevisionsBucket).Put(commentKey(rev.CommentID, rev.Revision), encoded)
+}
+
+func (t *boltTx) commentRevisions(commentID int) ([]models.CommentRevision, error) {
+	var revs []models.CommentRevision
+	prefix := itob(commentID)
+	c := t.tx.Bucket(commentRevisionsBucket).Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		var rev models.CommentRevision
+		if err := json.Unmarshal(v, &rev); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal revision of comment %d: %w", commentID, err)
+		}
+		revs = append(revs, rev)
+	}
+	return revs, nil
+}
+
+func (t *boltTx) deleteCommentRevisions(commentID int) error {
+	prefix := itob(commentID)
+	c := t.tx.Bucket(commentRevisionsBucket).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
+		if err := c.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *boltTx) deleteComment(bugID, id int) error {
+	if err := t.deleteCommentRevisions(id); err != nil {
+		return err
+	}
+	return t.tx.Bucket(commentsBucket).Delete(commentKey(bugID, id))
+}
+
+// deleteBugComments deletes the comments of a bug that is being deleted.
+func (t *boltTx) deleteBugComments(bugID int) error {
+	comments, err := t.bugComments(bugID)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		if err := t.deleteComment(bugID, comment.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateBoltCommentKeys moves comments stored by older versions, which were
+// keyed by a random 32-bit ID alone, under bug-prefixed keys. They are
+// renumbered from the comment counter in the order they were written, and
+// history entries that refer to them are updated to match.
+func migrateBoltCommentKeys(tx *bbolt.Tx) error {
+	b := tx.Bucket(commentsBucket)
+	var legacy []models.Comment
+	err := b.ForEach(func(k, v []byte) error {
+		if len(k) != 8 {
+			return nil
+		}
+		var comment models.Comment
+		if err := json.Unmarshal(v, &comment); err != nil {
+			return fmt.Errorf("failed to unmarshal comment %d: %w", btoi(k), err)
+		}
+		legacy = append(legacy, comment)
+		return nil
+	})
+	if err != nil || len(legacy) == 0 {
+		return err
+	}
+
+	sort.Slice(legacy, func(i, j int) bool {
+		if !legacy[i].CreatedAt.Equal(legacy[j].CreatedAt) {
+			return legacy[i].CreatedAt.Before(legacy[j].CreatedAt)
+		}
+		return legacy[i].ID < legacy[j].ID
+	})
+
+	t := &boltTx{tx: tx}
+	renumbered := make(map[int]map[int]int)
+	for _, comment := range legacy {
+		if err := b.Delete(itob(comment.ID)); err != nil {
+			return err
+		}
+		id, err := t.NextID(commentCounter)
+		if err != nil {
+			return err
+		}
+		if renumbered[comment.BugID] == nil {
+			renumbered[comment.BugID] = make(map[int]int)
+		}
+		renumbered[comment.BugID][comment.ID] = id
+
+		comment.ID = id
+		encoded, err := json.Marshal(comment)
+		if err != nil {
+			return fmt.Errorf("failed to marshal comment: %w", err)
+		}
+		if err := b.Put(commentKey(comment.BugID, id), encoded); err != nil {
+			return err
+		}
+	}
+
+	for bugID, ids := range renumbered {
+		entries, err := t.bugHistory(bugID)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			id, ok := ids[entry.CommentID]
+			if !ok {
+				continue
+			}
+			entry.CommentID = id
+			if err := t.putHistory(&entry); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
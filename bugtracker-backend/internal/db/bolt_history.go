@@ -0,0 +1,60 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"bugtracker-backend/internal/models"
+
+	"go.etcd.io/bbolt"
+)
+
+// historyBucket holds one nested bucket per bug, mapping entry IDs to
+// entries.
+var historyBucket = []byte("history")
+
+func (t *boltTx) AddHistory(entry *models.HistoryEntry) error {
+	return addHistory(t, t, t, entry)
+}
+
+func (t *boltTx) GetHistory(bugID int) ([]models.HistoryEntry, error) {
+	return getHistory(t, t, bugID)
+}
+
+func (t *boltTx) putHistory(entry *models.HistoryEntry) error {
+	b, err := t.tx.Bucket(historyBucket).CreateBucketIfNotExists(itob(entry.BugID))
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+	return b.Put(itob(entry.ID), encoded)
+}
+
+func (t *boltTx) bugHistory(bugID int) ([]models.HistoryEntry, error) {
+	b := t.tx.Bucket(historyBucket).Bucket(itob(bugID))
+	if b == nil {
+		return nil, nil
+	}
+
+	var entries []models.HistoryEntry
+	err := b.ForEach(func(k, v []byte) error {
+		var entry models.HistoryEntry
+		if err := json.Unmarshal(v, &entry); err != nil {
+			return fmt.Errorf("failed to unmarshal history entry %d: %w", btoi(k), err)
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+func (t *boltTx) deleteBugHistory(bugID int) error {
+	err := t.tx.Bucket(historyBucket).DeleteBucket(itob(bugID))
+	if err == bbolt.ErrBucketNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+
+	"bugtracker-backend/internal/models"
+)
+
+var (
+	// projectsBucket maps project IDs to projects and projectKeysBucket
+	// project keys to project IDs.
+	projectsBucket    = []byte("projects")
+	projectKeysBucket = []byte("project_keys")
+	// bugKeysBucket maps bug keys such as WEB-42 to bug IDs.
+	bugKeysBucket = []byte("bug_keys")
+)
+
+func (t *boltTx) CreateProject(project *models.Project) error {
+	return createProject(t, t, project)
+}
+
+func (t *boltTx) GetProject(key string) (*models.Project, error) {
+	id := t.tx.Bucket(projectKeysBucket).Get([]byte(models.NormalizeProjectKey(key)))
+	if id == nil {
+		return nil, ErrProjectNotFound
+	}
+	v := t.tx.Bucket(projectsBucket).Get(id)
+	if v == nil {
+		return nil, ErrProjectNotFound
+	}
+	return decodeBoltProject(v)
+}
+
+func (t *boltTx) ListProjects() ([]models.Project, error) {
+	var projects []models.Project
+	err := t.tx.Bucket(projectsBucket).ForEach(func(k, v []byte) error {
+		project, err := decodeBoltProject(v)
+		if err != nil {
+			return err
+		}
+		projects = append(projects, *project)
+		return nil
+	})
+	return projects, err
+}
+
+func (t *boltTx) putProject(project *models.Project) error {
+	encoded, err := json.Marshal(project)
+	if err != nil {
+		return fmt.Errorf("failed to marshal project: %w", err)
+	}
+	if err := t.tx.Bucket(projectsBucket).Put(itob(project.ID), encoded); err != nil {
+		return err
+	}
+	return t.tx.Bucket(projectKeysBucket).Put([]byte(project.Key), itob(project.ID))
+}
+
+func decodeBoltProject(v []byte) (*models.Project, error) {
+	var project models.Project
+	if err := json.Unmarshal(v, &project); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal project: %w", err)
+	}
+	return &project, nil
+}
+
+func (t *boltTx) GetBugID(project string, number int) (int, error) {
+	id := t.tx.Bucket(bugKeysBucket).Get([]byte(models.BugKey(models.NormalizeProjectKey(project), number)))
+	if id == nil {
+		return 0, ErrBugNotFound
+	}
+	return btoi(id), nil
+}
+
+// projectsAssignedKey is set in the counter bucket once the default project
+// exists and the bugs filed before projects have been moved into it.
+var projectsAssignedKey = []byte("projectsAssigned")
+
+// assignBoltProjects creates the default project and files the bugs written
+// by older versions in it, numbered by their IDs, so that bug 42 becomes
+// BUG-42.
+func assignBoltProjects(tx *bbolt.Tx) error {
+	counters := tx.Bucket(counterBucket)
+	if counters.Get(projectsAssignedKey) != nil {
+		return nil
+	}
+
+	t := &boltTx{tx: tx}
+	if err := t.CreateProject(models.DefaultProject()); err != nil {
+		return err
+	}
+
+	keys := tx.Bucket(bugKeysBucket)
+	err := rewriteBoltValues(tx.Bucket(bugsBucket), func(v []byte) (interface{}, error) {
+		var bug models.Bug
+		if err := json.Unmarshal(v, &bug); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal bug: %w", err)
+		}
+		bug.Project = models.DefaultProjectKey
+		bug.Number = bug.ID
+		bug.Key = models.BugKey(bug.Project, bug.Number)
+		if err := keys.Put([]byte(bug.Key), itob(bug.ID)); err != nil {
+			return nil, err
+		}
+		return &bug, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// New bugs continue from the highest ID so far.
+	last := counters.Get([]byte(bugCounter))
+	if last == nil {
+		last = itob(0)
+	}
+	if err := counters.Put([]byte(bugNumberCounter(models.DefaultProjectKey)), last); err != nil {
+		return err
+	}
+	return counters.Put(projectsAssignedKey, itob(1))
+}
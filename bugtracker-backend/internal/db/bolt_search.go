@@ -0,0 +1,165 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"bugtracker-backend/internal/search"
+
+	"go.etcd.io/bbolt"
+)
+
+var (
+	searchPostingsBucket = []byte("search_postings")
+	searchDocsBucket     = []byte("search_docs")
+	searchMetaBucket     = []byte("search_meta")
+	searchStatsKey       = []byte("stats")
+)
+
+// boltSearchDoc is the per-bug record kept next to the postings so that a
+// document's old postings can be removed when it is reindexed.
+type boltSearchDoc struct {
+	Length int      `json:"length"`
+	Terms  []string `json:"terms"`
+}
+
+// ensureSearchIndex creates the search buckets, building the index from the
+// existing bugs the first time a database is opened with search support.
+func ensureSearchIndex(tx *bbolt.Tx) error {
+	if tx.Bucket(searchDocsBucket) != nil {
+		return nil
+	}
+	t := &boltTx{tx: tx}
+	_, err := t.RebuildSearchIndex()
+	return err
+}
+
+func (t *boltTx) SearchBugs(query string, limit int) ([]SearchHit, error) {
+	return searchBugs(t, t, query, limit)
+}
+
+func (t *boltTx) RebuildSearchIndex() (int, error) {
+	return rebuildSearchIndex(t, t)
+}
+
+func (t *boltTx) putSearchDoc(doc *search.Document) error {
+	if err := t.deleteSearchDoc(doc.ID); err != nil {
+		return err
+	}
+
+	postings := t.tx.Bucket(searchPostingsBucket)
+	record := boltSearchDoc{Length: doc.Length}
+	for term, tf := range doc.Terms {
+		if err := postings.Put(searchPostingKey(term, doc.ID), itob(tf)); err != nil {
+			return err
+		}
+		record.Terms = append(record.Terms, term)
+	}
+
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal search document: %w", err)
+	}
+	if err := t.tx.Bucket(searchDocsBucket).Put(itob(doc.ID), encoded); err != nil {
+		return err
+	}
+
+	return t.adjustSearchStats(1, doc.Length)
+}
+
+func (t *boltTx) deleteSearchDoc(id int) error {
+	docs := t.tx.Bucket(searchDocsBucket)
+	data := docs.Get(itob(id))
+	if data == nil {
+		return nil
+	}
+
+	var record boltSearchDoc
+	if err := json.Unmarshal(data, &record); err != nil {
+		return fmt.Errorf("failed to unmarshal search document %d: %w", id, err)
+	}
+
+	postings := t.tx.Bucket(searchPostingsBucket)
+	for _, term := range record.Terms {
+		if err := postings.Delete(searchPostingKey(term, id)); err != nil {
+			return err
+		}
+	}
+	if err := docs.Delete(itob(id)); err != nil {
+		return err
+	}
+
+	return t.adjustSearchStats(-1, -record.Length)
+}
+
+func (t *boltTx) clearSearchIndex() error {
+	for _, name := range [][]byte{searchPostingsBucket, searchDocsBucket, searchMetaBucket} {
+		if t.tx.Bucket(name) != nil {
+			if err := t.tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		if _, err := t.tx.CreateBucket(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *boltTx) searchPostings(term string) (map[int]int, error) {
+	prefix := searchPostingKey(term, 0)
+	prefix = prefix[:len(prefix)-8]
+
+	postings := make(map[int]int)
+	c := t.tx.Bucket(searchPostingsBucket).Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		postings[btoi(k[len(prefix):])] = btoi(v)
+	}
+	return postings, nil
+}
+
+func (t *boltTx) searchDocLength(id int) (int, error) {
+	data := t.tx.Bucket(searchDocsBucket).Get(itob(id))
+	if data == nil {
+		return 0, nil
+	}
+	var record boltSearchDoc
+	if err := json.Unmarshal(data, &record); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal search document %d: %w", id, err)
+	}
+	return record.Length, nil
+}
+
+func (t *boltTx) searchStats() (search.Stats, error) {
+	var stats search.Stats
+	data := t.tx.Bucket(searchMetaBucket).Get(searchStatsKey)
+	if data == nil {
+		return stats, nil
+	}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return stats, fmt.Errorf("failed to unmarshal search stats: %w", err)
+	}
+	return stats, nil
+}
+
+func (t *boltTx) adjustSearchStats(docs, length int) error {
+	stats, err := t.searchStats()
+	if err != nil {
+		return err
+	}
+	stats.Docs += docs
+	stats.TotalLength += length
+
+	encoded, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	return t.tx.Bucket(searchMetaBucket).Put(searchStatsKey, encoded)
+}
+
+// searchPostingKey orders postings by term, then by bug ID, so that all
+// postings of a term can be read with a single prefix scan.
+func searchPostingKey(term string, id int) []byte {
+	return bugIndexKey(term, id)
+}
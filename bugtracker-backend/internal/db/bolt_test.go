@@ -0,0 +1,172 @@
+package db
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"bugtracker-backend/internal/models"
+	"bugtracker-backend/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func TestBoltMigratesLegacyCommentKeys(t *testing.T) {
+	defer testutil.CleanupTestDB()
+	path := testutil.GetTestDBPath()
+
+	store, err := OpenBolt(path)
+	assert.NoError(t, err)
+	assert.NoError(t, store.CreateBug(&models.Bug{Title: "Crash"}))
+	store.Close()
+
+	// Older versions keyed comments by a random ID alone.
+	db, err := bbolt.Open(path, 0600, nil)
+	assert.NoError(t, err)
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, c := range []models.Comment{
+			{ID: 3911, BugID: 1, Author: "a", Content: "second", CreatedAt: at.Add(5 * time.Minute)},
+			{ID: 27, BugID: 1, Author: "a", Content: "first", CreatedAt: at.Add(time.Minute)},
+		} {
+			data, err := json.Marshal(c)
+			if err != nil {
+				return err
+			}
+			if err := tx.Bucket(commentsBucket).Put(itob(c.ID), data); err != nil {
+				return err
+			}
+		}
+		return (&boltTx{tx: tx}).putHistory(&models.HistoryEntry{ID: 1, BugID: 1, Action: models.HistoryCommented, At: at, CommentID: 3911})
+	})
+	assert.NoError(t, err)
+	db.Close()
+
+	store, err = OpenBolt(path)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	comments, err := store.GetComments(1)
+	assert.NoError(t, err)
+	if assert.Len(t, comments, 2) {
+		assert.Equal(t, 1, comments[0].ID)
+		assert.Equal(t, "first", comments[0].Content)
+		assert.Equal(t, 2, comments[1].ID)
+	}
+	history, err := store.GetHistory(1)
+	assert.NoError(t, err)
+	if assert.Len(t, history, 1) {
+		assert.Equal(t, 2, history[0].CommentID)
+	}
+
+	comment := &models.Comment{Author: "a", Content: "third"}
+	assert.NoError(t, store.CreateComment(1, comment))
+	assert.Equal(t, 3, comment.ID)
+}
+
+func TestBoltRendersLegacyMarkdown(t *testing.T) {
+	defer testutil.CleanupTestDB()
+	path := testutil.GetTestDBPath()
+
+	store, err := OpenBolt(path)
+	assert.NoError(t, err)
+	assert.NoError(t, store.CreateBug(&models.Bug{Title: "Crash", Description: "Fails on **save**"}))
+	assert.NoError(t, store.CreateComment(1, &models.Comment{Author: "a", Content: "Seen it _too_"}))
+	store.Close()
+
+	// Older versions stored neither rendered field.
+	db, err := bbolt.Open(path, 0600, nil)
+	assert.NoError(t, err)
+	err = db.Update(func(tx *bbolt.Tx) error {
+		bug := models.Bug{ID: 1, Title: "Crash", Description: "Fails on **save**", Version: 1}
+		data, err := json.Marshal(bug)
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(bugsBucket).Put(itob(1), data); err != nil {
+			return err
+		}
+		comment := models.Comment{ID: 1, BugID: 1, Author: "a", Content: "Seen it _too_"}
+		if data, err = json.Marshal(comment); err != nil {
+			return err
+		}
+		if err := tx.Bucket(commentsBucket).Put(commentKey(1, 1), data); err != nil {
+			return err
+		}
+		return tx.Bucket(counterBucket).Delete(markdownRenderedKey)
+	})
+	assert.NoError(t, err)
+	db.Close()
+
+	store, err = OpenBolt(path)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	bug, err := store.GetBug(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Fails on <strong>save</strong></p>\n", bug.DescriptionHTML)
+	assert.Equal(t, 1, bug.Version)
+	comments, err := store.GetComments(1)
+	assert.NoError(t, err)
+	if assert.Len(t, comments, 1) {
+		assert.Equal(t, "<p>Seen it <em>too</em></p>\n", comments[0].ContentHTML)
+	}
+}
+
+func TestBoltAssignsLegacyBugsToDefaultProject(t *testing.T) {
+	defer testutil.CleanupTestDB()
+	path := testutil.GetTestDBPath()
+
+	store, err := OpenBolt(path)
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, store.CreateBug(&models.Bug{Title: "Crash"}))
+	}
+	assert.NoError(t, store.DeleteBug(2))
+	store.Close()
+
+	// Older versions had neither projects nor bug numbers.
+	db, err := bbolt.Open(path, 0600, nil)
+	assert.NoError(t, err)
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, id := range []int{1, 3} {
+			data, err := json.Marshal(models.Bug{ID: id, Title: "Crash", Version: 1})
+			if err != nil {
+				return err
+			}
+			if err := tx.Bucket(bugsBucket).Put(itob(id), data); err != nil {
+				return err
+			}
+		}
+		for _, name := range [][]byte{projectsBucket, projectKeysBucket, bugKeysBucket} {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		counters := tx.Bucket(counterBucket)
+		for _, name := range []string{projectCounter, bugNumberCounter(models.DefaultProjectKey), string(projectsAssignedKey)} {
+			if err := counters.Delete([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	db.Close()
+
+	store, err = OpenBolt(path)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	bug, err := store.GetBug(3)
+	assert.NoError(t, err)
+	assert.Equal(t, "BUG-3", bug.Key)
+	id, err := store.GetBugID(models.DefaultProjectKey, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, id)
+
+	bug = &models.Bug{Title: "Hang"}
+	assert.NoError(t, store.CreateBug(bug))
+	assert.Equal(t, "BUG-4", bug.Key)
+}
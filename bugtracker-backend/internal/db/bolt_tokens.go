@@ -0,0 +1,101 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"bugtracker-backend/internal/models"
+)
+
+var (
+	// apiTokensBucket maps token IDs to API tokens and apiTokenHashesBucket
+	// token hashes to token IDs.
+	apiTokensBucket      = []byte("api_tokens")
+	apiTokenHashesBucket = []byte("api_token_hashes")
+)
+
+// boltAPIToken is the stored form of an API token, which unlike the API form
+// includes the token hash.
+type boltAPIToken struct {
+	models.APIToken
+	TokenHash string `json:"token_hash"`
+}
+
+func (t *boltTx) CreateAPIToken(token *models.APIToken) error {
+	return createAPIToken(t, t, token)
+}
+
+func (t *boltTx) getAPIToken(id []byte) (*models.APIToken, error) {
+	v := t.tx.Bucket(apiTokensBucket).Get(id)
+	if v == nil {
+		return nil, ErrTokenNotFound
+	}
+	return decodeBoltAPIToken(v)
+}
+
+func (t *boltTx) GetAPIToken(tokenHash string) (*models.APIToken, error) {
+	id := t.tx.Bucket(apiTokenHashesBucket).Get([]byte(tokenHash))
+	if id == nil {
+		return nil, ErrTokenNotFound
+	}
+	return t.getAPIToken(id)
+}
+
+func (t *boltTx) ListAPITokens(userID int) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := t.tx.Bucket(apiTokensBucket).ForEach(func(k, v []byte) error {
+		token, err := decodeBoltAPIToken(v)
+		if err != nil {
+			return err
+		}
+		if token.UserID == userID {
+			tokens = append(tokens, *token)
+		}
+		return nil
+	})
+	return tokens, err
+}
+
+func (t *boltTx) DeleteAPIToken(userID, id int) error {
+	token, err := t.getAPIToken(itob(id))
+	if err != nil {
+		return err
+	}
+	if token.UserID != userID {
+		return ErrTokenNotFound
+	}
+	if err := t.tx.Bucket(apiTokenHashesBucket).Delete([]byte(token.TokenHash)); err != nil {
+		return err
+	}
+	return t.tx.Bucket(apiTokensBucket).Delete(itob(id))
+}
+
+func (t *boltTx) TouchAPIToken(id int, at time.Time) error {
+	token, err := t.getAPIToken(itob(id))
+	if err != nil {
+		return err
+	}
+	token.LastUsedAt = &at
+	return t.putAPIToken(token)
+}
+
+func (t *boltTx) putAPIToken(token *models.APIToken) error {
+	encoded, err := json.Marshal(boltAPIToken{APIToken: *token, TokenHash: token.TokenHash})
+	if err != nil {
+		return fmt.Errorf("failed to marshal API token: %w", err)
+	}
+	if err := t.tx.Bucket(apiTokensBucket).Put(itob(token.ID), encoded); err != nil {
+		return err
+	}
+	return t.tx.Bucket(apiTokenHashesBucket).Put([]byte(token.TokenHash), itob(token.ID))
+}
+
+func decodeBoltAPIToken(v []byte) (*models.APIToken, error) {
+	var stored boltAPIToken
+	if err := json.Unmarshal(v, &stored); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal API token: %w", err)
+	}
+	stored.APIToken.TokenHash = stored.TokenHash
+	return &stored.APIToken, nil
+}
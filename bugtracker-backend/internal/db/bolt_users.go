@@ -0,0 +1,161 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"bugtracker-backend/internal/models"
+)
+
+var (
+	// usersBucket maps user IDs to users and userNamesBucket usernames to
+	// user IDs.
+	usersBucket     = []byte("users")
+	userNamesBucket = []byte("user_names")
+	// sessionsBucket maps token hashes to sessions.
+	sessionsBucket = []byte("sessions")
+	// identitiesBucket maps identityKey keys to provider identities.
+	identitiesBucket = []byte("identities")
+)
+
+// boltUser is the stored form of a user, which unlike the API form includes
+// the password hash.
+type boltUser struct {
+	models.User
+	PasswordHash string `json:"password_hash"`
+}
+
+func (t *boltTx) CreateUser(user *models.User) error {
+	return createUser(t, t, user)
+}
+
+func (t *boltTx) GetUser(id int) (*models.User, error) {
+	v := t.tx.Bucket(usersBucket).Get(itob(id))
+	if v == nil {
+		return nil, ErrUserNotFound
+	}
+	return decodeBoltUser(v)
+}
+
+func (t *boltTx) GetUserByName(username string) (*models.User, error) {
+	id := t.tx.Bucket(userNamesBucket).Get([]byte(models.NormalizeUsername(username)))
+	if id == nil {
+		return nil, ErrUserNotFound
+	}
+	return t.GetUser(btoi(id))
+}
+
+func (t *boltTx) ListUsers() ([]models.User, error) {
+	var users []models.User
+	err := t.tx.Bucket(usersBucket).ForEach(func(k, v []byte) error {
+		user, err := decodeBoltUser(v)
+		if err != nil {
+			return err
+		}
+		users = append(users, *user)
+		return nil
+	})
+	return users, err
+}
+
+func (t *boltTx) UpdateUser(user *models.User) error {
+	return updateUser(t, t, user)
+}
+
+func (t *boltTx) putUser(user *models.User) error {
+	encoded, err := json.Marshal(boltUser{User: *user, PasswordHash: user.PasswordHash})
+	if err != nil {
+		return fmt.Errorf("failed to marshal user: %w", err)
+	}
+	if err := t.tx.Bucket(usersBucket).Put(itob(user.ID), encoded); err != nil {
+		return err
+	}
+	return t.tx.Bucket(userNamesBucket).Put([]byte(user.Username), itob(user.ID))
+}
+
+func decodeBoltUser(v []byte) (*models.User, error) {
+	var stored boltUser
+	if err := json.Unmarshal(v, &stored); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user: %w", err)
+	}
+	stored.User.PasswordHash = stored.PasswordHash
+	if stored.Role == "" {
+		stored.Role = models.DefaultRole
+	}
+	return &stored.User, nil
+}
+
+// identityKey joins an issuer and subject, neither of which can contain a
+// NUL byte.
+func identityKey(issuer, subject string) []byte {
+	return []byte(issuer + "\x00" + subject)
+}
+
+func (t *boltTx) CreateIdentity(identity *models.Identity) error {
+	encoded, err := json.Marshal(identity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal identity: %w", err)
+	}
+	return t.tx.Bucket(identitiesBucket).Put(identityKey(identity.Issuer, identity.Subject), encoded)
+}
+
+func (t *boltTx) GetIdentity(issuer, subject string) (*models.Identity, error) {
+	v := t.tx.Bucket(identitiesBucket).Get(identityKey(issuer, subject))
+	if v == nil {
+		return nil, ErrIdentityNotFound
+	}
+	var identity models.Identity
+	if err := json.Unmarshal(v, &identity); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal identity: %w", err)
+	}
+	return &identity, nil
+}
+
+func (t *boltTx) CreateSession(session *models.Session) error {
+	encoded, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	return t.tx.Bucket(sessionsBucket).Put([]byte(session.TokenHash), encoded)
+}
+
+func (t *boltTx) GetSession(tokenHash string) (*models.Session, error) {
+	v := t.tx.Bucket(sessionsBucket).Get([]byte(tokenHash))
+	if v == nil {
+		return nil, ErrSessionNotFound
+	}
+	var session models.Session
+	if err := json.Unmarshal(v, &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+	}
+	return &session, nil
+}
+
+func (t *boltTx) DeleteSession(tokenHash string) error {
+	return t.tx.Bucket(sessionsBucket).Delete([]byte(tokenHash))
+}
+
+func (t *boltTx) DeleteExpiredSessions(now time.Time) (int, error) {
+	b := t.tx.Bucket(sessionsBucket)
+	var expired [][]byte
+	err := b.ForEach(func(k, v []byte) error {
+		var session models.Session
+		if err := json.Unmarshal(v, &session); err != nil {
+			return fmt.Errorf("failed to unmarshal session: %w", err)
+		}
+		if session.Expired(now) {
+			expired = append(expired, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, k := range expired {
+		if err := b.Delete(k); err != nil {
+			return 0, err
+		}
+	}
+	return len(expired), nil
+}
@@ -1,65 +1,339 @@
 package db
 
 import (
-	"bugtracker-backend/internal/models"
-	"encoding/json"
-	"fmt"
+	"errors"
+	"sort"
 	"strconv"
 	"time"
 
-	"github.com/google/uuid"
-	"go.etcd.io/bbolt"
+	"bugtracker-backend/internal/models"
 )
 
-func CreateComment(bugID string, comment *models.Comment) error {
-	comment.CreatedAt = time.Now()
-	comment.ID = int(uuid.New().ID())
-	var err error
-	comment.BugID, err = strconv.Atoi(bugID)
+// commentCounter numbers comments across all bugs, so comment IDs are
+// unique and increase in the order comments are written.
+const commentCounter = "lastCommentID"
+
+// commentStore is implemented by every backend's transaction type to give
+// the shared maintenance code below raw access to comments, including those
+// whose bug no longer exists.
+type commentStore interface {
+	bugLoader
+	allComments() ([]models.Comment, error)
+	deleteComment(bugID, id int) error
+	// scanBugComments calls fn for a bug's comments in ID order, or reverse
+	// order if descending, starting after the comment afterID (or at the
+	// first one if it is 0) until fn returns false.
+	scanBugComments(bugID, afterID int, descending bool, fn func(comment models.Comment) bool) error
+	// commentCounts returns the number of comments stored under each bug.
+	commentCounts(bugIDs []int) (map[int]int, error)
+	// getComment returns ErrCommentNotFound if there is no such comment.
+	getComment(bugID, id int) (*models.Comment, error)
+	// putComment saves an existing comment.
+	putComment(comment *models.Comment) error
+	putCommentRevision(rev *models.CommentRevision) error
+	commentRevisions(commentID int) ([]models.CommentRevision, error)
+	deleteCommentRevisions(commentID int) error
+}
+
+// setCommentParent checks that a reply's parent is a comment on the same
+// bug that has not been deleted, and places the reply one level below it.
+func setCommentParent(cs commentStore, bugID int, comment *models.Comment) error {
+	comment.Depth = 0
+	if comment.ParentID == 0 {
+		return nil
+	}
+	parent, err := cs.getComment(bugID, comment.ParentID)
+	if errors.Is(err, ErrCommentNotFound) {
+		return ErrInvalidParent
+	}
 	if err != nil {
-		return fmt.Errorf("invalid bug ID format")
+		return err
 	}
+	if parent.DeletedAt != nil {
+		return ErrCommentDeleted
+	}
+	comment.Depth = parent.Depth + 1
+	return nil
+}
 
-	_, err = GetBug(comment.BugID)
+// getLiveComment returns a comment of a bug that is not in the trash.
+func getLiveComment(cs commentStore, bugID, id int) (*models.Comment, error) {
+	bug, err := cs.loadBug(bugID)
 	if err != nil {
-		return fmt.Errorf("bug not found")
+		return nil, err
+	}
+	if bug.DeletedAt != nil {
+		return nil, ErrBugNotFound
 	}
+	return cs.getComment(bugID, id)
+}
 
-	return db.Update(func(tx *bbolt.Tx) error {
-		b := tx.Bucket(commentsBucket)
-		encoded, err := json.Marshal(comment)
-		if err != nil {
-			return fmt.Errorf("failed to marshal comment: %v", err)
-		}
-		return b.Put(itob(comment.ID), encoded)
+func editComment(tx Tx, cs commentStore, idx searchIndex, bugID, id int, content string, at time.Time) (*models.Comment, error) {
+	comment, err := getLiveComment(cs, bugID, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.DeletedAt != nil {
+		return nil, ErrCommentDeleted
+	}
+	if comment.Content == content {
+		return comment, nil
+	}
+
+	revs, err := cs.commentRevisions(id)
+	if err != nil {
+		return nil, err
+	}
+	written := comment.CreatedAt
+	if comment.UpdatedAt != nil {
+		written = *comment.UpdatedAt
+	}
+	err = cs.putCommentRevision(&models.CommentRevision{
+		CommentID: id,
+		Revision:  len(revs) + 1,
+		Content:   comment.Content,
+		CreatedAt: written,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	comment.Content = content
+	comment.RenderContent()
+	comment.UpdatedAt = &at
+	comment.Edited = true
+	if err := cs.putComment(comment); err != nil {
+		return nil, err
+	}
+	return comment, indexBugForSearch(tx, idx, bugID)
+}
+
+// trashComment leaves a tombstone in the comment's place, keeping its
+// content and revisions until the trash is purged. Deleting a comment twice
+// keeps the first deletion.
+func trashComment(tx Tx, cs commentStore, idx searchIndex, bugID, id int, by string, at time.Time) (*models.Comment, error) {
+	comment, err := getLiveComment(cs, bugID, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.DeletedAt != nil {
+		return comment, nil
+	}
+
+	comment.DeletedAt = &at
+	comment.DeletedBy = by
+	if err := cs.putComment(comment); err != nil {
+		return nil, err
+	}
+	return comment, indexBugForSearch(tx, idx, bugID)
+}
+
+func restoreComment(tx Tx, cs commentStore, idx searchIndex, bugID, id int) (*models.Comment, error) {
+	comment, err := getLiveComment(cs, bugID, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.DeletedAt == nil {
+		return comment, nil
+	}
+	if commentPurged(comment) {
+		return nil, ErrCommentPurged
+	}
+
+	comment.DeletedAt = nil
+	comment.DeletedBy = ""
+	if err := cs.putComment(comment); err != nil {
+		return nil, err
+	}
+	return comment, indexBugForSearch(tx, idx, bugID)
+}
+
+// commentPurged reports whether a deleted comment's content has been
+// purged. Comments deleted by older versions lost their content at once.
+func commentPurged(comment *models.Comment) bool {
+	return comment.DeletedAt != nil && comment.Content == ""
+}
+
+// purgeDeletedComments removes the content and revisions of comments
+// deleted before the given time, leaving bare tombstones, and returns how
+// many there were.
+func purgeDeletedComments(cs commentStore, before time.Time) (int, error) {
+	comments, err := cs.allComments()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, c := range comments {
+		if c.DeletedAt == nil || commentPurged(&c) || !c.DeletedAt.Before(before) {
+			continue
+		}
+		if err := cs.deleteCommentRevisions(c.ID); err != nil {
+			return count, err
+		}
+		c.Content = ""
+		c.ContentHTML = ""
+		if err := cs.putComment(&c); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func getCommentRevisions(cs commentStore, bugID, id int) ([]models.CommentRevision, error) {
+	if _, err := getLiveComment(cs, bugID, id); err != nil {
+		return nil, err
+	}
+	return cs.commentRevisions(id)
+}
+
+// CommentQuery selects a page of a bug's comments.
+type CommentQuery struct {
+	Descending bool
+	// Limit caps the number of comments returned; 0 returns them all.
+	Limit  int
+	Cursor string
+}
+
+func (q *CommentQuery) Validate() error {
+	if q.Limit < 0 {
+		return errors.New("limit must not be negative")
+	}
+	if q.Cursor != "" {
+		if _, err := decodeCommentCursor(q.Cursor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A comment cursor is the ID of the last comment on the previous page.
+func encodeCommentCursor(id int) string {
+	return strconv.Itoa(id)
+}
+
+func decodeCommentCursor(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
 }
 
-func GetComments(bugID string) ([]models.Comment, error) {
+// listComments returns a page of a live bug's comments and the cursor for
+// the next page, if there is one.
+func listComments(cs commentStore, bugID int, q CommentQuery) ([]models.Comment, string, error) {
+	if err := q.Validate(); err != nil {
+		return nil, "", err
+	}
+	bug, err := cs.loadBug(bugID)
+	if err != nil {
+		return nil, "", err
+	}
+	if bug.DeletedAt != nil {
+		return nil, "", ErrBugNotFound
+	}
+
+	var after int
+	if q.Cursor != "" {
+		after, _ = decodeCommentCursor(q.Cursor)
+	}
 	var comments []models.Comment
-	bugIDInt, err := strconv.Atoi(bugID)
+	err = cs.scanBugComments(bugID, after, q.Descending, func(c models.Comment) bool {
+		comments = append(comments, c)
+		return q.Limit == 0 || len(comments) <= q.Limit
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if q.Limit == 0 || len(comments) <= q.Limit {
+		return comments, "", nil
+	}
+	comments = comments[:q.Limit]
+	return comments, encodeCommentCursor(comments[len(comments)-1].ID), nil
+}
+
+// fillCommentCounts sets the CommentCount of each bug.
+func fillCommentCounts(cs commentStore, bugs ...*models.Bug) error {
+	if len(bugs) == 0 {
+		return nil
+	}
+	ids := make([]int, len(bugs))
+	for i, bug := range bugs {
+		ids[i] = bug.ID
+	}
+	counts, err := cs.commentCounts(ids)
 	if err != nil {
-		return nil, fmt.Errorf("invalid bug ID format")
+		return err
+	}
+	for _, bug := range bugs {
+		bug.CommentCount = counts[bug.ID]
 	}
+	return nil
+}
 
-	_, err = GetBug(bugIDInt)
+// commentOrphaned reports whether comment belongs to no current bug: either
+// its bug is gone, or the bug's ID has since been reused by a bug created
+// after the comment was written. Comments of trashed bugs are not orphaned.
+func commentOrphaned(l bugLoader, comment *models.Comment) (bool, error) {
+	bug, err := l.loadBug(comment.BugID)
+	if errors.Is(err, ErrBugNotFound) {
+		return true, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("bug not found")
-	}
-
-	err = db.View(func(tx *bbolt.Tx) error {
-		b := tx.Bucket(commentsBucket)
-		return b.ForEach(func(k, v []byte) error {
-			var comment models.Comment
-			if err := json.Unmarshal(v, &comment); err != nil {
-				return err
-			}
-			if comment.BugID == bugIDInt {
-				comments = append(comments, comment)
-			}
-			return nil
-		})
+		return false, err
+	}
+	return comment.CreatedAt.Before(bug.CreatedAt), nil
+}
+
+func orphanedComments(cs commentStore) ([]models.Comment, error) {
+	comments, err := cs.allComments()
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []models.Comment
+	for _, c := range comments {
+		orphaned, err := commentOrphaned(cs, &c)
+		if err != nil {
+			return nil, err
+		}
+		if orphaned {
+			orphans = append(orphans, c)
+		}
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		if orphans[i].BugID != orphans[j].BugID {
+			return orphans[i].BugID < orphans[j].BugID
+		}
+		return orphans[i].CreatedAt.Before(orphans[j].CreatedAt)
 	})
+	return orphans, nil
+}
 
-	return comments, err
+// purgeOrphanedComments deletes every orphaned comment and reindexes the
+// bugs that had inherited some of them.
+func purgeOrphanedComments(tx Tx, cs commentStore, idx searchIndex) (int, error) {
+	orphans, err := orphanedComments(cs)
+	if err != nil {
+		return 0, err
+	}
+
+	reindex := make(map[int]bool)
+	for _, c := range orphans {
+		if err := cs.deleteComment(c.BugID, c.ID); err != nil {
+			return 0, err
+		}
+		if _, err := tx.GetBug(c.BugID); err == nil {
+			reindex[c.BugID] = true
+		}
+	}
+	for id := range reindex {
+		if err := indexBugForSearch(tx, idx, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(orphans), nil
 }
@@ -2,128 +2,409 @@ package db
 
 import (
 	"bugtracker-backend/internal/models"
-	"strconv"
+	"encoding/json"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestCreateComment(t *testing.T) {
-	cleanup := SetupTestDB(t)
-	defer cleanup()
-
-	bug := &models.Bug{
-		Title:       "Test Bug",
-		Description: "Test Description",
-	}
-	err := CreateBug(bug)
-	assert.NoError(t, err)
+	forEachBackend(t, func(t *testing.T, store Store) {
+		bug := &models.Bug{
+			Title:       "Test Bug",
+			Description: "Test Description",
+		}
+		err := store.CreateBug(bug)
+		assert.NoError(t, err)
 
-	tests := []struct {
-		name       string
-		bugID      string
-		comment    *models.Comment
-		shouldErr  bool
-		errMessage string
-	}{
-		{
-			name:  "Valid comment creation",
-			bugID: strconv.Itoa(bug.ID),
-			comment: &models.Comment{
-				Author:  "Test User",
-				Content: "Test Comment",
-			},
-			shouldErr: false,
-		},
-		{
-			name:  "Invalid bug ID",
-			bugID: "999",
-			comment: &models.Comment{
-				Author:  "Test User",
-				Content: "Test Comment",
+		tests := []struct {
+			name       string
+			bugID      int
+			comment    *models.Comment
+			shouldErr  bool
+			errMessage string
+		}{
+			{
+				name:  "Valid comment creation",
+				bugID: bug.ID,
+				comment: &models.Comment{
+					Author:  "Test User",
+					Content: "Test Comment",
+				},
+				shouldErr: false,
 			},
-			shouldErr:  true,
-			errMessage: "bug not found",
-		},
-		{
-			name:  "Invalid bug ID format",
-			bugID: "invalid",
-			comment: &models.Comment{
-				Author:  "Test User",
-				Content: "Test Comment",
+			{
+				name:  "Invalid bug ID",
+				bugID: 999,
+				comment: &models.Comment{
+					Author:  "Test User",
+					Content: "Test Comment",
+				},
+				shouldErr:  true,
+				errMessage: "bug not found",
 			},
-			shouldErr:  true,
-			errMessage: "invalid bug ID format",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := CreateComment(tt.bugID, tt.comment)
-			if tt.shouldErr {
-				assert.Error(t, err)
-				assert.Contains(t, err.Error(), tt.errMessage)
-			} else {
-				assert.NoError(t, err)
-				assert.NotEmpty(t, tt.comment.ID)
-				assert.NotEmpty(t, tt.comment.CreatedAt)
-				expectedBugID, _ := strconv.Atoi(tt.bugID)
-				assert.Equal(t, expectedBugID, tt.comment.BugID)
-			}
-		})
-	}
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := store.CreateComment(tt.bugID, tt.comment)
+				if tt.shouldErr {
+					assert.Error(t, err)
+					assert.Contains(t, err.Error(), tt.errMessage)
+				} else {
+					assert.NoError(t, err)
+					assert.NotEmpty(t, tt.comment.ID)
+					assert.NotEmpty(t, tt.comment.CreatedAt)
+					assert.Equal(t, tt.bugID, tt.comment.BugID)
+				}
+			})
+		}
+	})
 }
 
 func TestGetComments(t *testing.T) {
-	cleanup := SetupTestDB(t)
-	defer cleanup()
-
-	bug := &models.Bug{
-		Title:       "Test Bug",
-		Description: "Test Description",
-	}
-	err := CreateBug(bug)
+	forEachBackend(t, func(t *testing.T, store Store) {
+		bug := &models.Bug{
+			Title:       "Test Bug",
+			Description: "Test Description",
+		}
+		err := store.CreateBug(bug)
+		assert.NoError(t, err)
+
+		testComments := []*models.Comment{
+			{Author: "User1", Content: "Comment 1"},
+			{Author: "User2", Content: "Comment 2"},
+		}
+
+		for _, comment := range testComments {
+			err := store.CreateComment(bug.ID, comment)
+			assert.NoError(t, err)
+		}
+
+		tests := []struct {
+			name        string
+			bugID       int
+			wantErr     bool
+			expectedErr string
+		}{
+			{
+				name:    "Get existing comments",
+				bugID:   bug.ID,
+				wantErr: false,
+			},
+			{
+				name:        "Get comments for non-existent bug",
+				bugID:       999,
+				wantErr:     true,
+				expectedErr: "bug not found",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				comments, err := store.GetComments(tt.bugID)
+				if tt.wantErr {
+					assert.Error(t, err)
+					assert.Equal(t, tt.expectedErr, err.Error())
+				} else {
+					assert.NoError(t, err)
+					assert.Len(t, comments, len(testComments))
+				}
+			})
+		}
+	})
+}
+
+func TestDeleteBugDeletesComments(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		doomed := &models.Bug{Title: "Doomed"}
+		kept := &models.Bug{Title: "Kept"}
+		assert.NoError(t, store.CreateBug(doomed))
+		assert.NoError(t, store.CreateBug(kept))
+		assert.NoError(t, store.CreateComment(doomed.ID, &models.Comment{Author: "a", Content: "gone"}))
+		assert.NoError(t, store.CreateComment(kept.ID, &models.Comment{Author: "a", Content: "stays"}))
+
+		assert.NoError(t, store.DeleteBug(doomed.ID))
+
+		orphans, err := store.OrphanedComments()
+		assert.NoError(t, err)
+		assert.Empty(t, orphans)
+		comments, err := store.GetComments(kept.ID)
+		assert.NoError(t, err)
+		assert.Len(t, comments, 1)
+
+		// IDs are not reused, so a new bug starts without comments.
+		fresh := &models.Bug{Title: "Fresh"}
+		assert.NoError(t, store.CreateBug(fresh))
+		assert.Equal(t, 3, fresh.ID)
+		comments, err = store.GetComments(fresh.ID)
+		assert.NoError(t, err)
+		assert.Empty(t, comments)
+	})
+}
+
+// putRawComment stores a comment without any checks, as older versions did
+// for comments that outlived their bug.
+func putRawComment(t *testing.T, store Store, c models.Comment) {
+	err := store.Update(func(tx Tx) error {
+		switch tx := tx.(type) {
+		case *boltTx:
+			data, err := json.Marshal(c)
+			if err != nil {
+				return err
+			}
+			return tx.tx.Bucket(commentsBucket).Put(commentKey(c.BugID, c.ID), data)
+		case *sqliteTx:
+			_, err := tx.tx.Exec(`INSERT INTO comments (id, bug_id, content, author, created_at) VALUES (?, ?, ?, ?, ?)`,
+				c.ID, c.BugID, c.Content, c.Author, formatSortableTime(c.CreatedAt))
+			return err
+		case *memoryTx:
+			tx.state.comments[c.BugID] = append(tx.state.comments[c.BugID], c)
+		}
+		return nil
+	})
 	assert.NoError(t, err)
+}
+
+func TestPurgeOrphanedComments(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		now := time.Now()
+		bug := &models.Bug{Title: "Current", CreatedAt: now}
+		assert.NoError(t, store.CreateBug(bug))
+		assert.NoError(t, store.CreateComment(bug.ID, &models.Comment{Author: "a", Content: "current comment"}))
+
+		putRawComment(t, store, models.Comment{ID: 101, BugID: 42, Author: "a", Content: "deleted bug", CreatedAt: now})
+		putRawComment(t, store, models.Comment{ID: 102, BugID: bug.ID, Author: "a", Content: "inherited secret", CreatedAt: now.Add(-time.Hour)})
+		_, err := store.RebuildSearchIndex()
+		assert.NoError(t, err)
+
+		orphans, err := store.OrphanedComments()
+		assert.NoError(t, err)
+		ids := []int{}
+		for _, c := range orphans {
+			ids = append(ids, c.ID)
+		}
+		assert.Equal(t, []int{102, 101}, ids)
+
+		count, err := store.PurgeOrphanedComments()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		orphans, err = store.OrphanedComments()
+		assert.NoError(t, err)
+		assert.Empty(t, orphans)
+		comments, err := store.GetComments(bug.ID)
+		assert.NoError(t, err)
+		if assert.Len(t, comments, 1) {
+			assert.Equal(t, "current comment", comments[0].Content)
+		}
+		hits, err := store.SearchBugs("secret", 0)
+		assert.NoError(t, err)
+		assert.Empty(t, hits)
+	})
+}
+
+func TestCommentIDsAreSequential(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		first := &models.Bug{Title: "First"}
+		second := &models.Bug{Title: "Second"}
+		assert.NoError(t, store.CreateBug(first))
+		assert.NoError(t, store.CreateBug(second))
+
+		var ids []int
+		for i, bugID := range []int{second.ID, first.ID, second.ID, second.ID} {
+			comment := &models.Comment{Author: "a", Content: fmt.Sprintf("comment %d", i)}
+			assert.NoError(t, store.CreateComment(bugID, comment))
+			ids = append(ids, comment.ID)
+		}
+		assert.Equal(t, []int{1, 2, 3, 4}, ids)
+
+		comments, err := store.GetComments(second.ID)
+		assert.NoError(t, err)
+		contents := []string{}
+		for _, c := range comments {
+			contents = append(contents, c.Content)
+		}
+		assert.Equal(t, []string{"comment 0", "comment 2", "comment 3"}, contents)
 
-	testComments := []*models.Comment{
-		{Author: "User1", Content: "Comment 1"},
-		{Author: "User2", Content: "Comment 2"},
-	}
-
-	for _, comment := range testComments {
-		err := CreateComment(strconv.Itoa(bug.ID), comment)
-		assert.NoError(t, err)
-	}
-
-	tests := []struct {
-		name        string
-		bugID       string
-		wantErr     bool
-		expectedErr string
-	}{
-		{
-			name:    "Get existing comments",
-			bugID:   strconv.Itoa(bug.ID),
-			wantErr: false,
-		},
-		{
-			name:        "Get comments for non-existent bug",
-			bugID:       "999",
-			wantErr:     true,
-			expectedErr: "bug not found",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			comments, err := GetComments(tt.bugID)
-			if tt.wantErr {
-				assert.Error(t, err)
-				assert.Equal(t, tt.expectedErr, err.Error())
-			} else {
-				assert.NoError(t, err)
-				assert.Len(t, comments, len(testComments))
+		// IDs are not reused after the bugs are deleted.
+		assert.NoError(t, store.DeleteBug(first.ID))
+		assert.NoError(t, store.DeleteBug(second.ID))
+		third := &models.Bug{Title: "Third"}
+		assert.NoError(t, store.CreateBug(third))
+		comment := &models.Comment{Author: "a", Content: "later"}
+		assert.NoError(t, store.CreateComment(third.ID, comment))
+		assert.Equal(t, 5, comment.ID)
+	})
+}
+
+func TestListComments(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		first := &models.Bug{Title: "First"}
+		second := &models.Bug{Title: "Second"}
+		assert.NoError(t, store.CreateBug(first))
+		assert.NoError(t, store.CreateBug(second))
+		for i := 1; i <= 3; i++ {
+			assert.NoError(t, store.CreateComment(first.ID, &models.Comment{Author: "a", Content: "first"}))
+			assert.NoError(t, store.CreateComment(second.ID, &models.Comment{Author: "a", Content: "second"}))
+		}
+
+		pageIDs := func(bugID int, q CommentQuery) ([]int, string) {
+			comments, next, err := store.ListComments(bugID, q)
+			assert.NoError(t, err)
+			ids := []int{}
+			for _, c := range comments {
+				assert.Equal(t, bugID, c.BugID)
+				ids = append(ids, c.ID)
 			}
-		})
-	}
+			return ids, next
+		}
+
+		ids, next := pageIDs(second.ID, CommentQuery{Limit: 2})
+		assert.Equal(t, []int{2, 4}, ids)
+		ids, next = pageIDs(second.ID, CommentQuery{Limit: 2, Cursor: next})
+		assert.Equal(t, []int{6}, ids)
+		assert.Empty(t, next)
+
+		ids, next = pageIDs(first.ID, CommentQuery{Limit: 2, Descending: true})
+		assert.Equal(t, []int{5, 3}, ids)
+		ids, next = pageIDs(first.ID, CommentQuery{Limit: 2, Descending: true, Cursor: next})
+		assert.Equal(t, []int{1}, ids)
+		assert.Empty(t, next)
+		ids, _ = pageIDs(second.ID, CommentQuery{Descending: true})
+		assert.Equal(t, []int{6, 4, 2}, ids)
+
+		_, _, err := store.ListComments(first.ID, CommentQuery{Cursor: "x"})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+		_, _, err = store.ListComments(999, CommentQuery{})
+		assert.ErrorIs(t, err, ErrBugNotFound)
+
+		bug, err := store.GetBug(first.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, bug.CommentCount)
+		bugs, _, err := store.ListBugs(BugQuery{})
+		assert.NoError(t, err)
+		for _, bug := range bugs {
+			assert.Equal(t, 3, bug.CommentCount)
+		}
+	})
+}
+
+func TestEditAndDeleteComment(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		bug := &models.Bug{Title: "Crash"}
+		assert.NoError(t, store.CreateBug(bug))
+		comment := &models.Comment{Author: "alice", Content: "first draft"}
+		assert.NoError(t, store.CreateComment(bug.ID, comment))
+		other := &models.Comment{Author: "bob", Content: "unrelated"}
+		assert.NoError(t, store.CreateComment(bug.ID, other))
+
+		at := time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+		edited, err := store.EditComment(bug.ID, comment.ID, "second draft", at)
+		assert.NoError(t, err)
+		assert.True(t, edited.Edited)
+		assert.Equal(t, "second draft", edited.Content)
+		_, err = store.EditComment(bug.ID, comment.ID, "final", at.Add(time.Minute))
+		assert.NoError(t, err)
+
+		got, err := store.GetComment(bug.ID, comment.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "final", got.Content)
+		if assert.NotNil(t, got.UpdatedAt) {
+			assert.True(t, got.UpdatedAt.Equal(at.Add(time.Minute)))
+		}
+
+		revs, err := store.GetCommentRevisions(bug.ID, comment.ID)
+		assert.NoError(t, err)
+		if assert.Len(t, revs, 2) {
+			assert.Equal(t, 1, revs[0].Revision)
+			assert.Equal(t, "first draft", revs[0].Content)
+			assert.True(t, revs[0].CreatedAt.Equal(comment.CreatedAt))
+			assert.Equal(t, "second draft", revs[1].Content)
+			assert.True(t, revs[1].CreatedAt.Equal(at))
+		}
+
+		hits, err := store.SearchBugs("final", 0)
+		assert.NoError(t, err)
+		assert.Len(t, hits, 1)
+
+		deleted, err := store.DeleteComment(bug.ID, comment.ID, "admin", at.Add(2*time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, "admin", deleted.DeletedBy)
+		// The content and revisions are kept so the comment can be restored.
+		assert.Equal(t, "final", deleted.Content)
+		revs, err = store.GetCommentRevisions(bug.ID, comment.ID)
+		assert.NoError(t, err)
+		assert.Len(t, revs, 2)
+		_, err = store.EditComment(bug.ID, comment.ID, "undead", at)
+		assert.ErrorIs(t, err, ErrCommentDeleted)
+
+		// The tombstone keeps its place among the bug's comments.
+		comments, err := store.GetComments(bug.ID)
+		assert.NoError(t, err)
+		if assert.Len(t, comments, 2) {
+			assert.Equal(t, comment.ID, comments[0].ID)
+			assert.NotNil(t, comments[0].DeletedAt)
+			assert.Equal(t, "unrelated", comments[1].Content)
+		}
+		hits, err = store.SearchBugs("final", 0)
+		assert.NoError(t, err)
+		assert.Empty(t, hits)
+
+		restored, err := store.RestoreComment(bug.ID, comment.ID)
+		assert.NoError(t, err)
+		assert.Nil(t, restored.DeletedAt)
+		assert.Empty(t, restored.DeletedBy)
+		assert.Equal(t, "final", restored.Content)
+		hits, err = store.SearchBugs("final", 0)
+		assert.NoError(t, err)
+		assert.Len(t, hits, 1)
+		_, err = store.RestoreComment(bug.ID, comment.ID)
+		assert.NoError(t, err)
+
+		_, err = store.GetComment(bug.ID, 999)
+		assert.ErrorIs(t, err, ErrCommentNotFound)
+		_, err = store.GetComment(999, comment.ID)
+		assert.ErrorIs(t, err, ErrBugNotFound)
+	})
+}
+
+func TestCommentReplies(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		bug := &models.Bug{Title: "Crash"}
+		other := &models.Bug{Title: "Other"}
+		assert.NoError(t, store.CreateBug(bug))
+		assert.NoError(t, store.CreateBug(other))
+
+		root := &models.Comment{Author: "a", Content: "root"}
+		assert.NoError(t, store.CreateComment(bug.ID, root))
+		reply := &models.Comment{Author: "b", Content: "reply", ParentID: root.ID}
+		assert.NoError(t, store.CreateComment(bug.ID, reply))
+		nested := &models.Comment{Author: "a", Content: "nested", ParentID: reply.ID}
+		assert.NoError(t, store.CreateComment(bug.ID, nested))
+		assert.Equal(t, 1, reply.Depth)
+		assert.Equal(t, 2, nested.Depth)
+
+		got, err := store.GetComment(bug.ID, nested.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, reply.ID, got.ParentID)
+		assert.Equal(t, 2, got.Depth)
+
+		err = store.CreateComment(other.ID, &models.Comment{Author: "a", Content: "elsewhere", ParentID: root.ID})
+		assert.ErrorIs(t, err, ErrInvalidParent)
+		err = store.CreateComment(bug.ID, &models.Comment{Author: "a", Content: "nowhere", ParentID: 999})
+		assert.ErrorIs(t, err, ErrInvalidParent)
+
+		// Replies stay under a deleted parent, which takes no new ones.
+		_, err = store.DeleteComment(bug.ID, reply.ID, "b", time.Now())
+		assert.NoError(t, err)
+		err = store.CreateComment(bug.ID, &models.Comment{Author: "a", Content: "too late", ParentID: reply.ID})
+		assert.ErrorIs(t, err, ErrCommentDeleted)
+		got, err = store.GetComment(bug.ID, nested.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "nested", got.Content)
+		assert.Equal(t, reply.ID, got.ParentID)
+	})
 }
@@ -3,96 +3,245 @@ package db
 import (
 	"os"
 	"testing"
+	"time"
 
+	"bugtracker-backend/internal/config"
 	"bugtracker-backend/internal/models"
 	"bugtracker-backend/internal/testutil"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func TestDatabaseInitialization(t *testing.T) {
-	os.Setenv("DB_PATH", testutil.GetTestDBPath())
-	defer testutil.CleanupTestDB()
-
-	err := Init()
-	assert.NoError(t, err)
-	defer func() {
-		CleanupTestDB()
-		Cleanup()
-	}()
+// persistentBackends are the backends that keep their data in a file.
+var persistentBackends = []string{config.BackendBolt, config.BackendSQLite}
+
+// forEachBackend runs fn as a subtest against a fresh store of every backend.
+func forEachBackend(t *testing.T, fn func(t *testing.T, store Store)) {
+	for _, backend := range TestBackends {
+		t.Run(backend, func(t *testing.T) {
+			store, cleanup := SetupTestStore(t, backend)
+			defer cleanup()
+			fn(t, store)
+		})
+	}
+}
 
-	bug := &models.Bug{Title: "Test", Description: "Test"}
-	err = CreateBug(bug)
-	assert.NoError(t, err)
+func TestDatabaseInitialization(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		bug := &models.Bug{Title: "Test", Description: "Test"}
+		err := store.CreateBug(bug)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, bug.ID)
+	})
 }
 
 func TestMultipleInitializations(t *testing.T) {
-	os.Setenv("DB_PATH", testutil.GetTestDBPath())
 	defer testutil.CleanupTestDB()
 
-	err := Init()
-	assert.NoError(t, err)
-	Cleanup()
-
-	err = Init()
-	assert.NoError(t, err)
-	defer func() {
-		CleanupTestDB()
-		Cleanup()
-	}()
+	for _, backend := range persistentBackends {
+		t.Run(backend, func(t *testing.T) {
+			path := testutil.GetTestDBPath()
+
+			store, err := Open(backend, path)
+			assert.NoError(t, err)
+			err = store.CreateBug(&models.Bug{Title: "Test"})
+			assert.NoError(t, err)
+			store.Close()
+
+			store, err = Open(backend, path)
+			assert.NoError(t, err)
+			defer store.Close()
+
+			bug, err := store.GetBug(1)
+			assert.NoError(t, err)
+			assert.Equal(t, "Test", bug.Title)
+		})
+	}
 }
 
 func TestCleanup(t *testing.T) {
-	os.Setenv("DB_PATH", testutil.GetTestDBPath())
 	defer testutil.CleanupTestDB()
 
-	err := Init()
-	assert.NoError(t, err)
-	Cleanup()
-
-	// Test DB is inaccessible after cleanup
-	bug := &models.Bug{Title: "Test", Description: "Test"}
-	err = CreateBug(bug)
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "database not initialized",
-		"Should get 'database not initialized' error after cleanup")
+	for _, backend := range TestBackends {
+		t.Run(backend, func(t *testing.T) {
+			store, err := Open(backend, testutil.GetTestDBPath())
+			assert.NoError(t, err)
+			store.Close()
+
+			// Test DB is inaccessible after cleanup
+			bug := &models.Bug{Title: "Test", Description: "Test"}
+			err = store.CreateBug(bug)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "database not initialized",
+				"Should get 'database not initialized' error after cleanup")
+		})
+	}
 }
 
 func TestInitWithInvalidPath(t *testing.T) {
-	originalPath := os.Getenv("DB_PATH")
-	originalDBPath := databasePath
-	defer func() {
-		os.Setenv("DB_PATH", originalPath)
-		databasePath = originalDBPath
-	}()
-
-	invalidPath := "/invalid/path/db.sqlite"
-	t.Setenv("DB_PATH", invalidPath)
-	databasePath = invalidPath
-
-	err := Init()
+	for _, backend := range persistentBackends {
+		t.Run(backend, func(t *testing.T) {
+			_, err := Open(backend, "/invalid/path/db.sqlite")
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "failed to open database")
+		})
+	}
+}
+
+func TestUnknownBackend(t *testing.T) {
+	_, err := Open("postgres", testutil.GetTestDBPath())
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "failed to open database")
-	defer func() {
-		CleanupTestDB()
-		Cleanup()
-	}()
+	assert.Contains(t, err.Error(), "unknown storage backend")
 }
 
-func TestConcurrentInitializations(t *testing.T) {
-	os.Setenv("DB_PATH", testutil.GetTestDBPath())
+func TestIndependentStores(t *testing.T) {
 	defer testutil.CleanupTestDB()
 
-	err := Init()
+	first, err := OpenBolt(testutil.GetTestDBPath())
 	assert.NoError(t, err)
-	defer func() {
-		CleanupTestDB()
-		Cleanup()
-	}()
+	defer first.Close()
 
-	err = Init()
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "database already initialized")
+	second, err := OpenBolt(testutil.GetTestDBPath())
+	assert.NoError(t, err)
+	defer second.Close()
+
+	assert.NoError(t, first.CreateBug(&models.Bug{Title: "First"}))
+
+	bugs, err := second.GetAllBugs()
+	assert.NoError(t, err)
+	assert.Empty(t, bugs)
+}
+
+func TestBugCRUD(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		bug := &models.Bug{Title: "Original", Status: "Open", Priority: "Low"}
+		assert.NoError(t, store.CreateBug(bug))
+
+		bug.Title = "Updated"
+		bug.Priority = "High"
+		assert.NoError(t, store.UpdateBug(bug))
+
+		got, err := store.GetBug(bug.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Updated", got.Title)
+		assert.Equal(t, "High", got.Priority)
+
+		assert.ErrorIs(t, store.UpdateBug(&models.Bug{ID: 999}), ErrBugNotFound)
+
+		assert.NoError(t, store.DeleteBug(bug.ID))
+		_, err = store.GetBug(bug.ID)
+		assert.ErrorIs(t, err, ErrBugNotFound)
+		assert.ErrorIs(t, store.DeleteBug(bug.ID), ErrBugNotFound)
+	})
+}
+
+func TestBugVersions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		bug := &models.Bug{Title: "Original"}
+		assert.NoError(t, store.CreateBug(bug))
+		assert.Equal(t, 1, bug.Version)
+
+		first, err := store.GetBug(bug.ID)
+		assert.NoError(t, err)
+		second, err := store.GetBug(bug.ID)
+		assert.NoError(t, err)
+
+		first.Title = "First edit"
+		assert.NoError(t, store.UpdateBug(first))
+		assert.Equal(t, 2, first.Version)
+
+		second.Title = "Second edit"
+		assert.ErrorIs(t, store.UpdateBug(second), ErrVersionConflict)
+
+		got, err := store.GetBug(bug.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "First edit", got.Title)
+		assert.Equal(t, 2, got.Version)
+	})
+}
+
+func TestMarkdownIsRenderedOnWrite(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		bug := &models.Bug{Title: "Crash", Description: "Fails on **save**"}
+		assert.NoError(t, store.CreateBug(bug))
+		got, err := store.GetBug(bug.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Fails on **save**", got.Description)
+		assert.Equal(t, "<p>Fails on <strong>save</strong></p>\n", got.DescriptionHTML)
+
+		// Rendered HTML sent by a client is replaced.
+		got.Description = "Run `make`"
+		got.DescriptionHTML = "<script>alert(1)</script>"
+		assert.NoError(t, store.UpdateBug(got))
+		got, err = store.GetBug(bug.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "<p>Run <code>make</code></p>\n", got.DescriptionHTML)
+
+		comment := &models.Comment{Author: "alice", Content: "- [x] reproduced"}
+		assert.NoError(t, store.CreateComment(bug.ID, comment))
+		assert.Contains(t, comment.ContentHTML, `<input checked="" disabled="" type="checkbox">`)
+
+		_, err = store.EditComment(bug.ID, comment.ID, "_fixed_", time.Now())
+		assert.NoError(t, err)
+		c, err := store.GetComment(bug.ID, comment.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "<p><em>fixed</em></p>\n", c.ContentHTML)
+
+		_, err = store.DeleteComment(bug.ID, comment.ID, "alice", time.Now())
+		assert.NoError(t, err)
+		c, err = store.GetComment(bug.ID, comment.ID)
+		assert.NoError(t, err)
+		// Kept, though not shown, until the trash is purged.
+		assert.Equal(t, "<p><em>fixed</em></p>\n", c.ContentHTML)
+	})
+}
+
+func TestDeletedBugIDsAreNotReused(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, store.CreateBug(&models.Bug{Title: "Test"}))
+		}
+		for id := 1; id <= 3; id++ {
+			assert.NoError(t, store.DeleteBug(id))
+		}
+
+		bugs, err := store.GetAllBugs()
+		assert.NoError(t, err)
+		assert.Empty(t, bugs)
+
+		bug := &models.Bug{Title: "Fresh"}
+		assert.NoError(t, store.CreateBug(bug))
+		assert.Equal(t, 4, bug.ID)
+	})
+}
+
+func TestUpdateRollsBackOnError(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		err := store.Update(func(tx Tx) error {
+			if err := tx.CreateBug(&models.Bug{Title: "Test"}); err != nil {
+				return err
+			}
+			return tx.DeleteBug(999)
+		})
+		assert.ErrorIs(t, err, ErrBugNotFound)
+
+		bugs, err := store.GetAllBugs()
+		assert.NoError(t, err)
+		assert.Empty(t, bugs)
+	})
+}
+
+func TestViewIsReadOnly(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		err := store.View(func(tx Tx) error {
+			return tx.CreateBug(&models.Bug{Title: "Test"})
+		})
+		assert.Error(t, err)
+
+		bugs, err := store.GetAllBugs()
+		assert.NoError(t, err)
+		assert.Empty(t, bugs)
+	})
 }
 
 func TestMain(m *testing.M) {
@@ -0,0 +1,36 @@
+package db
+
+import "bugtracker-backend/internal/models"
+
+// historyCounter numbers history entries across all bugs, so entry IDs
+// also order them in time.
+const historyCounter = "lastHistoryID"
+
+// historyStore is implemented by every backend's transaction type to
+// persist bug history entries.
+type historyStore interface {
+	putHistory(entry *models.HistoryEntry) error
+	// bugHistory returns a bug's entries ordered by ID.
+	bugHistory(bugID int) ([]models.HistoryEntry, error)
+	deleteBugHistory(bugID int) error
+}
+
+// addHistory stores an entry for a live or trashed bug.
+func addHistory(tx Tx, l bugLoader, hs historyStore, entry *models.HistoryEntry) error {
+	if _, err := l.loadBug(entry.BugID); err != nil {
+		return err
+	}
+	id, err := tx.NextID(historyCounter)
+	if err != nil {
+		return err
+	}
+	entry.ID = id
+	return hs.putHistory(entry)
+}
+
+func getHistory(tx Tx, hs historyStore, bugID int) ([]models.HistoryEntry, error) {
+	if _, err := tx.GetBug(bugID); err != nil {
+		return nil, err
+	}
+	return hs.bugHistory(bugID)
+}
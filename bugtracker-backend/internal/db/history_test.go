@@ -0,0 +1,65 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"bugtracker-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBugHistory(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		bug := &models.Bug{Title: "Crash", Status: "Open"}
+		assert.NoError(t, store.CreateBug(bug))
+		other := &models.Bug{Title: "Other"}
+		assert.NoError(t, store.CreateBug(other))
+
+		at := time.Date(2025, 2, 12, 16, 0, 0, 0, time.UTC)
+		entries := []*models.HistoryEntry{
+			{BugID: bug.ID, Action: models.HistoryCreated, Actor: "alice", At: at,
+				Changes: []models.FieldChange{{Field: "title", New: "Crash"}}},
+			{BugID: other.ID, Action: models.HistoryCreated, At: at},
+			{BugID: bug.ID, Action: models.HistoryUpdated, Actor: "bob", At: at.Add(time.Hour),
+				Changes: []models.FieldChange{{Field: "status", Old: "Open", New: "Closed"}}},
+			{BugID: bug.ID, Action: models.HistoryCommented, Actor: "bob", At: at.Add(2 * time.Hour), CommentID: 7},
+		}
+		for _, e := range entries {
+			assert.NoError(t, store.AddHistory(e))
+		}
+		assert.ErrorIs(t, store.AddHistory(&models.HistoryEntry{BugID: 999, Action: models.HistoryUpdated}), ErrBugNotFound)
+
+		history, err := store.GetHistory(bug.ID)
+		assert.NoError(t, err)
+		if assert.Len(t, history, 3) {
+			assert.Equal(t, []int{1, 3, 4}, []int{history[0].ID, history[1].ID, history[2].ID})
+			assert.Equal(t, "alice", history[0].Actor)
+			assert.True(t, at.Equal(history[0].At))
+			assert.Equal(t, entries[2].Changes, history[1].Changes)
+			assert.Equal(t, 7, history[2].CommentID)
+			assert.Empty(t, history[2].Changes)
+		}
+
+		// History stays with a trashed bug and goes when it is purged.
+		assert.NoError(t, store.TrashBug(bug.ID, "alice", at))
+		assert.NoError(t, store.AddHistory(&models.HistoryEntry{BugID: bug.ID, Action: models.HistoryDeleted, At: at}))
+		_, err = store.GetHistory(bug.ID)
+		assert.ErrorIs(t, err, ErrBugNotFound)
+		_, err = store.RestoreBug(bug.ID)
+		assert.NoError(t, err)
+		history, err = store.GetHistory(bug.ID)
+		assert.NoError(t, err)
+		assert.Len(t, history, 4)
+
+		assert.NoError(t, store.DeleteBug(bug.ID))
+		_, err = store.GetHistory(bug.ID)
+		assert.ErrorIs(t, err, ErrBugNotFound)
+		next := &models.Bug{Title: "Next"}
+		assert.NoError(t, store.CreateBug(next))
+		assert.NotEqual(t, bug.ID, next.ID)
+		history, err = store.GetHistory(next.ID)
+		assert.NoError(t, err)
+		assert.Empty(t, history)
+	})
+}
@@ -0,0 +1,1079 @@
+package db
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"bugtracker-backend/internal/models"
+	"bugtracker-backend/internal/search"
+)
+
+var errTxNotWritable = errors.New("tx not writable")
+
+// MemoryStore is a Store that keeps everything in process memory. It mirrors
+// the bbolt semantics and is intended for tests and throwaway demo instances.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	state  *memoryState
+	closed bool
+}
+
+type memoryState struct {
+	bugs     map[int]models.Bug
+	projects map[int]models.Project
+	comments map[int][]models.Comment
+	// revisions maps comment IDs to their revisions.
+	revisions map[int][]models.CommentRevision
+	history   map[int][]models.HistoryEntry
+	audit     []models.AuditEntry
+	users     map[int]models.User
+	// sessions maps token hashes to sessions.
+	sessions map[string]models.Session
+	// identities maps issuer and subject to provider identities.
+	identities map[[2]string]models.Identity
+	apiTokens  map[int]models.APIToken
+	counters   map[string]int
+	// searchDocs are never modified once stored, so clones may share them.
+	searchDocs map[int]*search.Document
+}
+
+type memoryTx struct {
+	state    *memoryState
+	writable bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{state: newMemoryState()}
+	// Creating the default project in an empty store cannot fail.
+	s.CreateProject(models.DefaultProject())
+	return s
+}
+
+func newMemoryState() *memoryState {
+	return &memoryState{
+		bugs:       make(map[int]models.Bug),
+		projects:   make(map[int]models.Project),
+		comments:   make(map[int][]models.Comment),
+		revisions:  make(map[int][]models.CommentRevision),
+		history:    make(map[int][]models.HistoryEntry),
+		users:      make(map[int]models.User),
+		sessions:   make(map[string]models.Session),
+		identities: make(map[[2]string]models.Identity),
+		apiTokens:  make(map[int]models.APIToken),
+		counters:   make(map[string]int),
+		searchDocs: make(map[int]*search.Document),
+	}
+}
+
+// clone copies the state so that a failed Update can be discarded without
+// touching the committed data.
+func (s *memoryState) clone() *memoryState {
+	c := newMemoryState()
+	for k, v := range s.bugs {
+		c.bugs[k] = v
+	}
+	for k, v := range s.projects {
+		c.projects[k] = v
+	}
+	for k, v := range s.comments {
+		c.comments[k] = v[:len(v):len(v)]
+	}
+	for k, v := range s.revisions {
+		c.revisions[k] = v[:len(v):len(v)]
+	}
+	c.audit = s.audit[:len(s.audit):len(s.audit)]
+	for k, v := range s.history {
+		// Cap the copy so that appending to it never writes into the
+		// original's array.
+		c.history[k] = v[:len(v):len(v)]
+	}
+	for k, v := range s.users {
+		c.users[k] = v
+	}
+	for k, v := range s.sessions {
+		c.sessions[k] = v
+	}
+	for k, v := range s.identities {
+		c.identities[k] = v
+	}
+	for k, v := range s.apiTokens {
+		c.apiTokens[k] = v
+	}
+	for k, v := range s.counters {
+		c.counters[k] = v
+	}
+	for k, v := range s.searchDocs {
+		c.searchDocs[k] = v
+	}
+	return c
+}
+
+func (s *MemoryStore) View(fn func(tx Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return ErrNotInitialized
+	}
+	return fn(&memoryTx{state: s.state})
+}
+
+func (s *MemoryStore) Update(fn func(tx Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrNotInitialized
+	}
+
+	next := s.state.clone()
+	if err := fn(&memoryTx{state: next, writable: true}); err != nil {
+		return err
+	}
+	s.state = next
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.state = newMemoryState()
+	return nil
+}
+
+func (s *MemoryStore) CreateBug(bug *models.Bug) error {
+	return s.Update(func(tx Tx) error { return tx.CreateBug(bug) })
+}
+
+func (s *MemoryStore) GetBug(id int) (bug *models.Bug, err error) {
+	err = s.View(func(tx Tx) error {
+		bug, err = tx.GetBug(id)
+		return err
+	})
+	return bug, err
+}
+
+func (s *MemoryStore) GetAllBugs() (bugs []*models.Bug, err error) {
+	err = s.View(func(tx Tx) error {
+		bugs, err = tx.GetAllBugs()
+		return err
+	})
+	return bugs, err
+}
+
+func (s *MemoryStore) ListBugs(q BugQuery) (bugs []*models.Bug, next string, err error) {
+	err = s.View(func(tx Tx) error {
+		bugs, next, err = tx.ListBugs(q)
+		return err
+	})
+	return bugs, next, err
+}
+
+func (s *MemoryStore) UpdateBug(bug *models.Bug) error {
+	return s.Update(func(tx Tx) error { return tx.UpdateBug(bug) })
+}
+
+func (s *MemoryStore) DeleteBug(id int) error {
+	return s.Update(func(tx Tx) error { return tx.DeleteBug(id) })
+}
+
+func (s *MemoryStore) TrashBug(id int, by string, at time.Time) error {
+	return s.Update(func(tx Tx) error { return tx.TrashBug(id, by, at) })
+}
+
+func (s *MemoryStore) RestoreBug(id int) (bug *models.Bug, err error) {
+	err = s.Update(func(tx Tx) error {
+		bug, err = tx.RestoreBug(id)
+		return err
+	})
+	return bug, err
+}
+
+func (s *MemoryStore) PurgeTrash(before time.Time) (count int, err error) {
+	err = s.Update(func(tx Tx) error {
+		count, err = tx.PurgeTrash(before)
+		return err
+	})
+	return count, err
+}
+
+func (s *MemoryStore) CreateComment(bugID int, comment *models.Comment) error {
+	return s.Update(func(tx Tx) error { return tx.CreateComment(bugID, comment) })
+}
+
+func (s *MemoryStore) GetComments(bugID int) (comments []models.Comment, err error) {
+	err = s.View(func(tx Tx) error {
+		comments, err = tx.GetComments(bugID)
+		return err
+	})
+	return comments, err
+}
+
+func (s *MemoryStore) ListComments(bugID int, q CommentQuery) (comments []models.Comment, next string, err error) {
+	err = s.View(func(tx Tx) error {
+		comments, next, err = tx.ListComments(bugID, q)
+		return err
+	})
+	return comments, next, err
+}
+
+func (s *MemoryStore) GetComment(bugID, id int) (comment *models.Comment, err error) {
+	err = s.View(func(tx Tx) error {
+		comment, err = tx.GetComment(bugID, id)
+		return err
+	})
+	return comment, err
+}
+
+func (s *MemoryStore) EditComment(bugID, id int, content string, at time.Time) (comment *models.Comment, err error) {
+	err = s.Update(func(tx Tx) error {
+		comment, err = tx.EditComment(bugID, id, content, at)
+		return err
+	})
+	return comment, err
+}
+
+func (s *MemoryStore) DeleteComment(bugID, id int, by string, at time.Time) (comment *models.Comment, err error) {
+	err = s.Update(func(tx Tx) error {
+		comment, err = tx.DeleteComment(bugID, id, by, at)
+		return err
+	})
+	return comment, err
+}
+
+func (s *MemoryStore) RestoreComment(bugID, id int) (comment *models.Comment, err error) {
+	err = s.Update(func(tx Tx) error {
+		comment, err = tx.RestoreComment(bugID, id)
+		return err
+	})
+	return comment, err
+}
+
+func (s *MemoryStore) GetCommentRevisions(bugID, id int) (revs []models.CommentRevision, err error) {
+	err = s.View(func(tx Tx) error {
+		revs, err = tx.GetCommentRevisions(bugID, id)
+		return err
+	})
+	return revs, err
+}
+
+func (s *MemoryStore) OrphanedComments() (comments []models.Comment, err error) {
+	err = s.View(func(tx Tx) error {
+		comments, err = tx.OrphanedComments()
+		return err
+	})
+	return comments, err
+}
+
+func (s *MemoryStore) PurgeOrphanedComments() (count int, err error) {
+	err = s.Update(func(tx Tx) error {
+		count, err = tx.PurgeOrphanedComments()
+		return err
+	})
+	return count, err
+}
+
+func (s *MemoryStore) AddHistory(entry *models.HistoryEntry) error {
+	return s.Update(func(tx Tx) error { return tx.AddHistory(entry) })
+}
+
+func (s *MemoryStore) GetHistory(bugID int) (entries []models.HistoryEntry, err error) {
+	err = s.View(func(tx Tx) error {
+		entries, err = tx.GetHistory(bugID)
+		return err
+	})
+	return entries, err
+}
+
+func (s *MemoryStore) AppendAudit(entry *models.AuditEntry) error {
+	return s.Update(func(tx Tx) error { return tx.AppendAudit(entry) })
+}
+
+func (s *MemoryStore) ListAudit(q AuditQuery) (entries []models.AuditEntry, err error) {
+	err = s.View(func(tx Tx) error {
+		entries, err = tx.ListAudit(q)
+		return err
+	})
+	return entries, err
+}
+
+func (s *MemoryStore) CreateUser(user *models.User) error {
+	return s.Update(func(tx Tx) error { return tx.CreateUser(user) })
+}
+
+func (s *MemoryStore) GetUser(id int) (user *models.User, err error) {
+	err = s.View(func(tx Tx) error {
+		user, err = tx.GetUser(id)
+		return err
+	})
+	return user, err
+}
+
+func (s *MemoryStore) GetUserByName(username string) (user *models.User, err error) {
+	err = s.View(func(tx Tx) error {
+		user, err = tx.GetUserByName(username)
+		return err
+	})
+	return user, err
+}
+
+func (s *MemoryStore) ListUsers() (users []models.User, err error) {
+	err = s.View(func(tx Tx) error {
+		users, err = tx.ListUsers()
+		return err
+	})
+	return users, err
+}
+
+func (s *MemoryStore) UpdateUser(user *models.User) error {
+	return s.Update(func(tx Tx) error { return tx.UpdateUser(user) })
+}
+
+func (s *MemoryStore) CreateIdentity(identity *models.Identity) error {
+	return s.Update(func(tx Tx) error { return tx.CreateIdentity(identity) })
+}
+
+func (s *MemoryStore) GetIdentity(issuer, subject string) (identity *models.Identity, err error) {
+	err = s.View(func(tx Tx) error {
+		identity, err = tx.GetIdentity(issuer, subject)
+		return err
+	})
+	return identity, err
+}
+
+func (s *MemoryStore) CreateSession(session *models.Session) error {
+	return s.Update(func(tx Tx) error { return tx.CreateSession(session) })
+}
+
+func (s *MemoryStore) GetSession(tokenHash string) (session *models.Session, err error) {
+	err = s.View(func(tx Tx) error {
+		session, err = tx.GetSession(tokenHash)
+		return err
+	})
+	return session, err
+}
+
+func (s *MemoryStore) DeleteSession(tokenHash string) error {
+	return s.Update(func(tx Tx) error { return tx.DeleteSession(tokenHash) })
+}
+
+func (s *MemoryStore) DeleteExpiredSessions(now time.Time) (count int, err error) {
+	err = s.Update(func(tx Tx) error {
+		count, err = tx.DeleteExpiredSessions(now)
+		return err
+	})
+	return count, err
+}
+
+func (s *MemoryStore) CreateAPIToken(token *models.APIToken) error {
+	return s.Update(func(tx Tx) error { return tx.CreateAPIToken(token) })
+}
+
+func (s *MemoryStore) GetAPIToken(tokenHash string) (token *models.APIToken, err error) {
+	err = s.View(func(tx Tx) error {
+		token, err = tx.GetAPIToken(tokenHash)
+		return err
+	})
+	return token, err
+}
+
+func (s *MemoryStore) ListAPITokens(userID int) (tokens []models.APIToken, err error) {
+	err = s.View(func(tx Tx) error {
+		tokens, err = tx.ListAPITokens(userID)
+		return err
+	})
+	return tokens, err
+}
+
+func (s *MemoryStore) DeleteAPIToken(userID, id int) error {
+	return s.Update(func(tx Tx) error { return tx.DeleteAPIToken(userID, id) })
+}
+
+func (s *MemoryStore) TouchAPIToken(id int, at time.Time) error {
+	return s.Update(func(tx Tx) error { return tx.TouchAPIToken(id, at) })
+}
+
+func (s *MemoryStore) GetBugID(project string, number int) (id int, err error) {
+	err = s.View(func(tx Tx) error {
+		id, err = tx.GetBugID(project, number)
+		return err
+	})
+	return id, err
+}
+
+func (s *MemoryStore) CreateProject(project *models.Project) error {
+	return s.Update(func(tx Tx) error { return tx.CreateProject(project) })
+}
+
+func (s *MemoryStore) GetProject(key string) (project *models.Project, err error) {
+	err = s.View(func(tx Tx) error {
+		project, err = tx.GetProject(key)
+		return err
+	})
+	return project, err
+}
+
+func (s *MemoryStore) ListProjects() (projects []models.Project, err error) {
+	err = s.View(func(tx Tx) error {
+		projects, err = tx.ListProjects()
+		return err
+	})
+	return projects, err
+}
+
+func (s *MemoryStore) SearchBugs(query string, limit int) (hits []SearchHit, err error) {
+	err = s.View(func(tx Tx) error {
+		hits, err = tx.SearchBugs(query, limit)
+		return err
+	})
+	return hits, err
+}
+
+func (s *MemoryStore) RebuildSearchIndex() (count int, err error) {
+	err = s.Update(func(tx Tx) error {
+		count, err = tx.RebuildSearchIndex()
+		return err
+	})
+	return count, err
+}
+
+func (s *MemoryStore) NextID(counter string) (id int, err error) {
+	err = s.Update(func(tx Tx) error {
+		id, err = tx.NextID(counter)
+		return err
+	})
+	return id, err
+}
+
+func (t *memoryTx) CreateBug(bug *models.Bug) error {
+	if err := numberBug(t, bug); err != nil {
+		return err
+	}
+	nextID, err := t.NextID(bugCounter)
+	if err != nil {
+		return err
+	}
+
+	bug.ID = nextID
+	bug.Version = 1
+	bug.RenderDescription()
+	t.state.bugs[bug.ID] = *bug
+	return indexBugForSearch(t, t, bug.ID)
+}
+
+func (t *memoryTx) GetBug(id int) (*models.Bug, error) {
+	bug, err := t.loadBug(id)
+	if err != nil {
+		return nil, err
+	}
+	if bug.DeletedAt != nil {
+		return nil, ErrBugNotFound
+	}
+	return bug, fillCommentCounts(t, bug)
+}
+
+func (t *memoryTx) loadBug(id int) (*models.Bug, error) {
+	bug, ok := t.state.bugs[id]
+	if !ok {
+		return nil, ErrBugNotFound
+	}
+	return &bug, nil
+}
+
+func (t *memoryTx) GetAllBugs() ([]*models.Bug, error) {
+	var bugs []*models.Bug
+	for _, id := range sortedKeys(t.state.bugs) {
+		bug := t.state.bugs[id]
+		if bug.DeletedAt == nil {
+			bugs = append(bugs, &bug)
+		}
+	}
+	return bugs, fillCommentCounts(t, bugs...)
+}
+
+func (t *memoryTx) ListBugs(q BugQuery) ([]*models.Bug, string, error) {
+	if err := q.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	var after *bugCursor
+	if q.Cursor != "" {
+		cur, _ := decodeBugCursor(q.Cursor)
+		after = &cur
+	}
+
+	var bugs []*models.Bug
+	for _, bug := range t.state.bugs {
+		bug := bug
+		if !q.matches(&bug) {
+			continue
+		}
+		if after != nil && !q.less(*after, q.cursorFor(&bug)) {
+			continue
+		}
+		bugs = append(bugs, &bug)
+	}
+
+	sort.Slice(bugs, func(i, j int) bool {
+		return q.less(q.cursorFor(bugs[i]), q.cursorFor(bugs[j]))
+	})
+	if q.Limit > 0 && len(bugs) > q.Limit+1 {
+		bugs = bugs[:q.Limit+1]
+	}
+
+	bugs, next := q.page(bugs)
+	if err := fillCommentCounts(t, bugs...); err != nil {
+		return nil, "", err
+	}
+	return bugs, next, nil
+}
+
+func (t *memoryTx) UpdateBug(bug *models.Bug) error {
+	if !t.writable {
+		return errTxNotWritable
+	}
+	stored, ok := t.state.bugs[bug.ID]
+	if !ok {
+		return ErrBugNotFound
+	}
+	if err := checkBugVersion(&stored, bug); err != nil {
+		return err
+	}
+
+	bug.UpdatedAt = time.Now()
+	bug.Version++
+	bug.RenderDescription()
+	t.state.bugs[bug.ID] = *bug
+	return syncBugSearchDoc(t, t, bug)
+}
+
+func (t *memoryTx) DeleteBug(id int) error {
+	if !t.writable {
+		return errTxNotWritable
+	}
+	if _, ok := t.state.bugs[id]; !ok {
+		return ErrBugNotFound
+	}
+
+	delete(t.state.bugs, id)
+	for _, c := range t.state.comments[id] {
+		delete(t.state.revisions, c.ID)
+	}
+	delete(t.state.comments, id)
+	delete(t.state.history, id)
+	return t.deleteSearchDoc(id)
+}
+
+func (t *memoryTx) TrashBug(id int, by string, at time.Time) error {
+	if !t.writable {
+		return errTxNotWritable
+	}
+	return trashBug(t, id, by, at)
+}
+
+func (t *memoryTx) RestoreBug(id int) (*models.Bug, error) {
+	if !t.writable {
+		return nil, errTxNotWritable
+	}
+	return restoreBug(t, t, id)
+}
+
+func (t *memoryTx) PurgeTrash(before time.Time) (int, error) {
+	if !t.writable {
+		return 0, errTxNotWritable
+	}
+	return purgeTrash(t, t, before)
+}
+
+func (t *memoryTx) CreateComment(bugID int, comment *models.Comment) error {
+	if !t.writable {
+		return errTxNotWritable
+	}
+	if _, err := t.GetBug(bugID); err != nil {
+		return err
+	}
+	if err := setCommentParent(t, bugID, comment); err != nil {
+		return err
+	}
+
+	id, err := t.NextID(commentCounter)
+	if err != nil {
+		return err
+	}
+	comment.CreatedAt = time.Now()
+	comment.ID = id
+	comment.BugID = bugID
+	comment.RenderContent()
+
+	t.state.comments[bugID] = append(t.state.comments[bugID], *comment)
+	return indexBugForSearch(t, t, bugID)
+}
+
+func (t *memoryTx) GetComments(bugID int) ([]models.Comment, error) {
+	if _, err := t.GetBug(bugID); err != nil {
+		return nil, err
+	}
+
+	return append([]models.Comment(nil), t.state.comments[bugID]...), nil
+}
+
+func (t *memoryTx) ListComments(bugID int, q CommentQuery) ([]models.Comment, string, error) {
+	return listComments(t, bugID, q)
+}
+
+func (t *memoryTx) GetComment(bugID, id int) (*models.Comment, error) {
+	return getLiveComment(t, bugID, id)
+}
+
+func (t *memoryTx) EditComment(bugID, id int, content string, at time.Time) (*models.Comment, error) {
+	if !t.writable {
+		return nil, errTxNotWritable
+	}
+	return editComment(t, t, t, bugID, id, content, at)
+}
+
+func (t *memoryTx) DeleteComment(bugID, id int, by string, at time.Time) (*models.Comment, error) {
+	if !t.writable {
+		return nil, errTxNotWritable
+	}
+	return trashComment(t, t, t, bugID, id, by, at)
+}
+
+func (t *memoryTx) RestoreComment(bugID, id int) (*models.Comment, error) {
+	if !t.writable {
+		return nil, errTxNotWritable
+	}
+	return restoreComment(t, t, t, bugID, id)
+}
+
+func (t *memoryTx) GetCommentRevisions(bugID, id int) ([]models.CommentRevision, error) {
+	return getCommentRevisions(t, bugID, id)
+}
+
+func (t *memoryTx) OrphanedComments() ([]models.Comment, error) {
+	return orphanedComments(t)
+}
+
+func (t *memoryTx) PurgeOrphanedComments() (int, error) {
+	if !t.writable {
+		return 0, errTxNotWritable
+	}
+	return purgeOrphanedComments(t, t, t)
+}
+
+func (t *memoryTx) allComments() ([]models.Comment, error) {
+	var comments []models.Comment
+	for _, bugID := range sortedKeys(t.state.comments) {
+		comments = append(comments, t.state.comments[bugID]...)
+	}
+	return comments, nil
+}
+
+// scanBugComments relies on each bug's comments being in ID order.
+func (t *memoryTx) scanBugComments(bugID, afterID int, descending bool, fn func(comment models.Comment) bool) error {
+	comments := t.state.comments[bugID]
+	for i := range comments {
+		if descending {
+			i = len(comments) - 1 - i
+		}
+		c := comments[i]
+		if afterID > 0 && (descending && c.ID >= afterID || !descending && c.ID <= afterID) {
+			continue
+		}
+		if !fn(c) {
+			break
+		}
+	}
+	return nil
+}
+
+func (t *memoryTx) commentCounts(bugIDs []int) (map[int]int, error) {
+	counts := make(map[int]int, len(bugIDs))
+	for _, id := range bugIDs {
+		counts[id] = len(t.state.comments[id])
+	}
+	return counts, nil
+}
+
+func (t *memoryTx) getComment(bugID, id int) (*models.Comment, error) {
+	for _, c := range t.state.comments[bugID] {
+		if c.ID == id {
+			return &c, nil
+		}
+	}
+	return nil, ErrCommentNotFound
+}
+
+// putComment copies the bug's comments rather than writing into a slice
+// that a cloned state may share.
+func (t *memoryTx) putComment(comment *models.Comment) error {
+	comments := append([]models.Comment(nil), t.state.comments[comment.BugID]...)
+	for i := range comments {
+		if comments[i].ID == comment.ID {
+			comments[i] = *comment
+			t.state.comments[comment.BugID] = comments
+			return nil
+		}
+	}
+	return ErrCommentNotFound
+}
+
+func (t *memoryTx) putCommentRevision(rev *models.CommentRevision) error {
+	t.state.revisions[rev.CommentID] = append(t.state.revisions[rev.CommentID], *rev)
+	return nil
+}
+
+func (t *memoryTx) commentRevisions(commentID int) ([]models.CommentRevision, error) {
+	return append([]models.CommentRevision(nil), t.state.revisions[commentID]...), nil
+}
+
+func (t *memoryTx) deleteCommentRevisions(commentID int) error {
+	delete(t.state.revisions, commentID)
+	return nil
+}
+
+func (t *memoryTx) deleteComment(bugID, id int) error {
+	delete(t.state.revisions, id)
+	var kept []models.Comment
+	for _, c := range t.state.comments[bugID] {
+		if c.ID != id {
+			kept = append(kept, c)
+		}
+	}
+	if kept == nil {
+		delete(t.state.comments, bugID)
+	} else {
+		t.state.comments[bugID] = kept
+	}
+	return nil
+}
+
+func (t *memoryTx) AddHistory(entry *models.HistoryEntry) error {
+	if !t.writable {
+		return errTxNotWritable
+	}
+	return addHistory(t, t, t, entry)
+}
+
+func (t *memoryTx) GetHistory(bugID int) ([]models.HistoryEntry, error) {
+	return getHistory(t, t, bugID)
+}
+
+func (t *memoryTx) putHistory(entry *models.HistoryEntry) error {
+	t.state.history[entry.BugID] = append(t.state.history[entry.BugID], *entry)
+	return nil
+}
+
+func (t *memoryTx) bugHistory(bugID int) ([]models.HistoryEntry, error) {
+	return append([]models.HistoryEntry(nil), t.state.history[bugID]...), nil
+}
+
+func (t *memoryTx) deleteBugHistory(bugID int) error {
+	delete(t.state.history, bugID)
+	return nil
+}
+
+func (t *memoryTx) AppendAudit(entry *models.AuditEntry) error {
+	if !t.writable {
+		return errTxNotWritable
+	}
+	return appendAudit(t, entry)
+}
+
+func (t *memoryTx) ListAudit(q AuditQuery) ([]models.AuditEntry, error) {
+	return listAudit(t, q)
+}
+
+func (t *memoryTx) CreateUser(user *models.User) error {
+	if !t.writable {
+		return errTxNotWritable
+	}
+	return createUser(t, t, user)
+}
+
+func (t *memoryTx) GetUser(id int) (*models.User, error) {
+	user, ok := t.state.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (t *memoryTx) GetUserByName(username string) (*models.User, error) {
+	username = models.NormalizeUsername(username)
+	for _, user := range t.state.users {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (t *memoryTx) ListUsers() ([]models.User, error) {
+	users := make([]models.User, 0, len(t.state.users))
+	for _, user := range t.state.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (t *memoryTx) UpdateUser(user *models.User) error {
+	if !t.writable {
+		return errTxNotWritable
+	}
+	return updateUser(t, t, user)
+}
+
+func (t *memoryTx) putUser(user *models.User) error {
+	t.state.users[user.ID] = *user
+	return nil
+}
+
+func (t *memoryTx) CreateProject(project *models.Project) error {
+	if !t.writable {
+		return errTxNotWritable
+	}
+	return createProject(t, t, project)
+}
+
+func (t *memoryTx) GetProject(key string) (*models.Project, error) {
+	key = models.NormalizeProjectKey(key)
+	for _, project := range t.state.projects {
+		if project.Key == key {
+			return &project, nil
+		}
+	}
+	return nil, ErrProjectNotFound
+}
+
+func (t *memoryTx) ListProjects() ([]models.Project, error) {
+	projects := make([]models.Project, 0, len(t.state.projects))
+	for _, id := range sortedKeys(t.state.projects) {
+		projects = append(projects, t.state.projects[id])
+	}
+	return projects, nil
+}
+
+func (t *memoryTx) putProject(project *models.Project) error {
+	t.state.projects[project.ID] = *project
+	return nil
+}
+
+func (t *memoryTx) GetBugID(project string, number int) (int, error) {
+	project = models.NormalizeProjectKey(project)
+	for id, bug := range t.state.bugs {
+		if bug.Project == project && bug.Number == number {
+			return id, nil
+		}
+	}
+	return 0, ErrBugNotFound
+}
+
+func (t *memoryTx) CreateIdentity(identity *models.Identity) error {
+	if !t.writable {
+		return errTxNotWritable
+	}
+	t.state.identities[[2]string{identity.Issuer, identity.Subject}] = *identity
+	return nil
+}
+
+func (t *memoryTx) GetIdentity(issuer, subject string) (*models.Identity, error) {
+	identity, ok := t.state.identities[[2]string{issuer, subject}]
+	if !ok {
+		return nil, ErrIdentityNotFound
+	}
+	return &identity, nil
+}
+
+func (t *memoryTx) CreateSession(session *models.Session) error {
+	if !t.writable {
+		return errTxNotWritable
+	}
+	t.state.sessions[session.TokenHash] = *session
+	return nil
+}
+
+func (t *memoryTx) GetSession(tokenHash string) (*models.Session, error) {
+	session, ok := t.state.sessions[tokenHash]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+func (t *memoryTx) DeleteSession(tokenHash string) error {
+	if !t.writable {
+		return errTxNotWritable
+	}
+	delete(t.state.sessions, tokenHash)
+	return nil
+}
+
+func (t *memoryTx) DeleteExpiredSessions(now time.Time) (int, error) {
+	if !t.writable {
+		return 0, errTxNotWritable
+	}
+	count := 0
+	for k, session := range t.state.sessions {
+		if session.Expired(now) {
+			delete(t.state.sessions, k)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (t *memoryTx) CreateAPIToken(token *models.APIToken) error {
+	if !t.writable {
+		return errTxNotWritable
+	}
+	return createAPIToken(t, t, token)
+}
+
+func (t *memoryTx) GetAPIToken(tokenHash string) (*models.APIToken, error) {
+	for _, token := range t.state.apiTokens {
+		if token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+	return nil, ErrTokenNotFound
+}
+
+func (t *memoryTx) ListAPITokens(userID int) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	for _, token := range t.state.apiTokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].ID < tokens[j].ID })
+	return tokens, nil
+}
+
+func (t *memoryTx) DeleteAPIToken(userID, id int) error {
+	if !t.writable {
+		return errTxNotWritable
+	}
+	token, ok := t.state.apiTokens[id]
+	if !ok || token.UserID != userID {
+		return ErrTokenNotFound
+	}
+	delete(t.state.apiTokens, id)
+	return nil
+}
+
+func (t *memoryTx) TouchAPIToken(id int, at time.Time) error {
+	if !t.writable {
+		return errTxNotWritable
+	}
+	token, ok := t.state.apiTokens[id]
+	if !ok {
+		return ErrTokenNotFound
+	}
+	token.LastUsedAt = &at
+	t.state.apiTokens[id] = token
+	return nil
+}
+
+func (t *memoryTx) putAPIToken(token *models.APIToken) error {
+	t.state.apiTokens[token.ID] = *token
+	return nil
+}
+
+func (t *memoryTx) lastAudit() (*models.AuditEntry, error) {
+	if len(t.state.audit) == 0 {
+		return nil, nil
+	}
+	last := t.state.audit[len(t.state.audit)-1]
+	return &last, nil
+}
+
+func (t *memoryTx) putAudit(entry *models.AuditEntry) error {
+	t.state.audit = append(t.state.audit, *entry)
+	return nil
+}
+
+// scanAudit relies on entry n being at index n-1.
+func (t *memoryTx) scanAudit(afterSeq int, fn func(entry models.AuditEntry) bool) error {
+	for i := afterSeq; i < len(t.state.audit); i++ {
+		if !fn(t.state.audit[i]) {
+			break
+		}
+	}
+	return nil
+}
+
+func (t *memoryTx) NextID(counter string) (int, error) {
+	if !t.writable {
+		return 0, errTxNotWritable
+	}
+
+	t.state.counters[counter]++
+	return t.state.counters[counter], nil
+}
+
+func (t *memoryTx) SearchBugs(query string, limit int) ([]SearchHit, error) {
+	return searchBugs(t, t, query, limit)
+}
+
+func (t *memoryTx) RebuildSearchIndex() (int, error) {
+	if !t.writable {
+		return 0, errTxNotWritable
+	}
+	return rebuildSearchIndex(t, t)
+}
+
+func (t *memoryTx) putSearchDoc(doc *search.Document) error {
+	t.state.searchDocs[doc.ID] = doc
+	return nil
+}
+
+func (t *memoryTx) deleteSearchDoc(id int) error {
+	delete(t.state.searchDocs, id)
+	return nil
+}
+
+func (t *memoryTx) clearSearchIndex() error {
+	t.state.searchDocs = make(map[int]*search.Document)
+	return nil
+}
+
+// searchPostings scans every document; the memory store trades lookup speed
+// for cheap transaction snapshots.
+func (t *memoryTx) searchPostings(term string) (map[int]int, error) {
+	postings := make(map[int]int)
+	for id, doc := range t.state.searchDocs {
+		if tf := doc.Terms[term]; tf > 0 {
+			postings[id] = tf
+		}
+	}
+	return postings, nil
+}
+
+func (t *memoryTx) searchDocLength(id int) (int, error) {
+	if doc, ok := t.state.searchDocs[id]; ok {
+		return doc.Length, nil
+	}
+	return 0, nil
+}
+
+func (t *memoryTx) searchStats() (search.Stats, error) {
+	stats := search.Stats{Docs: len(t.state.searchDocs)}
+	for _, doc := range t.state.searchDocs {
+		stats.TotalLength += doc.Length
+	}
+	return stats, nil
+}
+
+// sortedKeys returns the keys of m in ascending order, matching the key
+// order bbolt iterates in.
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"bugtracker-backend/internal/models"
+)
+
+const projectCounter = "lastProjectID"
+
+// bugNumberCounter names the counter that numbers a project's bugs.
+func bugNumberCounter(project string) string {
+	return "lastBugNumber:" + project
+}
+
+// projectStore is implemented by every backend's transaction type to store
+// projects.
+type projectStore interface {
+	putProject(project *models.Project) error
+}
+
+// createProject numbers a new project and stores it unless its key is
+// taken.
+func createProject(tx Tx, ps projectStore, project *models.Project) error {
+	project.Key = models.NormalizeProjectKey(project.Key)
+	if _, err := tx.GetProject(project.Key); err == nil {
+		return ErrProjectExists
+	} else if !errors.Is(err, ErrProjectNotFound) {
+		return err
+	}
+
+	id, err := tx.NextID(projectCounter)
+	if err != nil {
+		return err
+	}
+	project.ID = id
+	if project.CreatedAt.IsZero() {
+		project.CreatedAt = time.Now()
+	}
+	return ps.putProject(project)
+}
+
+// numberBug files a new bug in its project, the default project if it
+// names none, as the project's next bug.
+func numberBug(tx Tx, bug *models.Bug) error {
+	if bug.Project == "" {
+		bug.Project = models.DefaultProjectKey
+	}
+	bug.Project = models.NormalizeProjectKey(bug.Project)
+	if _, err := tx.GetProject(bug.Project); err != nil {
+		return err
+	}
+	number, err := tx.NextID(bugNumberCounter(bug.Project))
+	if err != nil {
+		return err
+	}
+	bug.Number = number
+	bug.Key = models.BugKey(bug.Project, number)
+	return nil
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"testing"
+
+	"bugtracker-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjects(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		projects, err := store.ListProjects()
+		assert.NoError(t, err)
+		if assert.Len(t, projects, 1) {
+			assert.Equal(t, models.DefaultProjectKey, projects[0].Key)
+		}
+
+		web := &models.Project{Key: "web", Name: "Website"}
+		assert.NoError(t, store.CreateProject(web))
+		assert.Equal(t, "WEB", web.Key)
+		assert.Equal(t, 2, web.ID)
+		assert.ErrorIs(t, store.CreateProject(&models.Project{Key: "WEB", Name: "Again"}), ErrProjectExists)
+		assert.NoError(t, store.CreateProject(&models.Project{Key: "API", Name: "API"}))
+
+		got, err := store.GetProject("Web")
+		assert.NoError(t, err)
+		assert.Equal(t, "Website", got.Name)
+		_, err = store.GetProject("NOPE")
+		assert.ErrorIs(t, err, ErrProjectNotFound)
+
+		// Every project numbers its own bugs; IDs stay global.
+		var bugs []*models.Bug
+		for _, project := range []string{"WEB", "", "web", "API"} {
+			bug := &models.Bug{Project: project, Title: "Crash"}
+			assert.NoError(t, store.CreateBug(bug))
+			bugs = append(bugs, bug)
+		}
+		var keys []string
+		for _, bug := range bugs {
+			keys = append(keys, bug.Key)
+		}
+		assert.Equal(t, []string{"WEB-1", "BUG-1", "WEB-2", "API-1"}, keys)
+		assert.Equal(t, 3, bugs[2].ID)
+		assert.ErrorIs(t, store.CreateBug(&models.Bug{Project: "NOPE", Title: "Crash"}), ErrProjectNotFound)
+
+		stored, err := store.GetBug(bugs[2].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "WEB", stored.Project)
+		assert.Equal(t, 2, stored.Number)
+		assert.Equal(t, "WEB-2", stored.Key)
+
+		id, err := store.GetBugID("web", 2)
+		assert.NoError(t, err)
+		assert.Equal(t, bugs[2].ID, id)
+		_, err = store.GetBugID("API", 2)
+		assert.ErrorIs(t, err, ErrBugNotFound)
+
+		page, _, err := store.ListBugs(BugQuery{Project: "WEB"})
+		assert.NoError(t, err)
+		assert.Len(t, page, 2)
+
+		// Trashed bugs keep their keys until they are deleted.
+		assert.NoError(t, store.TrashBug(bugs[3].ID, "alice", stored.UpdatedAt))
+		id, err = store.GetBugID("API", 1)
+		assert.NoError(t, err)
+		assert.Equal(t, bugs[3].ID, id)
+		assert.NoError(t, store.DeleteBug(bugs[3].ID))
+		_, err = store.GetBugID("API", 1)
+		assert.ErrorIs(t, err, ErrBugNotFound)
+
+		// Numbers of deleted bugs are not reused.
+		bug := &models.Bug{Project: "API", Title: "Crash"}
+		assert.NoError(t, store.CreateBug(bug))
+		assert.Equal(t, "API-2", bug.Key)
+		_, err = store.GetBugID("API", 1)
+		assert.ErrorIs(t, err, ErrBugNotFound)
+	})
+}
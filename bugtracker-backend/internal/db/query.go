@@ -0,0 +1,223 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"bugtracker-backend/internal/bugquery"
+	"bugtracker-backend/internal/models"
+)
+
+// Fields accepted by BugQuery.SortBy.
+const (
+	SortByID        = "id"
+	SortByCreatedAt = "created_at"
+	SortByUpdatedAt = "updated_at"
+	SortByPriority  = "priority"
+	SortByStatus    = "status"
+	SortByTitle     = "title"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// sortableTimeLayout is fixed width so that formatted timestamps compare
+// chronologically as strings.
+const sortableTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// indexedSortFields are the sort fields other than the primary key; stores
+// that cannot sort natively keep a secondary index for each of them.
+var indexedSortFields = []string{SortByCreatedAt, SortByUpdatedAt, SortByPriority, SortByStatus, SortByTitle}
+
+// BugQuery selects, orders and pages the bugs returned by ListBugs. Zero
+// values mean "no constraint"; a zero Limit returns every matching bug.
+type BugQuery struct {
+	// Project is the key of the only project to list bugs from.
+	Project    string
+	Statuses   []string
+	Priorities []string
+
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+
+	// Resolutions and the resolved range only match bugs that have been
+	// resolved.
+	Resolutions    []string
+	ResolvedAfter  time.Time
+	ResolvedBefore time.Time
+	MinReopenCount int
+
+	// Filter is an additional query-language expression bugs must match.
+	Filter bugquery.Expr
+
+	// Trashed selects the bugs in the trash instead of the live ones.
+	Trashed bool
+
+	SortBy     string
+	Descending bool
+
+	Limit  int
+	Cursor string
+}
+
+// bugCursor marks the last bug of a page: its sort key and ID.
+type bugCursor struct {
+	Key string `json:"k"`
+	ID  int    `json:"id"`
+}
+
+func (q *BugQuery) Validate() error {
+	if q.SortBy == "" {
+		q.SortBy = SortByID
+	}
+	if q.SortBy != SortByID && !contains(indexedSortFields, q.SortBy) {
+		return fmt.Errorf("invalid sort field %q", q.SortBy)
+	}
+	if q.Limit < 0 {
+		return fmt.Errorf("limit must not be negative")
+	}
+	if q.MinReopenCount < 0 {
+		return fmt.Errorf("minimum reopen count must not be negative")
+	}
+	if q.Cursor != "" {
+		if _, err := decodeBugCursor(q.Cursor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matches reports whether bug satisfies the query's filters.
+func (q *BugQuery) matches(bug *models.Bug) bool {
+	if (bug.DeletedAt != nil) != q.Trashed {
+		return false
+	}
+	if q.Project != "" && bug.Project != q.Project {
+		return false
+	}
+	if len(q.Statuses) > 0 && !contains(q.Statuses, bug.Status) {
+		return false
+	}
+	if len(q.Priorities) > 0 && !contains(q.Priorities, bug.Priority) {
+		return false
+	}
+	if !q.CreatedAfter.IsZero() && !bug.CreatedAt.After(q.CreatedAfter) {
+		return false
+	}
+	if !q.CreatedBefore.IsZero() && !bug.CreatedAt.Before(q.CreatedBefore) {
+		return false
+	}
+	if !q.UpdatedAfter.IsZero() && !bug.UpdatedAt.After(q.UpdatedAfter) {
+		return false
+	}
+	if !q.UpdatedBefore.IsZero() && !bug.UpdatedAt.Before(q.UpdatedBefore) {
+		return false
+	}
+	if len(q.Resolutions) > 0 && !contains(q.Resolutions, bug.Resolution) {
+		return false
+	}
+	if !q.ResolvedAfter.IsZero() && (bug.ResolvedAt == nil || !bug.ResolvedAt.After(q.ResolvedAfter)) {
+		return false
+	}
+	if !q.ResolvedBefore.IsZero() && (bug.ResolvedAt == nil || !bug.ResolvedAt.Before(q.ResolvedBefore)) {
+		return false
+	}
+	if bug.ReopenCount < q.MinReopenCount {
+		return false
+	}
+	if q.Filter != nil && !q.Filter.Match(bug) {
+		return false
+	}
+	return true
+}
+
+// less reports whether a sorts before b in the query's order.
+func (q *BugQuery) less(a, b bugCursor) bool {
+	if q.Descending {
+		a, b = b, a
+	}
+	if a.Key != b.Key {
+		return a.Key < b.Key
+	}
+	return a.ID < b.ID
+}
+
+// cursorFor returns the position of bug in the query's sort order.
+func (q *BugQuery) cursorFor(bug *models.Bug) bugCursor {
+	return bugCursor{Key: bugSortKey(bug, q.SortBy), ID: bug.ID}
+}
+
+// page trims a result fetched with one extra row to the query's limit and
+// returns the cursor for the following page, if there is one.
+func (q *BugQuery) page(bugs []*models.Bug) ([]*models.Bug, string) {
+	if q.Limit == 0 || len(bugs) <= q.Limit {
+		return bugs, ""
+	}
+	bugs = bugs[:q.Limit]
+	return bugs, encodeBugCursor(q.cursorFor(bugs[len(bugs)-1]))
+}
+
+// bugSortKey renders a bug's sort field so that comparing keys as strings
+// gives the intended order. The SQLite backend computes the same keys in SQL.
+func bugSortKey(bug *models.Bug, field string) string {
+	switch field {
+	case SortByCreatedAt:
+		return formatSortableTime(bug.CreatedAt)
+	case SortByUpdatedAt:
+		return formatSortableTime(bug.UpdatedAt)
+	case SortByPriority:
+		return strconv.Itoa(models.PriorityRank(bug.Priority))
+	case SortByStatus:
+		return bug.Status
+	case SortByTitle:
+		return bug.Title
+	default:
+		return ""
+	}
+}
+
+func encodeBugCursor(c bugCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeBugCursor(s string) (bugCursor, error) {
+	var c bugCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
+
+func formatSortableTime(t time.Time) string {
+	return t.UTC().Format(sortableTimeLayout)
+}
+
+func parseSortableTime(s string) (time.Time, error) {
+	t, err := time.Parse(sortableTimeLayout, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if t.IsZero() {
+		return t, nil
+	}
+	return t.Local(), nil
+}
@@ -0,0 +1,288 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"bugtracker-backend/internal/bugquery"
+	"bugtracker-backend/internal/models"
+	"bugtracker-backend/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func seedQueryBugs(t *testing.T, store Store) time.Time {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	bugs := []models.Bug{
+		{Title: "Crash on save", Status: "Open", Priority: "High"},
+		{Title: "Typo in footer", Status: "Closed", Priority: "Low"},
+		{Title: "Slow search", Status: "In Progress", Priority: "Medium"},
+		{Title: "Login fails", Status: "Open", Priority: "Low"},
+		{Title: "Broken link", Status: "Open", Priority: "Medium"},
+	}
+	for i := range bugs {
+		bugs[i].CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		bugs[i].UpdatedAt = bugs[i].CreatedAt
+		assert.NoError(t, store.CreateBug(&bugs[i]))
+	}
+	return base
+}
+
+func bugIDs(bugs []*models.Bug) []int {
+	ids := make([]int, len(bugs))
+	for i, bug := range bugs {
+		ids[i] = bug.ID
+	}
+	return ids
+}
+
+func TestListBugsFilters(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		base := seedQueryBugs(t, store)
+
+		tests := []struct {
+			name     string
+			query    BugQuery
+			expected []int
+		}{
+			{
+				name:     "No filters",
+				query:    BugQuery{},
+				expected: []int{1, 2, 3, 4, 5},
+			},
+			{
+				name:     "Single status",
+				query:    BugQuery{Statuses: []string{"Open"}},
+				expected: []int{1, 4, 5},
+			},
+			{
+				name:     "Status and priority",
+				query:    BugQuery{Statuses: []string{"Open", "Closed"}, Priorities: []string{"Low"}},
+				expected: []int{2, 4},
+			},
+			{
+				name:     "Created range",
+				query:    BugQuery{CreatedAfter: base, CreatedBefore: base.Add(3 * time.Hour)},
+				expected: []int{2, 3},
+			},
+			{
+				name:     "Updated after",
+				query:    BugQuery{UpdatedAfter: base.Add(3 * time.Hour)},
+				expected: []int{5},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				bugs, next, err := store.ListBugs(tt.query)
+				assert.NoError(t, err)
+				assert.Empty(t, next)
+				assert.Equal(t, tt.expected, bugIDs(bugs))
+			})
+		}
+	})
+}
+
+func TestListBugsResolutionFilters(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		base := seedQueryBugs(t, store)
+
+		resolve := func(id int, resolution string, at time.Time, reopens int) {
+			bug, err := store.GetBug(id)
+			assert.NoError(t, err)
+			bug.Status = "Closed"
+			bug.Resolution = resolution
+			bug.ResolvedAt = &at
+			bug.ReopenCount = reopens
+			assert.NoError(t, store.UpdateBug(bug))
+		}
+		resolve(2, models.ResolutionFixed, base.Add(24*time.Hour), 0)
+		resolve(3, models.ResolutionDuplicate, base.Add(48*time.Hour), 2)
+
+		bug, err := store.GetBug(3)
+		assert.NoError(t, err)
+		assert.Equal(t, models.ResolutionDuplicate, bug.Resolution)
+		assert.True(t, base.Add(48*time.Hour).Equal(*bug.ResolvedAt))
+		assert.Equal(t, 2, bug.ReopenCount)
+
+		tests := []struct {
+			name     string
+			query    BugQuery
+			expected []int
+		}{
+			{
+				name:     "Resolution",
+				query:    BugQuery{Resolutions: []string{models.ResolutionFixed}},
+				expected: []int{2},
+			},
+			{
+				name:     "Resolved after",
+				query:    BugQuery{ResolvedAfter: base.Add(36 * time.Hour)},
+				expected: []int{3},
+			},
+			{
+				name:     "Resolved before",
+				query:    BugQuery{ResolvedBefore: base.Add(36 * time.Hour)},
+				expected: []int{2},
+			},
+			{
+				name:     "Reopened",
+				query:    BugQuery{MinReopenCount: 1},
+				expected: []int{3},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				bugs, _, err := store.ListBugs(tt.query)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, bugIDs(bugs))
+			})
+		}
+	})
+}
+
+func TestListBugsSorting(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		seedQueryBugs(t, store)
+
+		tests := []struct {
+			name     string
+			query    BugQuery
+			expected []int
+		}{
+			{
+				name:     "ID descending",
+				query:    BugQuery{Descending: true},
+				expected: []int{5, 4, 3, 2, 1},
+			},
+			{
+				name:     "Created descending",
+				query:    BugQuery{SortBy: SortByCreatedAt, Descending: true},
+				expected: []int{5, 4, 3, 2, 1},
+			},
+			{
+				name:     "Priority by severity",
+				query:    BugQuery{SortBy: SortByPriority, Descending: true},
+				expected: []int{1, 5, 3, 4, 2},
+			},
+			{
+				name:     "Title ascending",
+				query:    BugQuery{SortBy: SortByTitle},
+				expected: []int{5, 1, 4, 3, 2},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				bugs, _, err := store.ListBugs(tt.query)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, bugIDs(bugs))
+			})
+		}
+	})
+}
+
+func TestListBugsPagination(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		seedQueryBugs(t, store)
+
+		for _, desc := range []bool{false, true} {
+			query := BugQuery{SortBy: SortByPriority, Descending: desc, Limit: 2}
+
+			var pages [][]int
+			for {
+				bugs, next, err := store.ListBugs(query)
+				assert.NoError(t, err)
+				pages = append(pages, bugIDs(bugs))
+				if next == "" {
+					break
+				}
+				query.Cursor = next
+			}
+
+			if desc {
+				assert.Equal(t, [][]int{{1, 5}, {3, 4}, {2}}, pages)
+			} else {
+				assert.Equal(t, [][]int{{2, 4}, {3, 5}, {1}}, pages)
+			}
+		}
+	})
+}
+
+func TestListBugsFilterExpression(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		seedQueryBugs(t, store)
+
+		filter, err := bugquery.Parse("(status:Open OR priority:Medium) -title:login", time.Now())
+		assert.NoError(t, err)
+
+		query := BugQuery{Filter: filter, Priorities: []string{"High", "Medium"}, Limit: 2}
+		bugs, next, err := store.ListBugs(query)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 3}, bugIDs(bugs))
+		assert.NotEmpty(t, next)
+
+		query.Cursor = next
+		bugs, next, err = store.ListBugs(query)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{5}, bugIDs(bugs))
+		assert.Empty(t, next)
+	})
+}
+
+func TestListBugsPaginationSurvivesUpdates(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		seedQueryBugs(t, store)
+
+		bugs, next, err := store.ListBugs(BugQuery{SortBy: SortByStatus, Limit: 3})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 3, 1}, bugIDs(bugs))
+
+		// Moving an already listed bug must not make it appear again.
+		bug, err := store.GetBug(2)
+		assert.NoError(t, err)
+		bug.Status = "Reopened"
+		assert.NoError(t, store.UpdateBug(bug))
+
+		bugs, _, err = store.ListBugs(BugQuery{SortBy: SortByStatus, Limit: 3, Cursor: next})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{4, 5, 2}, bugIDs(bugs))
+	})
+}
+
+func TestListBugsInvalidQuery(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		_, _, err := store.ListBugs(BugQuery{SortBy: "severity"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid sort field")
+
+		_, _, err = store.ListBugs(BugQuery{Cursor: "not-a-cursor"})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+}
+
+func TestBoltRebuildsMissingIndexes(t *testing.T) {
+	defer testutil.CleanupTestDB()
+	path := testutil.GetTestDBPath()
+
+	store, err := OpenBolt(path)
+	assert.NoError(t, err)
+	seedQueryBugs(t, store)
+
+	// Simulate a database written before the indexes existed.
+	err = store.db.Update(func(tx *bbolt.Tx) error {
+		return tx.DeleteBucket(bugIndexBucket)
+	})
+	assert.NoError(t, err)
+	store.Close()
+
+	store, err = OpenBolt(path)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	bugs, _, err := store.ListBugs(BugQuery{SortBy: SortByTitle})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{5, 1, 4, 3, 2}, bugIDs(bugs))
+}
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"sort"
+
+	"bugtracker-backend/internal/models"
+	"bugtracker-backend/internal/search"
+)
+
+// snippetWidth is the approximate length of description and comment
+// excerpts returned with search results.
+const snippetWidth = 160
+
+// SearchHit is one ranked full-text search result. Highlights holds
+// HTML-escaped excerpts with matching words wrapped in <mark> tags, keyed by
+// "title", "description" and "comment".
+type SearchHit struct {
+	Bug        *models.Bug       `json:"bug"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// searchIndex is implemented by every backend's transaction type to persist
+// the inverted index used by SearchBugs. The ranking and highlighting on top
+// of it are shared.
+type searchIndex interface {
+	// putSearchDoc replaces any previously indexed version of the document.
+	putSearchDoc(doc *search.Document) error
+	deleteSearchDoc(id int) error
+	clearSearchIndex() error
+	// searchPostings maps the IDs of documents containing term to the
+	// term's weighted frequency in each.
+	searchPostings(term string) (map[int]int, error)
+	searchDocLength(id int) (int, error)
+	searchStats() (search.Stats, error)
+}
+
+// indexBugForSearch (re)indexes a bug's title, description and comments.
+func indexBugForSearch(tx Tx, idx searchIndex, bugID int) error {
+	bug, err := tx.GetBug(bugID)
+	if err != nil {
+		return err
+	}
+	comments, err := tx.GetComments(bugID)
+	if err != nil {
+		return err
+	}
+
+	doc := search.NewDocument(bug.ID)
+	doc.AddField(bug.Title, search.TitleWeight)
+	doc.AddField(bug.Description, search.BodyWeight)
+	for _, c := range comments {
+		if c.DeletedAt == nil {
+			doc.AddField(c.Content, search.BodyWeight)
+		}
+	}
+	return idx.putSearchDoc(doc)
+}
+
+func rebuildSearchIndex(tx Tx, idx searchIndex) (int, error) {
+	if err := idx.clearSearchIndex(); err != nil {
+		return 0, err
+	}
+	bugs, err := tx.GetAllBugs()
+	if err != nil {
+		return 0, err
+	}
+	for _, bug := range bugs {
+		if err := indexBugForSearch(tx, idx, bug.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(bugs), nil
+}
+
+// searchBugs ranks the indexed bugs against query with BM25 and returns the
+// best limit hits, or all of them when limit is zero.
+func searchBugs(tx Tx, idx searchIndex, query string, limit int) ([]SearchHit, error) {
+	terms := search.QueryTerms(query)
+	if len(terms) == 0 {
+		return []SearchHit{}, nil
+	}
+
+	stats, err := idx.searchStats()
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[int]float64)
+	lengths := make(map[int]int)
+	for _, term := range terms {
+		postings, err := idx.searchPostings(term)
+		if err != nil {
+			return nil, err
+		}
+		for id, tf := range postings {
+			if _, ok := lengths[id]; !ok {
+				if lengths[id], err = idx.searchDocLength(id); err != nil {
+					return nil, err
+				}
+			}
+			scores[id] += search.BM25(tf, lengths[id], len(postings), stats)
+		}
+	}
+
+	ids := make([]int, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	matched := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		matched[term] = true
+	}
+
+	hits := make([]SearchHit, 0, len(ids))
+	for _, id := range ids {
+		bug, err := tx.GetBug(id)
+		if err != nil {
+			return nil, err
+		}
+		highlights, err := searchHighlights(tx, bug, matched)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, SearchHit{Bug: bug, Score: scores[id], Highlights: highlights})
+	}
+	return hits, nil
+}
+
+func searchHighlights(tx Tx, bug *models.Bug, terms map[string]bool) (map[string]string, error) {
+	highlights := make(map[string]string)
+	if s := search.Highlight(bug.Title, terms, 0); s != "" {
+		highlights["title"] = s
+	}
+	if s := search.Highlight(bug.Description, terms, snippetWidth); s != "" {
+		highlights["description"] = s
+	}
+
+	comments, err := tx.GetComments(bug.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range comments {
+		if c.DeletedAt != nil {
+			continue
+		}
+		if s := search.Highlight(c.Content, terms, snippetWidth); s != "" {
+			highlights["comment"] = s
+			break
+		}
+	}
+	return highlights, nil
+}
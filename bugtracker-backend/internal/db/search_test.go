@@ -0,0 +1,117 @@
+package db
+
+import (
+	"testing"
+
+	"bugtracker-backend/internal/models"
+	"bugtracker-backend/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func searchIDs(hits []SearchHit) []int {
+	ids := make([]int, len(hits))
+	for i, h := range hits {
+		ids[i] = h.Bug.ID
+	}
+	return ids
+}
+
+func TestSearchBugs(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		bugs := []*models.Bug{
+			{Title: "Login page crashes", Description: "Submitting the form shows a blank page"},
+			{Title: "Slow dashboard", Description: "The dashboard crashed once while loading charts"},
+			{Title: "Typo in footer", Description: "Copyright year is wrong"},
+		}
+		for _, bug := range bugs {
+			assert.NoError(t, store.CreateBug(bug))
+		}
+		assert.NoError(t, store.CreateComment(bugs[2].ID, &models.Comment{
+			Author:  "User1",
+			Content: "Also the login link in the footer is broken",
+		}))
+
+		t.Run("Stemmed terms match and title ranks higher", func(t *testing.T) {
+			hits, err := store.SearchBugs("crashing", 0)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{bugs[0].ID, bugs[1].ID}, searchIDs(hits))
+			assert.Equal(t, "Login page <mark>crashes</mark>", hits[0].Highlights["title"])
+			assert.Contains(t, hits[1].Highlights["description"], "<mark>crashed</mark>")
+		})
+
+		t.Run("Comments are searched", func(t *testing.T) {
+			hits, err := store.SearchBugs("broken", 0)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{bugs[2].ID}, searchIDs(hits))
+			assert.Contains(t, hits[0].Highlights["comment"], "<mark>broken</mark>")
+		})
+
+		t.Run("Limit", func(t *testing.T) {
+			hits, err := store.SearchBugs("login", 1)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{bugs[0].ID}, searchIDs(hits))
+		})
+
+		t.Run("Stop words only", func(t *testing.T) {
+			hits, err := store.SearchBugs("the and of", 0)
+			assert.NoError(t, err)
+			assert.Empty(t, hits)
+		})
+	})
+}
+
+func TestSearchIndexFollowsChanges(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		bug := &models.Bug{Title: "Broken upload", Description: "Large files time out"}
+		assert.NoError(t, store.CreateBug(bug))
+
+		bug.Title = "Slow upload"
+		assert.NoError(t, store.UpdateBug(bug))
+
+		hits, err := store.SearchBugs("broken", 0)
+		assert.NoError(t, err)
+		assert.Empty(t, hits)
+
+		hits, err = store.SearchBugs("slow", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{bug.ID}, searchIDs(hits))
+
+		assert.NoError(t, store.DeleteBug(bug.ID))
+		hits, err = store.SearchBugs("slow", 0)
+		assert.NoError(t, err)
+		assert.Empty(t, hits)
+	})
+}
+
+func TestSearchIndexPersistsAndRebuilds(t *testing.T) {
+	defer testutil.CleanupTestDB()
+
+	for _, backend := range persistentBackends {
+		t.Run(backend, func(t *testing.T) {
+			path := testutil.GetTestDBPath()
+
+			store, err := Open(backend, path)
+			assert.NoError(t, err)
+			assert.NoError(t, store.CreateBug(&models.Bug{Title: "Memory leak in worker"}))
+			assert.NoError(t, store.CreateBug(&models.Bug{Title: "Worker restarts"}))
+			store.Close()
+
+			store, err = Open(backend, path)
+			assert.NoError(t, err)
+			defer store.Close()
+
+			hits, err := store.SearchBugs("leak", 0)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{1}, searchIDs(hits))
+
+			count, err := store.RebuildSearchIndex()
+			assert.NoError(t, err)
+			assert.Equal(t, 2, count)
+
+			hits, err = store.SearchBugs("workers", 0)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, []int{1, 2}, searchIDs(hits))
+		})
+	}
+}
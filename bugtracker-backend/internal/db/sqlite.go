@@ -0,0 +1,1797 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"bugtracker-backend/internal/markdown"
+	"bugtracker-backend/internal/models"
+	"bugtracker-backend/internal/search"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMigration is one step of the schema history. fn, if set, runs
+// after sql in the same transaction, for changes SQL cannot express.
+type sqliteMigration struct {
+	sql string
+	fn  func(tx *sql.Tx) error
+}
+
+// sqliteMigrations are applied in order; PRAGMA user_version records how
+// many have already run against a database file.
+var sqliteMigrations = []sqliteMigration{
+	{
+		sql: `CREATE TABLE bugs (
+			id          INTEGER PRIMARY KEY,
+			title       TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			status      TEXT NOT NULL DEFAULT '',
+			priority    TEXT NOT NULL DEFAULT '',
+			created_at  TEXT NOT NULL,
+			updated_at  TEXT NOT NULL
+		);
+		CREATE INDEX idx_bugs_status ON bugs (status);
+		CREATE INDEX idx_bugs_priority ON bugs (priority);
+		CREATE INDEX idx_bugs_created_at ON bugs (created_at);
+		CREATE INDEX idx_bugs_updated_at ON bugs (updated_at);
+
+		CREATE TABLE comments (
+			id         INTEGER PRIMARY KEY,
+			bug_id     INTEGER NOT NULL,
+			content    TEXT NOT NULL,
+			author     TEXT NOT NULL,
+			created_at TEXT NOT NULL
+		);
+		CREATE INDEX idx_comments_bug_id ON comments (bug_id, created_at);
+
+		CREATE TABLE counters (
+			name  TEXT PRIMARY KEY,
+			value INTEGER NOT NULL
+		);`,
+	},
+	{
+		sql: `CREATE TABLE search_docs (
+			bug_id INTEGER PRIMARY KEY,
+			length INTEGER NOT NULL
+		);
+
+		CREATE TABLE search_postings (
+			term   TEXT NOT NULL,
+			bug_id INTEGER NOT NULL,
+			tf     INTEGER NOT NULL,
+			PRIMARY KEY (term, bug_id)
+		) WITHOUT ROWID;
+		CREATE INDEX idx_search_postings_bug_id ON search_postings (bug_id);`,
+	},
+	{
+		sql: `ALTER TABLE bugs ADD COLUMN resolution TEXT NOT NULL DEFAULT '';
+		ALTER TABLE bugs ADD COLUMN resolved_at TEXT;
+		ALTER TABLE bugs ADD COLUMN reopen_count INTEGER NOT NULL DEFAULT 0;
+		CREATE INDEX idx_bugs_resolution ON bugs (resolution);`,
+	},
+	{
+		sql: `ALTER TABLE bugs ADD COLUMN version INTEGER NOT NULL DEFAULT 1;`,
+	},
+	{
+		sql: `ALTER TABLE bugs ADD COLUMN deleted_at TEXT;
+		ALTER TABLE bugs ADD COLUMN deleted_by TEXT NOT NULL DEFAULT '';
+		CREATE INDEX idx_bugs_deleted_at ON bugs (deleted_at);`,
+	},
+	{
+		sql: `CREATE TABLE bug_history (
+			id         INTEGER PRIMARY KEY,
+			bug_id     INTEGER NOT NULL,
+			action     TEXT NOT NULL,
+			actor      TEXT NOT NULL DEFAULT '',
+			at         TEXT NOT NULL,
+			comment_id INTEGER NOT NULL DEFAULT 0,
+			changes    TEXT NOT NULL DEFAULT '[]'
+		);
+		CREATE INDEX idx_bug_history_bug_id ON bug_history (bug_id, id);`,
+	},
+	{
+		sql: `CREATE TABLE audit_log (
+			seq         INTEGER PRIMARY KEY,
+			at          TEXT NOT NULL,
+			actor       TEXT NOT NULL DEFAULT '',
+			action      TEXT NOT NULL,
+			method      TEXT NOT NULL,
+			path        TEXT NOT NULL,
+			status      INTEGER NOT NULL,
+			remote_addr TEXT NOT NULL DEFAULT '',
+			prev_hash   TEXT NOT NULL,
+			hash        TEXT NOT NULL
+		);
+		CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+		BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END;
+		CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+		BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END;`,
+	},
+	{
+		// Older versions gave comments random 32-bit IDs. Renumber them
+		// in the order they were written and continue from the counter.
+		sql: `CREATE TEMP TABLE comment_ids AS
+			SELECT id AS old_id, ROW_NUMBER() OVER (ORDER BY created_at, id) AS new_id FROM comments;
+		UPDATE comments SET id = -id;
+		UPDATE comments SET id = (SELECT new_id FROM comment_ids WHERE old_id = -comments.id);
+		UPDATE bug_history SET comment_id = (SELECT new_id FROM comment_ids WHERE old_id = bug_history.comment_id)
+			WHERE comment_id IN (SELECT old_id FROM comment_ids);
+		INSERT INTO counters (name, value) SELECT 'lastCommentID', COUNT(*) FROM comment_ids;
+		DROP TABLE comment_ids;
+		DROP INDEX idx_comments_bug_id;
+		CREATE INDEX idx_comments_bug_id ON comments (bug_id, id);`,
+	},
+	{
+		sql: `ALTER TABLE comments ADD COLUMN updated_at TEXT;
+		ALTER TABLE comments ADD COLUMN deleted_at TEXT;
+		ALTER TABLE comments ADD COLUMN deleted_by TEXT NOT NULL DEFAULT '';
+		CREATE TABLE comment_revisions (
+			comment_id INTEGER NOT NULL,
+			revision   INTEGER NOT NULL,
+			content    TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (comment_id, revision)
+		);`,
+	},
+	{
+		sql: `ALTER TABLE comments ADD COLUMN parent_id INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE comments ADD COLUMN depth INTEGER NOT NULL DEFAULT 0;`,
+	},
+	{
+		sql: `ALTER TABLE bugs ADD COLUMN description_html TEXT NOT NULL DEFAULT '';
+		ALTER TABLE comments ADD COLUMN content_html TEXT NOT NULL DEFAULT '';`,
+		fn: renderSQLiteMarkdown,
+	},
+	{
+		sql: `CREATE TABLE users (
+			id            INTEGER PRIMARY KEY,
+			username      TEXT NOT NULL UNIQUE,
+			display_name  TEXT NOT NULL DEFAULT '',
+			password_hash TEXT NOT NULL,
+			created_at    TEXT NOT NULL
+		);
+		CREATE TABLE sessions (
+			token_hash TEXT PRIMARY KEY,
+			user_id    INTEGER NOT NULL,
+			created_at TEXT NOT NULL,
+			expires_at TEXT NOT NULL
+		);
+		CREATE INDEX idx_sessions_expires_at ON sessions (expires_at);
+		ALTER TABLE bugs ADD COLUMN reporter TEXT NOT NULL DEFAULT '';`,
+	},
+	{
+		sql: `CREATE TABLE api_tokens (
+			id           INTEGER PRIMARY KEY,
+			user_id      INTEGER NOT NULL,
+			name         TEXT NOT NULL,
+			scope        TEXT NOT NULL,
+			token_hash   TEXT NOT NULL UNIQUE,
+			created_at   TEXT NOT NULL,
+			expires_at   TEXT,
+			last_used_at TEXT
+		);
+		CREATE INDEX idx_api_tokens_user_id ON api_tokens (user_id);`,
+	},
+	{
+		sql: `ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'reporter';`,
+	},
+	{
+		sql: `CREATE TABLE identities (
+			issuer     TEXT NOT NULL,
+			subject    TEXT NOT NULL,
+			user_id    INTEGER NOT NULL,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (issuer, subject)
+		);`,
+	},
+	{
+		sql: `CREATE TABLE projects (
+			id          INTEGER PRIMARY KEY,
+			key         TEXT NOT NULL UNIQUE,
+			name        TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			created_at  TEXT NOT NULL
+		);
+		ALTER TABLE bugs ADD COLUMN project TEXT NOT NULL DEFAULT '';
+		ALTER TABLE bugs ADD COLUMN number INTEGER NOT NULL DEFAULT 0;`,
+		fn: assignSQLiteProjects,
+	},
+}
+
+// SQLiteStore is a Store backed by an embedded SQLite database.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+type sqliteTx struct {
+	tx *sql.Tx
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// SQLite allows a single writer; serialising connections avoids
+	// SQLITE_BUSY errors between concurrent transactions.
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	store := &SQLiteStore{db: db}
+	if err := store.ensureSearchIndex(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to build search index: %w", err)
+	}
+
+	log.Println("Database initialized successfully.")
+	return store, nil
+}
+
+// ensureSearchIndex rebuilds the search index when it does not cover every
+// bug, as after the migration that introduced it. It runs once the schema
+// is fully migrated because indexing reads bugs with the current columns.
+func (s *SQLiteStore) ensureSearchIndex() error {
+	var missing bool
+	err := s.db.QueryRow(`SELECT (SELECT COUNT(*) FROM bugs WHERE deleted_at IS NULL) != (SELECT COUNT(*) FROM search_docs)`).Scan(&missing)
+	if err != nil || !missing {
+		return err
+	}
+	log.Println("Rebuilding search index...")
+	_, err = s.RebuildSearchIndex()
+	return err
+}
+
+func migrateSQLite(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		if err := applySQLiteMigration(db, i); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func applySQLiteMigration(db *sql.DB, i int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	m := sqliteMigrations[i]
+	if _, err := tx.Exec(m.sql); err != nil {
+		return err
+	}
+	if m.fn != nil {
+		if err := m.fn(tx); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// renderSQLiteMarkdown fills in the rendered HTML of bugs and comments
+// written before it was stored.
+func renderSQLiteMarkdown(tx *sql.Tx) error {
+	for _, table := range []struct{ name, src, dst string }{
+		{"bugs", "description", "description_html"},
+		{"comments", "content", "content_html"},
+	} {
+		rows, err := tx.Query(`SELECT id, ` + table.src + ` FROM ` + table.name + ` WHERE ` + table.src + ` != ''`)
+		if err != nil {
+			return err
+		}
+		sources := make(map[int]string)
+		for rows.Next() {
+			var id int
+			var src string
+			if err := rows.Scan(&id, &src); err != nil {
+				rows.Close()
+				return err
+			}
+			sources[id] = src
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for id, src := range sources {
+			_, err := tx.Exec(`UPDATE `+table.name+` SET `+table.dst+` = ? WHERE id = ?`, markdown.Render(src), id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// assignSQLiteProjects creates the default project and files the bugs
+// written by older versions in it, numbered by their IDs, so that bug 42
+// becomes BUG-42.
+func assignSQLiteProjects(tx *sql.Tx) error {
+	t := &sqliteTx{tx: tx}
+	if err := t.CreateProject(models.DefaultProject()); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE bugs SET project = ?, number = id`, models.DefaultProjectKey); err != nil {
+		return err
+	}
+	// New bugs continue from the highest ID so far.
+	_, err := tx.Exec(`INSERT INTO counters (name, value)
+		SELECT ?, COALESCE((SELECT value FROM counters WHERE name = ?), 0)`,
+		bugNumberCounter(models.DefaultProjectKey), bugCounter)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`CREATE UNIQUE INDEX idx_bugs_project_number ON bugs (project, number)`)
+	return err
+}
+
+func (s *SQLiteStore) View(fn func(tx Tx) error) error {
+	return s.run(true, fn)
+}
+
+func (s *SQLiteStore) Update(fn func(tx Tx) error) error {
+	return s.run(false, fn)
+}
+
+func (s *SQLiteStore) run(readOnly bool, fn func(tx Tx) error) error {
+	if s.db == nil {
+		return ErrNotInitialized
+	}
+
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// The driver ignores sql.TxOptions.ReadOnly, so read-only transactions
+	// are enforced on the connection instead.
+	if readOnly {
+		if _, err := conn.ExecContext(ctx, `PRAGMA query_only = 1`); err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, `PRAGMA query_only = 0`)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(&sqliteTx{tx: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
+
+func (s *SQLiteStore) CreateBug(bug *models.Bug) error {
+	return s.Update(func(tx Tx) error { return tx.CreateBug(bug) })
+}
+
+func (s *SQLiteStore) GetBug(id int) (bug *models.Bug, err error) {
+	err = s.View(func(tx Tx) error {
+		bug, err = tx.GetBug(id)
+		return err
+	})
+	return bug, err
+}
+
+func (s *SQLiteStore) GetAllBugs() (bugs []*models.Bug, err error) {
+	err = s.View(func(tx Tx) error {
+		bugs, err = tx.GetAllBugs()
+		return err
+	})
+	return bugs, err
+}
+
+func (s *SQLiteStore) ListBugs(q BugQuery) (bugs []*models.Bug, next string, err error) {
+	err = s.View(func(tx Tx) error {
+		bugs, next, err = tx.ListBugs(q)
+		return err
+	})
+	return bugs, next, err
+}
+
+func (s *SQLiteStore) UpdateBug(bug *models.Bug) error {
+	return s.Update(func(tx Tx) error { return tx.UpdateBug(bug) })
+}
+
+func (s *SQLiteStore) DeleteBug(id int) error {
+	return s.Update(func(tx Tx) error { return tx.DeleteBug(id) })
+}
+
+func (s *SQLiteStore) TrashBug(id int, by string, at time.Time) error {
+	return s.Update(func(tx Tx) error { return tx.TrashBug(id, by, at) })
+}
+
+func (s *SQLiteStore) RestoreBug(id int) (bug *models.Bug, err error) {
+	err = s.Update(func(tx Tx) error {
+		bug, err = tx.RestoreBug(id)
+		return err
+	})
+	return bug, err
+}
+
+func (s *SQLiteStore) PurgeTrash(before time.Time) (count int, err error) {
+	err = s.Update(func(tx Tx) error {
+		count, err = tx.PurgeTrash(before)
+		return err
+	})
+	return count, err
+}
+
+func (s *SQLiteStore) CreateComment(bugID int, comment *models.Comment) error {
+	return s.Update(func(tx Tx) error { return tx.CreateComment(bugID, comment) })
+}
+
+func (s *SQLiteStore) GetComments(bugID int) (comments []models.Comment, err error) {
+	err = s.View(func(tx Tx) error {
+		comments, err = tx.GetComments(bugID)
+		return err
+	})
+	return comments, err
+}
+
+func (s *SQLiteStore) ListComments(bugID int, q CommentQuery) (comments []models.Comment, next string, err error) {
+	err = s.View(func(tx Tx) error {
+		comments, next, err = tx.ListComments(bugID, q)
+		return err
+	})
+	return comments, next, err
+}
+
+func (s *SQLiteStore) GetComment(bugID, id int) (comment *models.Comment, err error) {
+	err = s.View(func(tx Tx) error {
+		comment, err = tx.GetComment(bugID, id)
+		return err
+	})
+	return comment, err
+}
+
+func (s *SQLiteStore) EditComment(bugID, id int, content string, at time.Time) (comment *models.Comment, err error) {
+	err = s.Update(func(tx Tx) error {
+		comment, err = tx.EditComment(bugID, id, content, at)
+		return err
+	})
+	return comment, err
+}
+
+func (s *SQLiteStore) DeleteComment(bugID, id int, by string, at time.Time) (comment *models.Comment, err error) {
+	err = s.Update(func(tx Tx) error {
+		comment, err = tx.DeleteComment(bugID, id, by, at)
+		return err
+	})
+	return comment, err
+}
+
+func (s *SQLiteStore) RestoreComment(bugID, id int) (comment *models.Comment, err error) {
+	err = s.Update(func(tx Tx) error {
+		comment, err = tx.RestoreComment(bugID, id)
+		return err
+	})
+	return comment, err
+}
+
+func (s *SQLiteStore) GetCommentRevisions(bugID, id int) (revs []models.CommentRevision, err error) {
+	err = s.View(func(tx Tx) error {
+		revs, err = tx.GetCommentRevisions(bugID, id)
+		return err
+	})
+	return revs, err
+}
+
+func (s *SQLiteStore) OrphanedComments() (comments []models.Comment, err error) {
+	err = s.View(func(tx Tx) error {
+		comments, err = tx.OrphanedComments()
+		return err
+	})
+	return comments, err
+}
+
+func (s *SQLiteStore) PurgeOrphanedComments() (count int, err error) {
+	err = s.Update(func(tx Tx) error {
+		count, err = tx.PurgeOrphanedComments()
+		return err
+	})
+	return count, err
+}
+
+func (s *SQLiteStore) AddHistory(entry *models.HistoryEntry) error {
+	return s.Update(func(tx Tx) error { return tx.AddHistory(entry) })
+}
+
+func (s *SQLiteStore) GetHistory(bugID int) (entries []models.HistoryEntry, err error) {
+	err = s.View(func(tx Tx) error {
+		entries, err = tx.GetHistory(bugID)
+		return err
+	})
+	return entries, err
+}
+
+func (s *SQLiteStore) AppendAudit(entry *models.AuditEntry) error {
+	return s.Update(func(tx Tx) error { return tx.AppendAudit(entry) })
+}
+
+func (s *SQLiteStore) ListAudit(q AuditQuery) (entries []models.AuditEntry, err error) {
+	err = s.View(func(tx Tx) error {
+		entries, err = tx.ListAudit(q)
+		return err
+	})
+	return entries, err
+}
+
+func (s *SQLiteStore) CreateUser(user *models.User) error {
+	return s.Update(func(tx Tx) error { return tx.CreateUser(user) })
+}
+
+func (s *SQLiteStore) GetUser(id int) (user *models.User, err error) {
+	err = s.View(func(tx Tx) error {
+		user, err = tx.GetUser(id)
+		return err
+	})
+	return user, err
+}
+
+func (s *SQLiteStore) GetUserByName(username string) (user *models.User, err error) {
+	err = s.View(func(tx Tx) error {
+		user, err = tx.GetUserByName(username)
+		return err
+	})
+	return user, err
+}
+
+func (s *SQLiteStore) ListUsers() (users []models.User, err error) {
+	err = s.View(func(tx Tx) error {
+		users, err = tx.ListUsers()
+		return err
+	})
+	return users, err
+}
+
+func (s *SQLiteStore) UpdateUser(user *models.User) error {
+	return s.Update(func(tx Tx) error { return tx.UpdateUser(user) })
+}
+
+func (s *SQLiteStore) CreateIdentity(identity *models.Identity) error {
+	return s.Update(func(tx Tx) error { return tx.CreateIdentity(identity) })
+}
+
+func (s *SQLiteStore) GetIdentity(issuer, subject string) (identity *models.Identity, err error) {
+	err = s.View(func(tx Tx) error {
+		identity, err = tx.GetIdentity(issuer, subject)
+		return err
+	})
+	return identity, err
+}
+
+func (s *SQLiteStore) CreateSession(session *models.Session) error {
+	return s.Update(func(tx Tx) error { return tx.CreateSession(session) })
+}
+
+func (s *SQLiteStore) GetSession(tokenHash string) (session *models.Session, err error) {
+	err = s.View(func(tx Tx) error {
+		session, err = tx.GetSession(tokenHash)
+		return err
+	})
+	return session, err
+}
+
+func (s *SQLiteStore) DeleteSession(tokenHash string) error {
+	return s.Update(func(tx Tx) error { return tx.DeleteSession(tokenHash) })
+}
+
+func (s *SQLiteStore) DeleteExpiredSessions(now time.Time) (count int, err error) {
+	err = s.Update(func(tx Tx) error {
+		count, err = tx.DeleteExpiredSessions(now)
+		return err
+	})
+	return count, err
+}
+
+func (s *SQLiteStore) CreateAPIToken(token *models.APIToken) error {
+	return s.Update(func(tx Tx) error { return tx.CreateAPIToken(token) })
+}
+
+func (s *SQLiteStore) GetAPIToken(tokenHash string) (token *models.APIToken, err error) {
+	err = s.View(func(tx Tx) error {
+		token, err = tx.GetAPIToken(tokenHash)
+		return err
+	})
+	return token, err
+}
+
+func (s *SQLiteStore) ListAPITokens(userID int) (tokens []models.APIToken, err error) {
+	err = s.View(func(tx Tx) error {
+		tokens, err = tx.ListAPITokens(userID)
+		return err
+	})
+	return tokens, err
+}
+
+func (s *SQLiteStore) DeleteAPIToken(userID, id int) error {
+	return s.Update(func(tx Tx) error { return tx.DeleteAPIToken(userID, id) })
+}
+
+func (s *SQLiteStore) TouchAPIToken(id int, at time.Time) error {
+	return s.Update(func(tx Tx) error { return tx.TouchAPIToken(id, at) })
+}
+
+func (s *SQLiteStore) GetBugID(project string, number int) (id int, err error) {
+	err = s.View(func(tx Tx) error {
+		id, err = tx.GetBugID(project, number)
+		return err
+	})
+	return id, err
+}
+
+func (s *SQLiteStore) CreateProject(project *models.Project) error {
+	return s.Update(func(tx Tx) error { return tx.CreateProject(project) })
+}
+
+func (s *SQLiteStore) GetProject(key string) (project *models.Project, err error) {
+	err = s.View(func(tx Tx) error {
+		project, err = tx.GetProject(key)
+		return err
+	})
+	return project, err
+}
+
+func (s *SQLiteStore) ListProjects() (projects []models.Project, err error) {
+	err = s.View(func(tx Tx) error {
+		projects, err = tx.ListProjects()
+		return err
+	})
+	return projects, err
+}
+
+func (s *SQLiteStore) SearchBugs(query string, limit int) (hits []SearchHit, err error) {
+	err = s.View(func(tx Tx) error {
+		hits, err = tx.SearchBugs(query, limit)
+		return err
+	})
+	return hits, err
+}
+
+func (s *SQLiteStore) RebuildSearchIndex() (count int, err error) {
+	err = s.Update(func(tx Tx) error {
+		count, err = tx.RebuildSearchIndex()
+		return err
+	})
+	return count, err
+}
+
+func (s *SQLiteStore) NextID(counter string) (id int, err error) {
+	err = s.Update(func(tx Tx) error {
+		id, err = tx.NextID(counter)
+		return err
+	})
+	return id, err
+}
+
+const sqliteBugColumns = `id, title, description, status, priority, created_at, updated_at,
+	resolution, resolved_at, reopen_count, version, deleted_at, deleted_by, description_html, reporter, project, number`
+
+func scanSQLiteBug(row rowScanner) (*models.Bug, error) {
+	var bug models.Bug
+	var createdAt, updatedAt string
+	var resolvedAt, deletedAt sql.NullString
+	err := row.Scan(&bug.ID, &bug.Title, &bug.Description, &bug.Status, &bug.Priority, &createdAt, &updatedAt,
+		&bug.Resolution, &resolvedAt, &bug.ReopenCount, &bug.Version, &deletedAt, &bug.DeletedBy, &bug.DescriptionHTML, &bug.Reporter,
+		&bug.Project, &bug.Number)
+	if err != nil {
+		return nil, err
+	}
+	bug.Key = models.BugKey(bug.Project, bug.Number)
+	if bug.CreatedAt, err = parseSortableTime(createdAt); err != nil {
+		return nil, err
+	}
+	if bug.UpdatedAt, err = parseSortableTime(updatedAt); err != nil {
+		return nil, err
+	}
+	if bug.ResolvedAt, err = parseSQLiteNullTime(resolvedAt); err != nil {
+		return nil, err
+	}
+	if bug.DeletedAt, err = parseSQLiteNullTime(deletedAt); err != nil {
+		return nil, err
+	}
+	return &bug, nil
+}
+
+// sqliteNullTime formats an optional time for a nullable TEXT column.
+func sqliteNullTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return formatSortableTime(*t)
+}
+
+func parseSQLiteNullTime(s sql.NullString) (*time.Time, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	t, err := parseSortableTime(s.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (t *sqliteTx) CreateBug(bug *models.Bug) error {
+	if err := numberBug(t, bug); err != nil {
+		return err
+	}
+	nextID, err := t.NextID(bugCounter)
+	if err != nil {
+		return err
+	}
+
+	bug.ID = nextID
+	bug.Version = 1
+	bug.RenderDescription()
+
+	_, err = t.tx.Exec(`INSERT INTO bugs (`+sqliteBugColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		bug.ID, bug.Title, bug.Description, bug.Status, bug.Priority,
+		formatSortableTime(bug.CreatedAt), formatSortableTime(bug.UpdatedAt),
+		bug.Resolution, sqliteNullTime(bug.ResolvedAt), bug.ReopenCount, bug.Version,
+		sqliteNullTime(bug.DeletedAt), bug.DeletedBy, bug.DescriptionHTML, bug.Reporter, bug.Project, bug.Number)
+	if err != nil {
+		return fmt.Errorf("failed to insert bug: %w", err)
+	}
+	return indexBugForSearch(t, t, bug.ID)
+}
+
+func (t *sqliteTx) GetBug(id int) (*models.Bug, error) {
+	bug, err := t.queryBug(`SELECT `+sqliteBugColumns+` FROM bugs WHERE id = ? AND deleted_at IS NULL`, id)
+	if err != nil {
+		return nil, err
+	}
+	return bug, fillCommentCounts(t, bug)
+}
+
+func (t *sqliteTx) loadBug(id int) (*models.Bug, error) {
+	return t.queryBug(`SELECT `+sqliteBugColumns+` FROM bugs WHERE id = ?`, id)
+}
+
+func (t *sqliteTx) queryBug(query string, id int) (*models.Bug, error) {
+	row := t.tx.QueryRow(query, id)
+	bug, err := scanSQLiteBug(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrBugNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load bug %d: %w", id, err)
+	}
+	return bug, nil
+}
+
+func (t *sqliteTx) GetAllBugs() ([]*models.Bug, error) {
+	rows, err := t.tx.Query(`SELECT ` + sqliteBugColumns + ` FROM bugs WHERE deleted_at IS NULL ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bugs []*models.Bug
+	for rows.Next() {
+		bug, err := scanSQLiteBug(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load bug: %w", err)
+		}
+		bugs = append(bugs, bug)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return bugs, fillCommentCounts(t, bugs...)
+}
+
+// sqliteSortKeys holds the SQL expression computing bugSortKey for each
+// indexed sort field.
+var sqliteSortKeys = map[string]string{
+	SortByCreatedAt: "created_at",
+	SortByUpdatedAt: "updated_at",
+	SortByPriority:  sqlitePriorityRank(),
+	SortByStatus:    "status",
+	SortByTitle:     "title",
+}
+
+// sqlitePriorityRank builds a CASE expression giving models.PriorityRank of
+// the priority column as a string.
+func sqlitePriorityRank() string {
+	var b strings.Builder
+	b.WriteString("CASE priority")
+	for _, priority := range models.Priorities {
+		fmt.Fprintf(&b, " WHEN '%s' THEN '%d'", priority, models.PriorityRank(priority))
+	}
+	b.WriteString(" ELSE '0' END")
+	return b.String()
+}
+
+func (t *sqliteTx) ListBugs(q BugQuery) ([]*models.Bug, string, error) {
+	if err := q.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	where := []string{"deleted_at IS NULL"}
+	if q.Trashed {
+		where[0] = "deleted_at IS NOT NULL"
+	}
+	var args []interface{}
+
+	if q.Project != "" {
+		where = append(where, "project = ?")
+		args = append(args, q.Project)
+	}
+	if len(q.Statuses) > 0 {
+		where = append(where, "status IN ("+sqlitePlaceholders(len(q.Statuses))+")")
+		for _, status := range q.Statuses {
+			args = append(args, status)
+		}
+	}
+	if len(q.Priorities) > 0 {
+		where = append(where, "priority IN ("+sqlitePlaceholders(len(q.Priorities))+")")
+		for _, priority := range q.Priorities {
+			args = append(args, priority)
+		}
+	}
+
+	if len(q.Resolutions) > 0 {
+		where = append(where, "resolution IN ("+sqlitePlaceholders(len(q.Resolutions))+")")
+		for _, resolution := range q.Resolutions {
+			args = append(args, resolution)
+		}
+	}
+	if q.MinReopenCount > 0 {
+		where = append(where, "reopen_count >= ?")
+		args = append(args, q.MinReopenCount)
+	}
+
+	// A NULL resolved_at never satisfies a comparison, so unresolved bugs
+	// drop out of resolved ranges as in BugQuery.matches.
+	timeFilters := []struct {
+		clause string
+		value  time.Time
+	}{
+		{"created_at > ?", q.CreatedAfter},
+		{"created_at < ?", q.CreatedBefore},
+		{"updated_at > ?", q.UpdatedAfter},
+		{"updated_at < ?", q.UpdatedBefore},
+		{"resolved_at > ?", q.ResolvedAfter},
+		{"resolved_at < ?", q.ResolvedBefore},
+	}
+	for _, f := range timeFilters {
+		if !f.value.IsZero() {
+			where = append(where, f.clause)
+			args = append(args, formatSortableTime(f.value))
+		}
+	}
+
+	cmp, dir := ">", "ASC"
+	if q.Descending {
+		cmp, dir = "<", "DESC"
+	}
+
+	orderBy := "id " + dir
+	if q.SortBy != SortByID {
+		key := sqliteSortKeys[q.SortBy]
+		orderBy = key + " " + dir + ", " + orderBy
+		if q.Cursor != "" {
+			cur, _ := decodeBugCursor(q.Cursor)
+			where = append(where, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", key, cmp))
+			args = append(args, cur.Key, cur.Key, cur.ID)
+		}
+	} else if q.Cursor != "" {
+		cur, _ := decodeBugCursor(q.Cursor)
+		where = append(where, "id "+cmp+" ?")
+		args = append(args, cur.ID)
+	}
+
+	query := `SELECT ` + sqliteBugColumns + ` FROM bugs WHERE ` + strings.Join(where, " AND ")
+	query += ` ORDER BY ` + orderBy
+	// A query-language filter cannot be expressed in SQL, so it is applied
+	// to the rows as they are read and the limit enforced here instead.
+	if q.Limit > 0 && q.Filter == nil {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit+1)
+	}
+
+	rows, err := t.tx.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var bugs []*models.Bug
+	for rows.Next() {
+		bug, err := scanSQLiteBug(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to load bug: %w", err)
+		}
+		if q.Filter != nil && !q.Filter.Match(bug) {
+			continue
+		}
+		bugs = append(bugs, bug)
+		if q.Limit > 0 && len(bugs) > q.Limit {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	bugs, next := q.page(bugs)
+	if err := fillCommentCounts(t, bugs...); err != nil {
+		return nil, "", err
+	}
+	return bugs, next, nil
+}
+
+func (t *sqliteTx) UpdateBug(bug *models.Bug) error {
+	updatedAt := time.Now()
+	bug.RenderDescription()
+
+	res, err := t.tx.Exec(`UPDATE bugs SET title = ?, description = ?, description_html = ?, status = ?, priority = ?, created_at = ?, updated_at = ?,
+		resolution = ?, resolved_at = ?, reopen_count = ?, deleted_at = ?, deleted_by = ?, reporter = ?, version = version + 1
+		WHERE id = ? AND version = ?`,
+		bug.Title, bug.Description, bug.DescriptionHTML, bug.Status, bug.Priority,
+		formatSortableTime(bug.CreatedAt), formatSortableTime(updatedAt),
+		bug.Resolution, sqliteNullTime(bug.ResolvedAt), bug.ReopenCount,
+		sqliteNullTime(bug.DeletedAt), bug.DeletedBy, bug.Reporter, bug.ID, bug.Version)
+	if err != nil {
+		return fmt.Errorf("failed to update bug: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// Either the bug is gone or its version moved on.
+		stored, err := t.loadBug(bug.ID)
+		if err != nil {
+			return err
+		}
+		return checkBugVersion(stored, bug)
+	}
+
+	bug.UpdatedAt = updatedAt
+	bug.Version++
+	return syncBugSearchDoc(t, t, bug)
+}
+
+func (t *sqliteTx) DeleteBug(id int) error {
+	res, err := t.tx.Exec(`DELETE FROM bugs WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete bug: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrBugNotFound
+	}
+	if _, err := t.tx.Exec(`DELETE FROM comment_revisions WHERE comment_id IN (SELECT id FROM comments WHERE bug_id = ?)`, id); err != nil {
+		return fmt.Errorf("failed to delete comment revisions: %w", err)
+	}
+	if _, err := t.tx.Exec(`DELETE FROM comments WHERE bug_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete comments: %w", err)
+	}
+	if err := t.deleteBugHistory(id); err != nil {
+		return err
+	}
+	return t.deleteSearchDoc(id)
+}
+
+func (t *sqliteTx) TrashBug(id int, by string, at time.Time) error {
+	return trashBug(t, id, by, at)
+}
+
+func (t *sqliteTx) RestoreBug(id int) (*models.Bug, error) {
+	return restoreBug(t, t, id)
+}
+
+func (t *sqliteTx) PurgeTrash(before time.Time) (int, error) {
+	return purgeTrash(t, t, before)
+}
+
+func (t *sqliteTx) CreateComment(bugID int, comment *models.Comment) error {
+	if _, err := t.GetBug(bugID); err != nil {
+		return err
+	}
+	if err := setCommentParent(t, bugID, comment); err != nil {
+		return err
+	}
+
+	id, err := t.NextID(commentCounter)
+	if err != nil {
+		return err
+	}
+	comment.CreatedAt = time.Now()
+	comment.ID = id
+	comment.BugID = bugID
+	comment.RenderContent()
+
+	_, err = t.tx.Exec(`INSERT INTO comments (id, bug_id, content, content_html, author, created_at, parent_id, depth) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		comment.ID, comment.BugID, comment.Content, comment.ContentHTML, comment.Author, formatSortableTime(comment.CreatedAt), comment.ParentID, comment.Depth)
+	if err != nil {
+		return fmt.Errorf("failed to insert comment: %w", err)
+	}
+	return indexBugForSearch(t, t, bugID)
+}
+
+func (t *sqliteTx) ListComments(bugID int, q CommentQuery) ([]models.Comment, string, error) {
+	return listComments(t, bugID, q)
+}
+
+func (t *sqliteTx) GetComments(bugID int) ([]models.Comment, error) {
+	if _, err := t.GetBug(bugID); err != nil {
+		return nil, err
+	}
+
+	return t.queryComments(`SELECT `+sqliteCommentColumns+` FROM comments
+		WHERE bug_id = ? ORDER BY id`, bugID)
+}
+
+func (t *sqliteTx) OrphanedComments() ([]models.Comment, error) {
+	return orphanedComments(t)
+}
+
+func (t *sqliteTx) PurgeOrphanedComments() (int, error) {
+	return purgeOrphanedComments(t, t, t)
+}
+
+func (t *sqliteTx) allComments() ([]models.Comment, error) {
+	return t.queryComments(`SELECT ` + sqliteCommentColumns + ` FROM comments ORDER BY id`)
+}
+
+func (t *sqliteTx) scanBugComments(bugID, afterID int, descending bool, fn func(comment models.Comment) bool) error {
+	query := `SELECT ` + sqliteCommentColumns + ` FROM comments WHERE bug_id = ?`
+	args := []interface{}{bugID}
+	switch {
+	case afterID > 0 && descending:
+		query += ` AND id < ?`
+		args = append(args, afterID)
+	case afterID > 0:
+		query += ` AND id > ?`
+		args = append(args, afterID)
+	}
+	if descending {
+		query += ` ORDER BY id DESC`
+	} else {
+		query += ` ORDER BY id`
+	}
+
+	rows, err := t.tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		comment, err := scanSQLiteComment(rows)
+		if err != nil {
+			return err
+		}
+		if !fn(*comment) {
+			break
+		}
+	}
+	return rows.Err()
+}
+
+// sqliteCountBatch bounds the number of IDs bound to one query, well
+// under SQLite's limit on host parameters.
+const sqliteCountBatch = 500
+
+func (t *sqliteTx) commentCounts(bugIDs []int) (map[int]int, error) {
+	counts := make(map[int]int, len(bugIDs))
+	for len(bugIDs) > 0 {
+		batch := bugIDs
+		if len(batch) > sqliteCountBatch {
+			batch = batch[:sqliteCountBatch]
+		}
+		bugIDs = bugIDs[len(batch):]
+		if err := t.countComments(batch, counts); err != nil {
+			return nil, err
+		}
+	}
+	return counts, nil
+}
+
+func (t *sqliteTx) countComments(bugIDs []int, counts map[int]int) error {
+	placeholders := make([]string, len(bugIDs))
+	args := make([]interface{}, len(bugIDs))
+	for i, id := range bugIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	rows, err := t.tx.Query(`SELECT bug_id, COUNT(*) FROM comments WHERE bug_id IN (`+strings.Join(placeholders, ", ")+`) GROUP BY bug_id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return err
+		}
+		counts[id] = count
+	}
+	return rows.Err()
+}
+
+func (t *sqliteTx) deleteComment(bugID, id int) error {
+	if err := t.deleteCommentRevisions(id); err != nil {
+		return err
+	}
+	if _, err := t.tx.Exec(`DELETE FROM comments WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete comment %d: %w", id, err)
+	}
+	return nil
+}
+
+func (t *sqliteTx) queryComments(query string, args ...interface{}) ([]models.Comment, error) {
+	rows, err := t.tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []models.Comment
+	for rows.Next() {
+		comment, err := scanSQLiteComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, *comment)
+	}
+	return comments, rows.Err()
+}
+
+const sqliteCommentColumns = `id, bug_id, content, author, created_at, updated_at, deleted_at, deleted_by, parent_id, depth, content_html`
+
+func scanSQLiteComment(row rowScanner) (*models.Comment, error) {
+	var comment models.Comment
+	var createdAt string
+	var updatedAt, deletedAt sql.NullString
+	err := row.Scan(&comment.ID, &comment.BugID, &comment.Content, &comment.Author, &createdAt,
+		&updatedAt, &deletedAt, &comment.DeletedBy, &comment.ParentID, &comment.Depth, &comment.ContentHTML)
+	if err != nil {
+		return nil, err
+	}
+	if comment.CreatedAt, err = parseSortableTime(createdAt); err != nil {
+		return nil, err
+	}
+	if comment.UpdatedAt, err = parseSQLiteNullTime(updatedAt); err != nil {
+		return nil, err
+	}
+	if comment.DeletedAt, err = parseSQLiteNullTime(deletedAt); err != nil {
+		return nil, err
+	}
+	comment.Edited = comment.UpdatedAt != nil
+	return &comment, nil
+}
+
+func (t *sqliteTx) GetComment(bugID, id int) (*models.Comment, error) {
+	return getLiveComment(t, bugID, id)
+}
+
+func (t *sqliteTx) EditComment(bugID, id int, content string, at time.Time) (*models.Comment, error) {
+	return editComment(t, t, t, bugID, id, content, at)
+}
+
+func (t *sqliteTx) DeleteComment(bugID, id int, by string, at time.Time) (*models.Comment, error) {
+	return trashComment(t, t, t, bugID, id, by, at)
+}
+
+func (t *sqliteTx) RestoreComment(bugID, id int) (*models.Comment, error) {
+	return restoreComment(t, t, t, bugID, id)
+}
+
+func (t *sqliteTx) GetCommentRevisions(bugID, id int) ([]models.CommentRevision, error) {
+	return getCommentRevisions(t, bugID, id)
+}
+
+func (t *sqliteTx) getComment(bugID, id int) (*models.Comment, error) {
+	row := t.tx.QueryRow(`SELECT `+sqliteCommentColumns+` FROM comments WHERE bug_id = ? AND id = ?`, bugID, id)
+	comment, err := scanSQLiteComment(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load comment %d: %w", id, err)
+	}
+	return comment, nil
+}
+
+func (t *sqliteTx) putComment(c *models.Comment) error {
+	res, err := t.tx.Exec(`UPDATE comments SET content = ?, content_html = ?, author = ?, created_at = ?, updated_at = ?, deleted_at = ?, deleted_by = ?
+		WHERE bug_id = ? AND id = ?`,
+		c.Content, c.ContentHTML, c.Author, formatSortableTime(c.CreatedAt), sqliteNullTime(c.UpdatedAt), sqliteNullTime(c.DeletedAt), c.DeletedBy,
+		c.BugID, c.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update comment %d: %w", c.ID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+func (t *sqliteTx) putCommentRevision(rev *models.CommentRevision) error {
+	_, err := t.tx.Exec(`INSERT INTO comment_revisions (comment_id, revision, content, created_at) VALUES (?, ?, ?, ?)`,
+		rev.CommentID, rev.Revision, rev.Content, formatSortableTime(rev.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to insert revision of comment %d: %w", rev.CommentID, err)
+	}
+	return nil
+}
+
+func (t *sqliteTx) commentRevisions(commentID int) ([]models.CommentRevision, error) {
+	rows, err := t.tx.Query(`SELECT comment_id, revision, content, created_at FROM comment_revisions
+		WHERE comment_id = ? ORDER BY revision`, commentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revs []models.CommentRevision
+	for rows.Next() {
+		var rev models.CommentRevision
+		var createdAt string
+		if err := rows.Scan(&rev.CommentID, &rev.Revision, &rev.Content, &createdAt); err != nil {
+			return nil, err
+		}
+		if rev.CreatedAt, err = parseSortableTime(createdAt); err != nil {
+			return nil, err
+		}
+		revs = append(revs, rev)
+	}
+	return revs, rows.Err()
+}
+
+func (t *sqliteTx) deleteCommentRevisions(commentID int) error {
+	if _, err := t.tx.Exec(`DELETE FROM comment_revisions WHERE comment_id = ?`, commentID); err != nil {
+		return fmt.Errorf("failed to delete revisions of comment %d: %w", commentID, err)
+	}
+	return nil
+}
+
+func (t *sqliteTx) AddHistory(entry *models.HistoryEntry) error {
+	return addHistory(t, t, t, entry)
+}
+
+func (t *sqliteTx) GetHistory(bugID int) ([]models.HistoryEntry, error) {
+	return getHistory(t, t, bugID)
+}
+
+func (t *sqliteTx) putHistory(entry *models.HistoryEntry) error {
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history changes: %w", err)
+	}
+	_, err = t.tx.Exec(`INSERT INTO bug_history (id, bug_id, action, actor, at, comment_id, changes) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		entry.ID, entry.BugID, entry.Action, entry.Actor, formatSortableTime(entry.At), entry.CommentID, string(changes))
+	if err != nil {
+		return fmt.Errorf("failed to insert history entry: %w", err)
+	}
+	return nil
+}
+
+func (t *sqliteTx) bugHistory(bugID int) ([]models.HistoryEntry, error) {
+	rows, err := t.tx.Query(`SELECT id, bug_id, action, actor, at, comment_id, changes FROM bug_history
+		WHERE bug_id = ? ORDER BY id`, bugID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.HistoryEntry
+	for rows.Next() {
+		var entry models.HistoryEntry
+		var at, changes string
+		if err := rows.Scan(&entry.ID, &entry.BugID, &entry.Action, &entry.Actor, &at, &entry.CommentID, &changes); err != nil {
+			return nil, err
+		}
+		if entry.At, err = parseSortableTime(at); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(changes), &entry.Changes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal history entry %d: %w", entry.ID, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (t *sqliteTx) deleteBugHistory(bugID int) error {
+	if _, err := t.tx.Exec(`DELETE FROM bug_history WHERE bug_id = ?`, bugID); err != nil {
+		return fmt.Errorf("failed to delete history: %w", err)
+	}
+	return nil
+}
+
+func (t *sqliteTx) CreateUser(user *models.User) error {
+	return createUser(t, t, user)
+}
+
+const sqliteUserColumns = `id, username, display_name, password_hash, created_at, role`
+
+func (t *sqliteTx) queryUser(query string, args ...interface{}) (*models.User, error) {
+	user, err := scanSQLiteUser(t.tx.QueryRow(query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	return user, nil
+}
+
+func scanSQLiteUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	var createdAt string
+	if err := row.Scan(&user.ID, &user.Username, &user.DisplayName, &user.PasswordHash, &createdAt, &user.Role); err != nil {
+		return nil, err
+	}
+	var err error
+	if user.CreatedAt, err = parseSortableTime(createdAt); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (t *sqliteTx) GetUser(id int) (*models.User, error) {
+	return t.queryUser(`SELECT `+sqliteUserColumns+` FROM users WHERE id = ?`, id)
+}
+
+func (t *sqliteTx) GetUserByName(username string) (*models.User, error) {
+	return t.queryUser(`SELECT `+sqliteUserColumns+` FROM users WHERE username = ?`, models.NormalizeUsername(username))
+}
+
+func (t *sqliteTx) ListUsers() ([]models.User, error) {
+	rows, err := t.tx.Query(`SELECT ` + sqliteUserColumns + ` FROM users ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		user, err := scanSQLiteUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+func (t *sqliteTx) UpdateUser(user *models.User) error {
+	return updateUser(t, t, user)
+}
+
+func (t *sqliteTx) putUser(user *models.User) error {
+	_, err := t.tx.Exec(`INSERT OR REPLACE INTO users (`+sqliteUserColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		user.ID, user.Username, user.DisplayName, user.PasswordHash, formatSortableTime(user.CreatedAt), user.Role)
+	if err != nil {
+		return fmt.Errorf("failed to store user: %w", err)
+	}
+	return nil
+}
+
+func (t *sqliteTx) CreateProject(project *models.Project) error {
+	return createProject(t, t, project)
+}
+
+const sqliteProjectColumns = `id, key, name, description, created_at`
+
+func scanSQLiteProject(row rowScanner) (*models.Project, error) {
+	var project models.Project
+	var createdAt string
+	if err := row.Scan(&project.ID, &project.Key, &project.Name, &project.Description, &createdAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if project.CreatedAt, err = parseSortableTime(createdAt); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (t *sqliteTx) GetProject(key string) (*models.Project, error) {
+	row := t.tx.QueryRow(`SELECT `+sqliteProjectColumns+` FROM projects WHERE key = ?`, models.NormalizeProjectKey(key))
+	project, err := scanSQLiteProject(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load project: %w", err)
+	}
+	return project, nil
+}
+
+func (t *sqliteTx) ListProjects() ([]models.Project, error) {
+	rows, err := t.tx.Query(`SELECT ` + sqliteProjectColumns + ` FROM projects ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []models.Project
+	for rows.Next() {
+		project, err := scanSQLiteProject(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		projects = append(projects, *project)
+	}
+	return projects, rows.Err()
+}
+
+func (t *sqliteTx) putProject(project *models.Project) error {
+	_, err := t.tx.Exec(`INSERT INTO projects (`+sqliteProjectColumns+`) VALUES (?, ?, ?, ?, ?)`,
+		project.ID, project.Key, project.Name, project.Description, formatSortableTime(project.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to insert project: %w", err)
+	}
+	return nil
+}
+
+func (t *sqliteTx) GetBugID(project string, number int) (int, error) {
+	var id int
+	err := t.tx.QueryRow(`SELECT id FROM bugs WHERE project = ? AND number = ?`,
+		models.NormalizeProjectKey(project), number).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, ErrBugNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up bug: %w", err)
+	}
+	return id, nil
+}
+
+func (t *sqliteTx) CreateIdentity(identity *models.Identity) error {
+	_, err := t.tx.Exec(`INSERT OR REPLACE INTO identities (issuer, subject, user_id, created_at) VALUES (?, ?, ?, ?)`,
+		identity.Issuer, identity.Subject, identity.UserID, formatSortableTime(identity.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to insert identity: %w", err)
+	}
+	return nil
+}
+
+func (t *sqliteTx) GetIdentity(issuer, subject string) (*models.Identity, error) {
+	identity := models.Identity{Issuer: issuer, Subject: subject}
+	var createdAt string
+	err := t.tx.QueryRow(`SELECT user_id, created_at FROM identities WHERE issuer = ? AND subject = ?`, issuer, subject).
+		Scan(&identity.UserID, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrIdentityNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load identity: %w", err)
+	}
+	if identity.CreatedAt, err = parseSortableTime(createdAt); err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (t *sqliteTx) CreateSession(session *models.Session) error {
+	_, err := t.tx.Exec(`INSERT INTO sessions (token_hash, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)`,
+		session.TokenHash, session.UserID, formatSortableTime(session.CreatedAt), formatSortableTime(session.ExpiresAt))
+	if err != nil {
+		return fmt.Errorf("failed to insert session: %w", err)
+	}
+	return nil
+}
+
+func (t *sqliteTx) GetSession(tokenHash string) (*models.Session, error) {
+	session := models.Session{TokenHash: tokenHash}
+	var createdAt, expiresAt string
+	err := t.tx.QueryRow(`SELECT user_id, created_at, expires_at FROM sessions WHERE token_hash = ?`, tokenHash).
+		Scan(&session.UserID, &createdAt, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	if session.CreatedAt, err = parseSortableTime(createdAt); err != nil {
+		return nil, err
+	}
+	if session.ExpiresAt, err = parseSortableTime(expiresAt); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (t *sqliteTx) DeleteSession(tokenHash string) error {
+	if _, err := t.tx.Exec(`DELETE FROM sessions WHERE token_hash = ?`, tokenHash); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+func (t *sqliteTx) DeleteExpiredSessions(now time.Time) (int, error) {
+	res, err := t.tx.Exec(`DELETE FROM sessions WHERE expires_at <= ?`, formatSortableTime(now))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (t *sqliteTx) CreateAPIToken(token *models.APIToken) error {
+	return createAPIToken(t, t, token)
+}
+
+const sqliteAPITokenColumns = `id, user_id, name, scope, token_hash, created_at, expires_at, last_used_at`
+
+func scanSQLiteAPIToken(row rowScanner) (*models.APIToken, error) {
+	var token models.APIToken
+	var createdAt string
+	var expiresAt, lastUsedAt sql.NullString
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.Scope, &token.TokenHash,
+		&createdAt, &expiresAt, &lastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+	if token.CreatedAt, err = parseSortableTime(createdAt); err != nil {
+		return nil, err
+	}
+	if token.ExpiresAt, err = parseSQLiteNullTime(expiresAt); err != nil {
+		return nil, err
+	}
+	if token.LastUsedAt, err = parseSQLiteNullTime(lastUsedAt); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (t *sqliteTx) GetAPIToken(tokenHash string) (*models.APIToken, error) {
+	row := t.tx.QueryRow(`SELECT `+sqliteAPITokenColumns+` FROM api_tokens WHERE token_hash = ?`, tokenHash)
+	token, err := scanSQLiteAPIToken(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API token: %w", err)
+	}
+	return token, nil
+}
+
+func (t *sqliteTx) ListAPITokens(userID int) ([]models.APIToken, error) {
+	rows, err := t.tx.Query(`SELECT `+sqliteAPITokenColumns+` FROM api_tokens WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []models.APIToken
+	for rows.Next() {
+		token, err := scanSQLiteAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API token: %w", err)
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+func (t *sqliteTx) DeleteAPIToken(userID, id int) error {
+	res, err := t.tx.Exec(`DELETE FROM api_tokens WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete API token: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+func (t *sqliteTx) TouchAPIToken(id int, at time.Time) error {
+	res, err := t.tx.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, formatSortableTime(at), id)
+	if err != nil {
+		return fmt.Errorf("failed to update API token: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+func (t *sqliteTx) putAPIToken(token *models.APIToken) error {
+	_, err := t.tx.Exec(`INSERT INTO api_tokens (`+sqliteAPITokenColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		token.ID, token.UserID, token.Name, token.Scope, token.TokenHash,
+		formatSortableTime(token.CreatedAt), sqliteNullTime(token.ExpiresAt), sqliteNullTime(token.LastUsedAt))
+	if err != nil {
+		return fmt.Errorf("failed to insert API token: %w", err)
+	}
+	return nil
+}
+
+func (t *sqliteTx) AppendAudit(entry *models.AuditEntry) error {
+	return appendAudit(t, entry)
+}
+
+func (t *sqliteTx) ListAudit(q AuditQuery) ([]models.AuditEntry, error) {
+	return listAudit(t, q)
+}
+
+const sqliteAuditColumns = `seq, at, actor, action, method, path, status, remote_addr, prev_hash, hash`
+
+func scanSQLiteAudit(row rowScanner) (*models.AuditEntry, error) {
+	var e models.AuditEntry
+	var at string
+	if err := row.Scan(&e.Seq, &at, &e.Actor, &e.Action, &e.Method, &e.Path, &e.Status, &e.RemoteAddr, &e.PrevHash, &e.Hash); err != nil {
+		return nil, err
+	}
+	t, err := parseSortableTime(at)
+	if err != nil {
+		return nil, err
+	}
+	e.At = t.UTC()
+	return &e, nil
+}
+
+func (t *sqliteTx) lastAudit() (*models.AuditEntry, error) {
+	e, err := scanSQLiteAudit(t.tx.QueryRow(`SELECT ` + sqliteAuditColumns + ` FROM audit_log ORDER BY seq DESC LIMIT 1`))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return e, err
+}
+
+func (t *sqliteTx) putAudit(e *models.AuditEntry) error {
+	_, err := t.tx.Exec(`INSERT INTO audit_log (`+sqliteAuditColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Seq, formatSortableTime(e.At), e.Actor, e.Action, e.Method, e.Path, e.Status, e.RemoteAddr, e.PrevHash, e.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to append audit entry: %w", err)
+	}
+	return nil
+}
+
+func (t *sqliteTx) scanAudit(afterSeq int, fn func(entry models.AuditEntry) bool) error {
+	rows, err := t.tx.Query(`SELECT `+sqliteAuditColumns+` FROM audit_log WHERE seq > ? ORDER BY seq`, afterSeq)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanSQLiteAudit(rows)
+		if err != nil {
+			return err
+		}
+		if !fn(*e) {
+			break
+		}
+	}
+	return rows.Err()
+}
+
+func (t *sqliteTx) NextID(counter string) (int, error) {
+	var nextID int
+	err := t.tx.QueryRow(`INSERT INTO counters (name, value) VALUES (?, 1)
+		ON CONFLICT (name) DO UPDATE SET value = value + 1
+		RETURNING value`, counter).Scan(&nextID)
+	if err != nil {
+		return 0, err
+	}
+	return nextID, nil
+}
+
+func (t *sqliteTx) SearchBugs(query string, limit int) ([]SearchHit, error) {
+	return searchBugs(t, t, query, limit)
+}
+
+func (t *sqliteTx) RebuildSearchIndex() (int, error) {
+	return rebuildSearchIndex(t, t)
+}
+
+func (t *sqliteTx) putSearchDoc(doc *search.Document) error {
+	if err := t.deleteSearchDoc(doc.ID); err != nil {
+		return err
+	}
+	if _, err := t.tx.Exec(`INSERT INTO search_docs (bug_id, length) VALUES (?, ?)`, doc.ID, doc.Length); err != nil {
+		return fmt.Errorf("failed to index bug %d: %w", doc.ID, err)
+	}
+	for term, tf := range doc.Terms {
+		if _, err := t.tx.Exec(`INSERT INTO search_postings (term, bug_id, tf) VALUES (?, ?, ?)`, term, doc.ID, tf); err != nil {
+			return fmt.Errorf("failed to index bug %d: %w", doc.ID, err)
+		}
+	}
+	return nil
+}
+
+func (t *sqliteTx) deleteSearchDoc(id int) error {
+	if _, err := t.tx.Exec(`DELETE FROM search_postings WHERE bug_id = ?`, id); err != nil {
+		return err
+	}
+	_, err := t.tx.Exec(`DELETE FROM search_docs WHERE bug_id = ?`, id)
+	return err
+}
+
+func (t *sqliteTx) clearSearchIndex() error {
+	if _, err := t.tx.Exec(`DELETE FROM search_postings`); err != nil {
+		return err
+	}
+	_, err := t.tx.Exec(`DELETE FROM search_docs`)
+	return err
+}
+
+func (t *sqliteTx) searchPostings(term string) (map[int]int, error) {
+	rows, err := t.tx.Query(`SELECT bug_id, tf FROM search_postings WHERE term = ?`, term)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	postings := make(map[int]int)
+	for rows.Next() {
+		var id, tf int
+		if err := rows.Scan(&id, &tf); err != nil {
+			return nil, err
+		}
+		postings[id] = tf
+	}
+	return postings, rows.Err()
+}
+
+func (t *sqliteTx) searchDocLength(id int) (int, error) {
+	var length int
+	err := t.tx.QueryRow(`SELECT length FROM search_docs WHERE bug_id = ?`, id).Scan(&length)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return length, err
+}
+
+func (t *sqliteTx) searchStats() (search.Stats, error) {
+	var stats search.Stats
+	err := t.tx.QueryRow(`SELECT COUNT(*), COALESCE(SUM(length), 0) FROM search_docs`).Scan(&stats.Docs, &stats.TotalLength)
+	return stats, err
+}
+
+func sqlitePlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"database/sql"
+	"testing"
+
+	"bugtracker-backend/internal/config"
+	"bugtracker-backend/internal/models"
+	"bugtracker-backend/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteMigratesOldSchema(t *testing.T) {
+	defer testutil.CleanupTestDB()
+	path := testutil.GetTestDBPath()
+
+	// Simulate a database written by the first schema version.
+	raw, err := sql.Open("sqlite", "file:"+path)
+	assert.NoError(t, err)
+	_, err = raw.Exec(sqliteMigrations[0].sql)
+	assert.NoError(t, err)
+	_, err = raw.Exec(`INSERT INTO bugs (id, title, description, status, created_at, updated_at) VALUES (1, 'Crash on save', 'Fails on **save**', 'Closed', ?, ?);
+		INSERT INTO comments (id, bug_id, content, author, created_at) VALUES (7, 1, 'Seen it _too_', 'alice', ?);
+		INSERT INTO counters (name, value) VALUES ('lastBugID', 1);
+		PRAGMA user_version = 1;`,
+		"2025-01-01T12:00:00.000000000Z", "2025-01-01T12:00:00.000000000Z", "2025-01-01T12:05:00.000000000Z")
+	assert.NoError(t, err)
+	raw.Close()
+
+	store, err := OpenSQLite(path)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	bug, err := store.GetBug(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Closed", bug.Status)
+	assert.Empty(t, bug.Resolution)
+	assert.Nil(t, bug.ResolvedAt)
+	assert.Equal(t, 1, bug.Version)
+	assert.Equal(t, "<p>Fails on <strong>save</strong></p>\n", bug.DescriptionHTML)
+	assert.Equal(t, "BUG-1", bug.Key)
+
+	comments, err := store.GetComments(1)
+	assert.NoError(t, err)
+	if assert.Len(t, comments, 1) {
+		assert.Equal(t, "<p>Seen it <em>too</em></p>\n", comments[0].ContentHTML)
+	}
+
+	hits, err := store.SearchBugs("crash", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, searchIDs(hits))
+
+	// Numbering in the default project continues after the old bugs.
+	bug = &models.Bug{Title: "Hang"}
+	assert.NoError(t, store.CreateBug(bug))
+	assert.Equal(t, "BUG-2", bug.Key)
+}
+
+func TestSQLiteAuditLogIsAppendOnly(t *testing.T) {
+	store, cleanup := SetupTestStore(t, config.BackendSQLite)
+	defer cleanup()
+
+	assert.NoError(t, store.AppendAudit(&models.AuditEntry{Action: "POST /bugs", Method: "POST", Path: "/api/bugs", Status: 201}))
+
+	for _, stmt := range []string{
+		`UPDATE audit_log SET actor = 'mallory'`,
+		`DELETE FROM audit_log`,
+	} {
+		err := store.Update(func(tx Tx) error {
+			_, err := tx.(*sqliteTx).tx.Exec(stmt)
+			return err
+		})
+		if assert.Error(t, err, stmt) {
+			assert.Contains(t, err.Error(), "append-only")
+		}
+	}
+}
+
+func TestSQLiteRenumbersLegacyComments(t *testing.T) {
+	defer testutil.CleanupTestDB()
+	path := testutil.GetTestDBPath()
+
+	// Simulate a database whose comments have random IDs.
+	raw, err := sql.Open("sqlite", "file:"+path)
+	assert.NoError(t, err)
+	for _, m := range sqliteMigrations[:7] {
+		_, err = raw.Exec(m.sql)
+		assert.NoError(t, err)
+	}
+	_, err = raw.Exec(`INSERT INTO bugs (id, title, status, created_at, updated_at) VALUES (1, 'Crash', 'Open', ?, ?);
+		INSERT INTO counters (name, value) VALUES ('lastBugID', 1);
+		INSERT INTO comments (id, bug_id, content, author, created_at) VALUES
+			(3911, 1, 'second', 'a', '2025-01-01T12:05:00.000000000Z'),
+			(27, 1, 'first', 'a', '2025-01-01T12:01:00.000000000Z');
+		INSERT INTO bug_history (id, bug_id, action, at, comment_id) VALUES (1, 1, 'commented', '2025-01-01T12:05:00.000000000Z', 3911);
+		PRAGMA user_version = 7;`,
+		"2025-01-01T12:00:00.000000000Z", "2025-01-01T12:00:00.000000000Z")
+	assert.NoError(t, err)
+	raw.Close()
+
+	store, err := OpenSQLite(path)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	comments, err := store.GetComments(1)
+	assert.NoError(t, err)
+	if assert.Len(t, comments, 2) {
+		assert.Equal(t, 1, comments[0].ID)
+		assert.Equal(t, "first", comments[0].Content)
+		assert.Equal(t, 2, comments[1].ID)
+	}
+	history, err := store.GetHistory(1)
+	assert.NoError(t, err)
+	if assert.Len(t, history, 1) {
+		assert.Equal(t, 2, history[0].CommentID)
+	}
+
+	comment := &models.Comment{Author: "a", Content: "third"}
+	assert.NoError(t, store.CreateComment(1, comment))
+	assert.Equal(t, 3, comment.ID)
+}
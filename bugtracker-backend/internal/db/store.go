@@ -0,0 +1,208 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"bugtracker-backend/internal/config"
+	"bugtracker-backend/internal/models"
+)
+
+var (
+	ErrNotInitialized = errors.New("database not initialized")
+	ErrBugNotFound    = errors.New("bug not found")
+	// ErrVersionConflict is returned by UpdateBug when the bug was changed
+	// after the caller read it.
+	ErrVersionConflict = errors.New("bug was modified concurrently")
+	ErrCommentNotFound = errors.New("comment not found")
+	// ErrCommentDeleted is returned when editing a comment that has been
+	// deleted.
+	ErrCommentDeleted = errors.New("comment is deleted")
+	// ErrCommentPurged is returned when restoring a deleted comment whose
+	// content has been purged from the trash.
+	ErrCommentPurged = errors.New("comment has been purged from the trash")
+	// ErrInvalidParent is returned when a reply names a parent comment that
+	// is not on the same bug.
+	ErrInvalidParent = errors.New("parent comment not found on this bug")
+	ErrUserNotFound  = errors.New("user not found")
+	// ErrUserExists is returned when creating a user whose username is
+	// taken.
+	ErrUserExists      = errors.New("username is already taken")
+	ErrSessionNotFound = errors.New("session not found")
+	ErrTokenNotFound   = errors.New("API token not found")
+	// ErrIdentityNotFound is returned for a provider identity that is not
+	// linked to a user.
+	ErrIdentityNotFound = errors.New("identity not linked to a user")
+	ErrProjectNotFound  = errors.New("project not found")
+	// ErrProjectExists is returned when creating a project whose key is
+	// taken.
+	ErrProjectExists = errors.New("project key is already taken")
+)
+
+// Store is the persistence layer used by the HTTP handlers. Each Tx method
+// called directly on a Store runs in its own transaction; use View or Update
+// to group several operations atomically.
+type Store interface {
+	Tx
+	View(fn func(tx Tx) error) error
+	Update(fn func(tx Tx) error) error
+	Close() error
+}
+
+// Tx is the set of operations available inside a single transaction.
+type Tx interface {
+	// CreateBug assigns the bug its ID and its number in bug.Project, or in
+	// the default project if that is empty. The project must exist.
+	CreateBug(bug *models.Bug) error
+	// GetBug and GetAllBugs only see live bugs; a bug in the trash is
+	// reported as ErrBugNotFound.
+	GetBug(id int) (*models.Bug, error)
+	// GetBugID returns the ID of a project's bug, live or trashed, or
+	// ErrBugNotFound.
+	GetBugID(project string, number int) (int, error)
+	GetAllBugs() ([]*models.Bug, error)
+	// ListBugs returns one page of bugs matching q together with the cursor
+	// of the next page, which is empty on the last page.
+	ListBugs(q BugQuery) ([]*models.Bug, string, error)
+	// UpdateBug saves bug if its Version matches the stored one, returning
+	// ErrVersionConflict otherwise, and increments Version.
+	UpdateBug(bug *models.Bug) error
+	// DeleteBug permanently removes a bug, live or trashed, together with
+	// its comments. IDs and numbers are never reused.
+	DeleteBug(id int) error
+
+	// TrashBug moves a live bug to the trash. Its comments stay with it.
+	TrashBug(id int, by string, at time.Time) error
+	// RestoreBug brings a bug back from the trash, returning
+	// ErrBugNotFound if it is not there.
+	RestoreBug(id int) (*models.Bug, error)
+	// PurgeTrash permanently deletes the bugs trashed before the given time,
+	// and the content of comments deleted before then, and returns how many
+	// bugs and comments there were.
+	PurgeTrash(before time.Time) (int, error)
+
+	// CreateComment adds a comment, or a reply if comment.ParentID is set.
+	// The parent must be a comment on the same bug that is not deleted.
+	CreateComment(bugID int, comment *models.Comment) error
+	GetComments(bugID int) ([]models.Comment, error)
+	// ListComments returns a page of a bug's comments, oldest first unless
+	// q.Descending, and the cursor for the next page if there is one.
+	ListComments(bugID int, q CommentQuery) ([]models.Comment, string, error)
+	// GetComment returns one of a live bug's comments, or
+	// ErrCommentNotFound.
+	GetComment(bugID, id int) (*models.Comment, error)
+	// EditComment replaces a comment's content, keeping the previous content
+	// as a revision. Editing a deleted comment returns ErrCommentDeleted.
+	EditComment(bugID, id int, content string, at time.Time) (*models.Comment, error)
+	// DeleteComment turns a comment into a tombstone. Its content and
+	// revisions are kept until PurgeTrash, so it can be restored.
+	DeleteComment(bugID, id int, by string, at time.Time) (*models.Comment, error)
+	// RestoreComment brings back a deleted comment, returning
+	// ErrCommentPurged if its content is gone. Restoring a comment that is
+	// not deleted changes nothing.
+	RestoreComment(bugID, id int) (*models.Comment, error)
+	// GetCommentRevisions lists a comment's earlier contents, oldest first.
+	GetCommentRevisions(bugID, id int) ([]models.CommentRevision, error)
+	// OrphanedComments lists comments whose bug was deleted, or whose bug
+	// ID was reused by a later bug, before deletes cascaded to comments.
+	OrphanedComments() ([]models.Comment, error)
+	// PurgeOrphanedComments deletes the comments listed by
+	// OrphanedComments and returns how many there were.
+	PurgeOrphanedComments() (int, error)
+
+	// AddHistory appends an entry to a bug's history, assigning its ID.
+	// History is kept while the bug is in the trash and deleted with it.
+	AddHistory(entry *models.HistoryEntry) error
+	// GetHistory returns a live bug's history, oldest first.
+	GetHistory(bugID int) ([]models.HistoryEntry, error)
+
+	// AppendAudit adds an entry to the end of the append-only audit log,
+	// filling in its sequence number and hashes.
+	AppendAudit(entry *models.AuditEntry) error
+	// ListAudit returns the audit entries matching q in sequence order.
+	ListAudit(q AuditQuery) ([]models.AuditEntry, error)
+
+	// SearchBugs runs a ranked full-text search over bug titles,
+	// descriptions and comments, returning at most limit hits (all when
+	// limit is zero).
+	SearchBugs(query string, limit int) ([]SearchHit, error)
+	// RebuildSearchIndex reindexes every bug and returns how many there are.
+	RebuildSearchIndex() (int, error)
+
+	// CreateUser adds a user with a lower-cased username, assigning its ID,
+	// or returns ErrUserExists if the username is taken.
+	CreateUser(user *models.User) error
+	// GetUser and GetUserByName return ErrUserNotFound for unknown users.
+	GetUser(id int) (*models.User, error)
+	GetUserByName(username string) (*models.User, error)
+	// ListUsers returns every user ordered by ID.
+	ListUsers() ([]models.User, error)
+	// UpdateUser replaces a stored user other than its username, or returns
+	// ErrUserNotFound.
+	UpdateUser(user *models.User) error
+
+	// CreateIdentity links a provider identity to a user.
+	CreateIdentity(identity *models.Identity) error
+	// GetIdentity returns the link for a provider identity, or
+	// ErrIdentityNotFound.
+	GetIdentity(issuer, subject string) (*models.Identity, error)
+
+	// CreateSession stores a session under its token hash.
+	CreateSession(session *models.Session) error
+	// GetSession returns the session with the given token hash, whether or
+	// not it has expired, or ErrSessionNotFound.
+	GetSession(tokenHash string) (*models.Session, error)
+	DeleteSession(tokenHash string) error
+	// DeleteExpiredSessions removes the sessions that have expired at now
+	// and returns how many there were.
+	DeleteExpiredSessions(now time.Time) (int, error)
+
+	// CreateAPIToken stores a token, assigning its ID and creation time.
+	CreateAPIToken(token *models.APIToken) error
+	// GetAPIToken returns the token with the given hash, whether or not it
+	// has expired, or ErrTokenNotFound.
+	GetAPIToken(tokenHash string) (*models.APIToken, error)
+	// ListAPITokens returns a user's tokens ordered by ID.
+	ListAPITokens(userID int) ([]models.APIToken, error)
+	// DeleteAPIToken revokes one of a user's tokens, returning
+	// ErrTokenNotFound if the user has no token with that ID.
+	DeleteAPIToken(userID, id int) error
+	// TouchAPIToken records that a token was used at the given time.
+	TouchAPIToken(id int, at time.Time) error
+
+	// CreateProject adds a project with an upper-cased key, assigning its
+	// ID, or returns ErrProjectExists if the key is taken.
+	CreateProject(project *models.Project) error
+	// GetProject returns ErrProjectNotFound for unknown keys.
+	GetProject(key string) (*models.Project, error)
+	// ListProjects returns every project ordered by ID.
+	ListProjects() ([]models.Project, error)
+
+	// NextID increments the named counter and returns its new value.
+	NextID(counter string) (int, error)
+}
+
+// Open returns the Store implementation for the named backend. The path is
+// ignored by the memory backend.
+func Open(backend, path string) (Store, error) {
+	switch backend {
+	case config.BackendBolt:
+		return OpenBolt(path)
+	case config.BackendSQLite:
+		return OpenSQLite(path)
+	case config.BackendMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
+
+// checkBugVersion fails with ErrVersionConflict unless bug is an update of
+// the stored version.
+func checkBugVersion(stored, bug *models.Bug) error {
+	if bug.Version != stored.Version {
+		return fmt.Errorf("%w: bug %d is at version %d, not %d", ErrVersionConflict, bug.ID, stored.Version, bug.Version)
+	}
+	return nil
+}
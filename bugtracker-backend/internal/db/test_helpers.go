@@ -4,32 +4,37 @@ import (
 	"os"
 	"testing"
 
-	"go.etcd.io/bbolt"
+	"bugtracker-backend/internal/config"
+	"bugtracker-backend/internal/testutil"
 )
 
-func SetupTestDB(t *testing.T) func() {
-	tmpFile := "test.db"
-	os.Remove(tmpFile)
-	
-	databasePath = tmpFile
-	
-	if err := Init(); err != nil {
+// TestBackends lists the storage backends the shared store tests run against.
+var TestBackends = []string{config.BackendBolt, config.BackendSQLite, config.BackendMemory}
+
+// SetupTestDB opens a fresh bbolt store in a temporary file and returns it
+// along with a function that closes it and removes the file.
+func SetupTestDB(t *testing.T) (Store, func()) {
+	return SetupTestStore(t, config.BackendBolt)
+}
+
+// SetupTestStore is SetupTestDB for an arbitrary backend.
+func SetupTestStore(t *testing.T, backend string) (Store, func()) {
+	path := testutil.GetTestDBPath()
+	store, err := Open(backend, path)
+	if err != nil {
 		t.Fatalf("Failed to initialize test database: %v", err)
 	}
 
-	err := db.Update(func(tx *bbolt.Tx) error {
-		b, err := tx.CreateBucketIfNotExists(counterBucket)
-		if err != nil {
-			return err
-		}
-		return b.Put([]byte("bug_id"), itob(0))
-	})
-	if err != nil {
-		t.Fatalf("Failed to initialize counter: %v", err)
+	return store, func() {
+		store.Close()
+		removeDBFiles(path)
 	}
+}
 
-	return func() {
-		Cleanup()
-		os.Remove(tmpFile)
+// removeDBFiles deletes a database file along with any SQLite journal files
+// created next to it.
+func removeDBFiles(path string) {
+	for _, suffix := range []string{"", "-wal", "-shm", "-journal"} {
+		os.Remove(path + suffix)
 	}
-} 
\ No newline at end of file
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"time"
+
+	"bugtracker-backend/internal/models"
+)
+
+// bugLoader is implemented by every backend's transaction type to read a
+// bug whether or not it is in the trash, which Tx.GetBug hides.
+type bugLoader interface {
+	loadBug(id int) (*models.Bug, error)
+}
+
+func trashBug(tx Tx, id int, by string, at time.Time) error {
+	bug, err := tx.GetBug(id)
+	if err != nil {
+		return err
+	}
+	bug.DeletedAt = &at
+	bug.DeletedBy = by
+	return tx.UpdateBug(bug)
+}
+
+func restoreBug(tx Tx, cs commentStore, id int) (*models.Bug, error) {
+	bug, err := cs.loadBug(id)
+	if err != nil {
+		return nil, err
+	}
+	if bug.DeletedAt == nil {
+		return nil, ErrBugNotFound
+	}
+	bug.DeletedAt = nil
+	bug.DeletedBy = ""
+	if err := tx.UpdateBug(bug); err != nil {
+		return nil, err
+	}
+	return bug, fillCommentCounts(cs, bug)
+}
+
+func purgeTrash(tx Tx, cs commentStore, before time.Time) (int, error) {
+	bugs, _, err := tx.ListBugs(BugQuery{Trashed: true})
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, bug := range bugs {
+		if !bug.DeletedAt.Before(before) {
+			continue
+		}
+		if err := tx.DeleteBug(bug.ID); err != nil {
+			return count, err
+		}
+		count++
+	}
+	comments, err := purgeDeletedComments(cs, before)
+	return count + comments, err
+}
+
+// syncBugSearchDoc indexes a live bug and drops a trashed one from the
+// search index.
+func syncBugSearchDoc(tx Tx, idx searchIndex, bug *models.Bug) error {
+	if bug.DeletedAt != nil {
+		return idx.deleteSearchDoc(bug.ID)
+	}
+	return indexBugForSearch(tx, idx, bug.ID)
+}
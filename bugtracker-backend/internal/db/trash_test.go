@@ -0,0 +1,133 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"bugtracker-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrashAndRestore(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		bug := &models.Bug{Title: "Flaky upload", Status: "Open"}
+		assert.NoError(t, store.CreateBug(bug))
+		assert.NoError(t, store.CreateComment(bug.ID, &models.Comment{Author: "a", Content: "Seen again"}))
+		assert.NoError(t, store.CreateBug(&models.Bug{Title: "Other", Status: "Open"}))
+
+		at := time.Now().Add(-time.Minute)
+		assert.NoError(t, store.TrashBug(bug.ID, "alice", at))
+		assert.ErrorIs(t, store.TrashBug(bug.ID, "alice", at), ErrBugNotFound)
+
+		// Trashed bugs are hidden from everything but the trash.
+		_, err := store.GetBug(bug.ID)
+		assert.ErrorIs(t, err, ErrBugNotFound)
+		_, err = store.GetComments(bug.ID)
+		assert.ErrorIs(t, err, ErrBugNotFound)
+		all, err := store.GetAllBugs()
+		assert.NoError(t, err)
+		assert.Len(t, all, 1)
+		bugs, _, err := store.ListBugs(BugQuery{})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2}, bugIDs(bugs))
+		hits, err := store.SearchBugs("upload", 0)
+		assert.NoError(t, err)
+		assert.Empty(t, hits)
+		orphans, err := store.OrphanedComments()
+		assert.NoError(t, err)
+		assert.Empty(t, orphans)
+
+		trash, _, err := store.ListBugs(BugQuery{Trashed: true})
+		assert.NoError(t, err)
+		if assert.Len(t, trash, 1) {
+			assert.Equal(t, "alice", trash[0].DeletedBy)
+			assert.WithinDuration(t, at, *trash[0].DeletedAt, time.Millisecond)
+		}
+
+		restored, err := store.RestoreBug(bug.ID)
+		assert.NoError(t, err)
+		assert.Nil(t, restored.DeletedAt)
+		assert.Empty(t, restored.DeletedBy)
+		_, err = store.RestoreBug(bug.ID)
+		assert.ErrorIs(t, err, ErrBugNotFound)
+		_, err = store.RestoreBug(999)
+		assert.ErrorIs(t, err, ErrBugNotFound)
+
+		comments, err := store.GetComments(bug.ID)
+		assert.NoError(t, err)
+		assert.Len(t, comments, 1)
+		hits, err = store.SearchBugs("seen", 0)
+		assert.NoError(t, err)
+		assert.Len(t, hits, 1)
+	})
+}
+
+func TestPurgeTrash(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		now := time.Now()
+		for _, age := range []time.Duration{0, 10 * 24 * time.Hour, 40 * 24 * time.Hour} {
+			bug := &models.Bug{Title: "Bug"}
+			assert.NoError(t, store.CreateBug(bug))
+			assert.NoError(t, store.CreateComment(bug.ID, &models.Comment{Author: "a", Content: "note"}))
+			if age > 0 {
+				assert.NoError(t, store.TrashBug(bug.ID, "", now.Add(-age)))
+			}
+		}
+
+		count, err := store.PurgeTrash(now.Add(-30 * 24 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		trash, _, err := store.ListBugs(BugQuery{Trashed: true})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2}, bugIDs(trash))
+		_, err = store.RestoreBug(3)
+		assert.ErrorIs(t, err, ErrBugNotFound)
+
+		// The purged bug's comments go with it.
+		orphans, err := store.OrphanedComments()
+		assert.NoError(t, err)
+		assert.Empty(t, orphans)
+	})
+}
+
+func TestPurgeTrashPurgesDeletedComments(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		now := time.Now()
+		bug := &models.Bug{Title: "Bug"}
+		assert.NoError(t, store.CreateBug(bug))
+		for _, age := range []time.Duration{0, 10 * 24 * time.Hour, 40 * 24 * time.Hour} {
+			comment := &models.Comment{Author: "a", Content: "note"}
+			assert.NoError(t, store.CreateComment(bug.ID, comment))
+			_, err := store.EditComment(bug.ID, comment.ID, "edited note", now)
+			assert.NoError(t, err)
+			if age > 0 {
+				_, err = store.DeleteComment(bug.ID, comment.ID, "a", now.Add(-age))
+				assert.NoError(t, err)
+			}
+		}
+
+		count, err := store.PurgeTrash(now.Add(-30 * 24 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		count, err = store.PurgeTrash(now.Add(-30 * 24 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+
+		// The purged comment stays a tombstone, but its content is gone.
+		comment, err := store.GetComment(bug.ID, 3)
+		assert.NoError(t, err)
+		assert.NotNil(t, comment.DeletedAt)
+		assert.Empty(t, comment.Content)
+		revs, err := store.GetCommentRevisions(bug.ID, 3)
+		assert.NoError(t, err)
+		assert.Empty(t, revs)
+		_, err = store.RestoreComment(bug.ID, 3)
+		assert.ErrorIs(t, err, ErrCommentPurged)
+
+		comment, err = store.RestoreComment(bug.ID, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, "edited note", comment.Content)
+	})
+}
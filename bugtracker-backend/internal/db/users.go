@@ -0,0 +1,49 @@
+package db
+
+import (
+	"time"
+
+	"bugtracker-backend/internal/models"
+)
+
+const userCounter = "lastUserID"
+
+// userStore is implemented by every backend's transaction type to store
+// user accounts.
+type userStore interface {
+	GetUserByName(username string) (*models.User, error)
+	putUser(user *models.User) error
+}
+
+// createUser numbers a new user and stores it unless its username is taken.
+// Users without a role get the default one.
+func createUser(tx Tx, us userStore, user *models.User) error {
+	user.Username = models.NormalizeUsername(user.Username)
+	if user.Role == "" {
+		user.Role = models.DefaultRole
+	}
+	if _, err := us.GetUserByName(user.Username); err == nil {
+		return ErrUserExists
+	} else if err != ErrUserNotFound {
+		return err
+	}
+
+	id, err := tx.NextID(userCounter)
+	if err != nil {
+		return err
+	}
+	user.ID = id
+	user.CreatedAt = time.Now()
+	return us.putUser(user)
+}
+
+// updateUser stores user over the existing user with its ID, keeping the
+// stored username.
+func updateUser(tx Tx, us userStore, user *models.User) error {
+	stored, err := tx.GetUser(user.ID)
+	if err != nil {
+		return err
+	}
+	user.Username = stored.Username
+	return us.putUser(user)
+}
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"bugtracker-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		alice := &models.User{Username: "Alice", DisplayName: "Alice A.", PasswordHash: "hash-a"}
+		assert.NoError(t, store.CreateUser(alice))
+		assert.Equal(t, 1, alice.ID)
+		assert.Equal(t, "alice", alice.Username)
+		assert.False(t, alice.CreatedAt.IsZero())
+
+		assert.ErrorIs(t, store.CreateUser(&models.User{Username: "ALICE", PasswordHash: "x"}), ErrUserExists)
+		bob := &models.User{Username: "bob", PasswordHash: "hash-b"}
+		assert.NoError(t, store.CreateUser(bob))
+		assert.Equal(t, 2, bob.ID)
+
+		got, err := store.GetUserByName(" Alice ")
+		assert.NoError(t, err)
+		assert.Equal(t, alice.ID, got.ID)
+		assert.Equal(t, "Alice A.", got.DisplayName)
+		assert.Equal(t, "hash-a", got.PasswordHash)
+
+		got, err = store.GetUser(bob.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "bob", got.Username)
+		assert.Equal(t, "hash-b", got.PasswordHash)
+
+		_, err = store.GetUser(99)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		_, err = store.GetUserByName("carol")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+
+		users, err := store.ListUsers()
+		assert.NoError(t, err)
+		if assert.Len(t, users, 2) {
+			assert.Equal(t, "alice", users[0].Username)
+			assert.Equal(t, "bob", users[1].Username)
+		}
+	})
+}
+
+func TestUpdateUser(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		alice := &models.User{Username: "alice", PasswordHash: "hash-a"}
+		assert.NoError(t, store.CreateUser(alice))
+		assert.Equal(t, models.DefaultRole, alice.Role)
+
+		alice.Role = models.RoleDeveloper
+		alice.Username = "mallory"
+		assert.NoError(t, store.UpdateUser(alice))
+		assert.Equal(t, "alice", alice.Username)
+
+		got, err := store.GetUserByName("alice")
+		assert.NoError(t, err)
+		assert.Equal(t, models.RoleDeveloper, got.Role)
+		assert.Equal(t, "hash-a", got.PasswordHash)
+		_, err = store.GetUserByName("mallory")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+
+		assert.ErrorIs(t, store.UpdateUser(&models.User{ID: 99, Role: models.RoleAdmin}), ErrUserNotFound)
+	})
+}
+
+func TestSessions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		now := time.Now().UTC().Truncate(time.Second)
+		current := &models.Session{TokenHash: "current", UserID: 1, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+		stale := &models.Session{TokenHash: "stale", UserID: 1, CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)}
+		assert.NoError(t, store.CreateSession(current))
+		assert.NoError(t, store.CreateSession(stale))
+
+		got, err := store.GetSession("current")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.UserID)
+		assert.True(t, got.ExpiresAt.Equal(current.ExpiresAt))
+		assert.False(t, got.Expired(now))
+
+		got, err = store.GetSession("stale")
+		assert.NoError(t, err)
+		assert.True(t, got.Expired(now))
+
+		count, err := store.DeleteExpiredSessions(now)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		_, err = store.GetSession("stale")
+		assert.ErrorIs(t, err, ErrSessionNotFound)
+
+		assert.NoError(t, store.DeleteSession("current"))
+		_, err = store.GetSession("current")
+		assert.ErrorIs(t, err, ErrSessionNotFound)
+	})
+}
+
+func TestAPITokens(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		expires := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+		ci := &models.APIToken{UserID: 1, Name: "ci", Scope: models.ScopeWrite, TokenHash: "hash-ci", ExpiresAt: &expires}
+		assert.NoError(t, store.CreateAPIToken(ci))
+		assert.Equal(t, 1, ci.ID)
+		assert.False(t, ci.CreatedAt.IsZero())
+		other := &models.APIToken{UserID: 2, Name: "script", Scope: models.ScopeRead, TokenHash: "hash-other"}
+		assert.NoError(t, store.CreateAPIToken(other))
+
+		got, err := store.GetAPIToken("hash-ci")
+		assert.NoError(t, err)
+		assert.Equal(t, "ci", got.Name)
+		assert.Equal(t, models.ScopeWrite, got.Scope)
+		assert.Equal(t, "hash-ci", got.TokenHash)
+		if assert.NotNil(t, got.ExpiresAt) {
+			assert.True(t, got.ExpiresAt.Equal(expires))
+		}
+		assert.Nil(t, got.LastUsedAt)
+		_, err = store.GetAPIToken("unknown")
+		assert.ErrorIs(t, err, ErrTokenNotFound)
+
+		used := time.Now().UTC().Truncate(time.Second)
+		assert.NoError(t, store.TouchAPIToken(ci.ID, used))
+		got, err = store.GetAPIToken("hash-ci")
+		assert.NoError(t, err)
+		if assert.NotNil(t, got.LastUsedAt) {
+			assert.True(t, got.LastUsedAt.Equal(used))
+		}
+
+		tokens, err := store.ListAPITokens(1)
+		assert.NoError(t, err)
+		if assert.Len(t, tokens, 1) {
+			assert.Equal(t, ci.ID, tokens[0].ID)
+		}
+
+		assert.ErrorIs(t, store.DeleteAPIToken(1, other.ID), ErrTokenNotFound)
+		assert.NoError(t, store.DeleteAPIToken(1, ci.ID))
+		_, err = store.GetAPIToken("hash-ci")
+		assert.ErrorIs(t, err, ErrTokenNotFound)
+		assert.ErrorIs(t, store.DeleteAPIToken(1, ci.ID), ErrTokenNotFound)
+		_, err = store.GetAPIToken("hash-other")
+		assert.NoError(t, err)
+	})
+}
+
+func TestIdentities(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		now := time.Now().UTC().Truncate(time.Second)
+		assert.NoError(t, store.CreateIdentity(&models.Identity{Issuer: "https://idp.test", Subject: "abc", UserID: 3, CreatedAt: now}))
+
+		got, err := store.GetIdentity("https://idp.test", "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, got.UserID)
+		assert.True(t, got.CreatedAt.Equal(now))
+
+		_, err = store.GetIdentity("https://other.test", "abc")
+		assert.ErrorIs(t, err, ErrIdentityNotFound)
+		_, err = store.GetIdentity("https://idp.test", "abd")
+		assert.ErrorIs(t, err, ErrIdentityNotFound)
+	})
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"bugtracker-backend/internal/db"
+	"bugtracker-backend/internal/models"
+)
+
+func (h *Handler) registerAuditRoutes(r *mux.Router) {
+	r.HandleFunc("/admin/audit", h.GetAudit).Methods("GET").Name("GetAudit")
+	r.HandleFunc("/admin/audit/verify", h.VerifyAudit).Methods("GET").Name("VerifyAudit")
+	r.HandleFunc("/admin/audit/export", h.ExportAudit).Methods("GET").Name("ExportAudit")
+}
+
+// statusRecorder remembers the status code a handler wrote.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// auditActorKey is the context key of the *string through which
+// Authenticate, which runs inside AuditWrites, tells it who made the
+// request.
+type auditActorKey struct{}
+
+// setAuditActor records the signed-in user of a request being audited.
+func setAuditActor(r *http.Request, username string) {
+	if actor, ok := r.Context().Value(auditActorKey{}).(*string); ok {
+		*actor = username
+	}
+}
+
+// AuditWrites appends every POST, PUT, PATCH and DELETE request to the audit
+// log once it has been handled, whether or not it succeeded. The action is
+// the method and route template, so all requests to one endpoint share it.
+// Install it before Authenticate, so that requests without valid
+// credentials are audited too, with no actor.
+func (h *Handler) AuditWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		actor := requestActor(r)
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), auditActorKey{}, &actor)))
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		action := r.Method + " " + r.URL.Path
+		if route := mux.CurrentRoute(r); route != nil {
+			if tmpl, err := route.GetPathTemplate(); err == nil {
+				action = r.Method + " " + tmpl
+			}
+		}
+		entry := &models.AuditEntry{
+			At:         time.Now().UTC(),
+			Actor:      actor,
+			Action:     action,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Status:     rec.status,
+			RemoteAddr: r.RemoteAddr,
+		}
+		if err := h.store.AppendAudit(entry); err != nil {
+			log.Printf("Failed to append audit entry for %s: %v", action, err)
+		}
+	})
+}
+
+// GetAudit lists audit entries in sequence order, filtered by the actor,
+// action, since and until parameters. X-Next-Cursor holds the value to pass
+// as after for the next page.
+func (h *Handler) GetAudit(w http.ResponseWriter, r *http.Request) {
+	query, err := parseAuditQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, err := h.store.ListAudit(query)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+
+	if len(entries) == query.Limit {
+		w.Header().Set("X-Next-Cursor", strconv.Itoa(entries[len(entries)-1].Seq))
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// VerifyAudit checks the whole audit chain. A broken chain is reported with
+// 409 and the sequence number of the first bad entry.
+func (h *Handler) VerifyAudit(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.store.ListAudit(db.AuditQuery{})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	err = models.VerifyAuditChain(entries)
+	var cerr *models.AuditChainError
+	if errors.As(err, &cerr) {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"ok":    false,
+			"error": err.Error(),
+			"seq":   cerr.Seq,
+		})
+		return
+	}
+
+	result := map[string]interface{}{
+		"ok":      true,
+		"entries": len(entries),
+	}
+	if len(entries) > 0 {
+		result["head"] = entries[len(entries)-1].Hash
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ExportAudit writes the audit entries matching the query as JSON Lines,
+// one entry per line.
+func (h *Handler) ExportAudit(w http.ResponseWriter, r *http.Request) {
+	query, err := parseAuditQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	query.Limit = 0
+
+	entries, err := h.store.ListAudit(query)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.jsonl"`)
+	enc := json.NewEncoder(w)
+	for i := range entries {
+		if err := enc.Encode(&entries[i]); err != nil {
+			log.Printf("Failed to export audit log: %v", err)
+			return
+		}
+	}
+}
+
+// parseAuditQuery builds an audit query from the GET /admin/audit query
+// string. Times accept the same formats as the GET /bugs date filters.
+func parseAuditQuery(values url.Values) (db.AuditQuery, error) {
+	q := db.AuditQuery{
+		Actor:  values.Get("actor"),
+		Action: values.Get("action"),
+		Limit:  maxPageSize,
+	}
+
+	for _, d := range []struct {
+		param string
+		dest  *time.Time
+	}{
+		{"since", &q.Since},
+		{"until", &q.Until},
+	} {
+		v := values.Get(d.param)
+		if v == "" {
+			continue
+		}
+		t, err := parseDateParam(v)
+		if err != nil {
+			return q, fmt.Errorf("invalid %s %q", d.param, v)
+		}
+		*d.dest = t
+	}
+
+	if v := values.Get("after"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return q, fmt.Errorf("invalid after %q", v)
+		}
+		q.AfterSeq = n
+	}
+
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return q, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		q.Limit = limit
+	}
+
+	return q, nil
+}
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"bugtracker-backend/internal/auth"
+	"bugtracker-backend/internal/db"
+	"bugtracker-backend/internal/models"
+)
+
+// loginRoute names the one write route open to anonymous clients.
+const loginRoute = "Login"
+
+func (h *Handler) registerAuthRoutes(r *mux.Router) {
+	r.HandleFunc("/auth/login", h.Login).Methods("POST").Name(loginRoute)
+	r.HandleFunc("/auth/logout", h.Logout).Methods("POST").Name("Logout")
+	r.HandleFunc("/auth/me", h.GetCurrentUser).Methods("GET").Name("GetCurrentUser")
+	r.HandleFunc("/users", h.GetUsers).Methods("GET").Name("GetUsers")
+	r.HandleFunc("/users", h.CreateUser).Methods("POST").Name("CreateUser")
+	r.HandleFunc("/users/{id}/role", h.SetUserRole).Methods("PUT").Name("SetUserRole")
+}
+
+var (
+	// errInvalidSession is returned for a bearer token that does not name a
+	// current session.
+	errInvalidSession = errors.New("invalid or expired session")
+	// errInvalidAPIToken is returned for an API token that is unknown,
+	// revoked or expired.
+	errInvalidAPIToken = errors.New("invalid, revoked or expired API token")
+	// errAuthRequired is returned when an anonymous client asks for
+	// something that needs a signed-in user.
+	errAuthRequired = errors.New("authentication required")
+)
+
+// lastUsedResolution is how stale an API token's last-used time may get
+// before a request updates it, so that busy tokens do not cost a write on
+// every request.
+const lastUsedResolution = time.Minute
+
+// writeUnauthorized rejects a request that needs a signed-in user.
+func writeUnauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	writeError(w, http.StatusUnauthorized, message)
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header, or ""
+// if there is none.
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// Authenticate attaches the user of the session or API token named by an
+// "Authorization: Bearer" header to the request's context, where
+// requestActor finds it, together with the token's scope. Reads are open to
+// anonymous clients, but every other request except logging in must carry a
+// valid token, and read-scoped API tokens may only read.
+func (h *Handler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil && route.GetName() == loginRoute {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := bearerToken(r)
+		if token == "" {
+			if isSafeMethod(r.Method) {
+				next.ServeHTTP(w, r)
+			} else {
+				writeUnauthorized(w, "authentication required")
+			}
+			return
+		}
+
+		var (
+			user  *models.User
+			scope = models.ScopeAdmin
+			err   error
+		)
+		if strings.HasPrefix(token, auth.APITokenPrefix) {
+			user, scope, err = h.apiTokenUser(token)
+		} else {
+			user, err = h.sessionUser(token)
+		}
+		if errors.Is(err, errInvalidSession) || errors.Is(err, errInvalidAPIToken) {
+			writeUnauthorized(w, err.Error())
+			return
+		}
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		setAuditActor(r, user.Username)
+		if scope == models.ScopeRead && !isSafeMethod(r.Method) {
+			writeError(w, http.StatusForbidden, "this API token has the read scope and may not make changes")
+			return
+		}
+		ctx := auth.WithScope(auth.WithUser(r.Context(), user), scope)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// isSafeMethod reports whether method only reads.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// sessionUser returns the user signed in with token.
+func (h *Handler) sessionUser(token string) (user *models.User, err error) {
+	err = h.store.View(func(tx db.Tx) error {
+		session, err := tx.GetSession(auth.HashToken(token))
+		if errors.Is(err, db.ErrSessionNotFound) {
+			return errInvalidSession
+		}
+		if err != nil {
+			return err
+		}
+		if session.Expired(time.Now()) {
+			return errInvalidSession
+		}
+		user, err = tx.GetUser(session.UserID)
+		if errors.Is(err, db.ErrUserNotFound) {
+			return errInvalidSession
+		}
+		return err
+	})
+	return user, err
+}
+
+// apiTokenUser returns the owner and scope of an API token, recording that
+// the token was used.
+func (h *Handler) apiTokenUser(token string) (user *models.User, scope string, err error) {
+	now := time.Now().UTC()
+	var apiToken *models.APIToken
+	err = h.store.View(func(tx db.Tx) error {
+		apiToken, err = tx.GetAPIToken(auth.HashToken(token))
+		if errors.Is(err, db.ErrTokenNotFound) {
+			return errInvalidAPIToken
+		}
+		if err != nil {
+			return err
+		}
+		if apiToken.Expired(now) {
+			return errInvalidAPIToken
+		}
+		user, err = tx.GetUser(apiToken.UserID)
+		if errors.Is(err, db.ErrUserNotFound) {
+			return errInvalidAPIToken
+		}
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if apiToken.LastUsedAt == nil || now.Sub(*apiToken.LastUsedAt) >= lastUsedResolution {
+		// The request can go ahead even if this fails; the time is only a
+		// hint for users tidying up their tokens.
+		if err := h.store.TouchAPIToken(apiToken.ID, now); err != nil {
+			log.Printf("Failed to record use of API token %d: %v", apiToken.ID, err)
+		}
+	}
+	return user, apiToken.Scope, nil
+}
+
+// Login checks a username and password and starts a session.
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	user, err := h.store.GetUserByName(req.Username)
+	if errors.Is(err, db.ErrUserNotFound) {
+		// Take as long as a wrong password would, so that responses do not
+		// reveal which usernames exist.
+		auth.CheckNoPassword(req.Password)
+		writeUnauthorized(w, "invalid username or password")
+		return
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if !auth.CheckPassword(user.PasswordHash, req.Password) {
+		writeUnauthorized(w, "invalid username or password")
+		return
+	}
+
+	login, err := h.startSession(user)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, login)
+}
+
+// startSession signs user in, clearing out sessions that have expired at
+// the same time.
+func (h *Handler) startSession(user *models.User) (*models.LoginResponse, error) {
+	token, err := auth.NewToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	session := &models.Session{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(h.sessionTTL),
+	}
+	err = h.store.Update(func(tx db.Tx) error {
+		if _, err := tx.DeleteExpiredSessions(now); err != nil {
+			return err
+		}
+		return tx.CreateSession(session)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.LoginResponse{Token: token, ExpiresAt: session.ExpiresAt, User: user}, nil
+}
+
+// Logout ends the session the request was made with.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r)
+	if token == "" {
+		writeUnauthorized(w, "authentication required")
+		return
+	}
+	if strings.HasPrefix(token, auth.APITokenPrefix) {
+		writeError(w, http.StatusBadRequest, "API tokens are revoked with DELETE /api/tokens/{id}, not logged out")
+		return
+	}
+	if err := h.store.DeleteSession(auth.HashToken(token)); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCurrentUser returns the signed-in user.
+func (h *Handler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	user := auth.UserFromContext(r.Context())
+	if user == nil {
+		writeUnauthorized(w, "authentication required")
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+// GetUsers lists every user to signed-in users.
+func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	if requestActor(r) == "" {
+		writeUnauthorized(w, "authentication required")
+		return
+	}
+	users, err := h.store.ListUsers()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if users == nil {
+		users = []models.User{}
+	}
+	writeJSON(w, http.StatusOK, users)
+}
+
+// CreateUser adds a user account, with the default role unless the
+// request names one.
+func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	user := &models.User{Username: req.Username, DisplayName: req.DisplayName, Role: req.Role, PasswordHash: hash}
+	if err := h.store.CreateUser(user); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, user)
+}
+
+// SetUserRole changes a user's role. It takes effect on the user's next
+// request.
+func (h *Handler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	var req models.SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	var user *models.User
+	err = h.store.Update(func(tx db.Tx) error {
+		if user, err = tx.GetUser(id); err != nil {
+			return err
+		}
+		user.Role = req.Role
+		return tx.UpdateUser(user)
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
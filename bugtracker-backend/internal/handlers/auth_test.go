@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bugtracker-backend/internal/auth"
+	"bugtracker-backend/internal/db"
+	"bugtracker-backend/internal/models"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// createTestUser stores a user who can log in with password.
+func createTestUser(t *testing.T, store db.Store, username, password string) *models.User {
+	hash, err := auth.HashPassword(password)
+	assert.NoError(t, err)
+	user := &models.User{Username: username, PasswordHash: hash}
+	assert.NoError(t, store.CreateUser(user))
+	return user
+}
+
+func TestAuthentication(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := mux.NewRouter()
+	router.Use(h.AuditWrites, h.Authenticate, h.Authorize)
+	h.RegisterRoutes(router)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	createTestUser(t, store, "alice", "correct horse")
+
+	w := do("POST", "/auth/login", "", `{"username": "alice", "password": "wrong"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+	w = do("POST", "/auth/login", "", `{"username": "nobody", "password": "correct horse"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = do("POST", "/auth/login", "", `{"username": "Alice", "password": "correct horse"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var login models.LoginResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&login))
+	assert.NotEmpty(t, login.Token)
+	assert.Equal(t, "alice", login.User.Username)
+	assert.True(t, login.ExpiresAt.After(time.Now()))
+	assert.NotContains(t, w.Body.String(), "password")
+	token := login.Token
+
+	// Reads are open, writes need a session.
+	assert.Equal(t, http.StatusOK, do("GET", "/bugs", "", "").Code)
+	w = do("POST", "/bugs", "", `{"title": "Crash"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "authentication required")
+	assert.Equal(t, http.StatusUnauthorized, do("DELETE", "/bugs", "", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, do("POST", "/bugs", "not-a-token", `{"title": "Crash"}`).Code)
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/bugs", "not-a-token", "").Code)
+
+	w = do("POST", "/bugs", token, `{"title": "Crash"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var bug models.Bug
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&bug))
+	assert.Equal(t, "alice", bug.Reporter)
+
+	w = do("POST", "/bugs/1/comments", token, `{"author": "mallory", "content": "Confirmed"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var comment models.Comment
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&comment))
+	assert.Equal(t, "alice", comment.Author)
+
+	w = do("GET", "/auth/me", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"username":"alice"`)
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/auth/me", "", "").Code)
+
+	// The audit log names the signed-in user; the refused anonymous writes
+	// have no actor.
+	entries, err := store.ListAudit(db.AuditQuery{Action: "POST /bugs"})
+	assert.NoError(t, err)
+	actors := []string{}
+	for _, e := range entries {
+		actors = append(actors, e.Actor)
+	}
+	assert.ElementsMatch(t, []string{"", "", "alice"}, actors)
+
+	assert.Equal(t, http.StatusNoContent, do("POST", "/auth/logout", token, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/auth/me", token, "").Code)
+}
+
+func TestExpiredSession(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+	h.SetSessionTTL(time.Millisecond)
+
+	router := mux.NewRouter()
+	router.Use(h.Authenticate, h.Authorize)
+	h.RegisterRoutes(router)
+
+	createTestUser(t, store, "alice", "correct horse")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/auth/login", bytes.NewBufferString(`{"username": "alice", "password": "correct horse"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var login models.LoginResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&login))
+
+	time.Sleep(5 * time.Millisecond)
+	req := httptest.NewRequest("POST", "/bugs", bytes.NewBufferString(`{"title": "Crash"}`))
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid or expired session")
+}
+
+func TestCreateUser(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+	h.SetAdmins([]string{"root"})
+
+	router := mux.NewRouter()
+	router.Use(h.Authorize)
+	h.RegisterRoutes(router)
+
+	do := func(actor, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/users", bytes.NewBufferString(body))
+		if actor != "" {
+			req = withUser(req, actor)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	valid := `{"username": "Bob", "display_name": "Bob B.", "password": "long enough"}`
+	assert.Equal(t, http.StatusUnauthorized, do("", valid).Code)
+	assert.Equal(t, http.StatusForbidden, do("alice", valid).Code)
+
+	w := do("root", valid)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var user models.User
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&user))
+	assert.Equal(t, "bob", user.Username)
+	assert.Equal(t, "Bob B.", user.DisplayName)
+	assert.NotContains(t, w.Body.String(), "password")
+
+	assert.Equal(t, http.StatusConflict, do("root", valid).Code)
+
+	w = do("root", `{"username": "no spaces", "password": "short"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var resp struct {
+		Fields []models.FieldError `json:"fields"`
+	}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Len(t, resp.Fields, 2)
+
+	req := withUser(httptest.NewRequest("GET", "/users", nil), "alice")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var users []models.User
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&users))
+	assert.Len(t, users, 1)
+}
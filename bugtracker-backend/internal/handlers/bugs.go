@@ -2,217 +2,691 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 
+	"bugtracker-backend/internal/bugquery"
 	"bugtracker-backend/internal/db"
 	"bugtracker-backend/internal/models"
+	"bugtracker-backend/internal/patch"
+	"bugtracker-backend/internal/rbac"
 )
 
-func RegisterRoutes(r *mux.Router) {
-	r.HandleFunc("/bugs", CreateBug).Methods("POST")
-	r.HandleFunc("/bugs", GetBugs).Methods("GET")
-	r.HandleFunc("/bugs", DeleteAllBugs).Methods("DELETE")
-	r.HandleFunc("/bugs/{id}", GetBug).Methods("GET")
-	r.HandleFunc("/bugs/{id}", UpdateBug).Methods("PUT")
-	r.HandleFunc("/bugs/{id}", DeleteBug).Methods("DELETE")
-	RegisterCommentRoutes(r)
+func (h *Handler) registerBugRoutes(r *mux.Router) {
+	r.HandleFunc("/bugs", h.CreateBug).Methods("POST").Name("CreateBug")
+	r.HandleFunc("/bugs", h.GetBugs).Methods("GET").Name("GetBugs")
+	r.HandleFunc("/bugs", h.DeleteAllBugs).Methods("DELETE").Name("DeleteAllBugs")
+	r.HandleFunc("/bugs/{id}", h.GetBug).Methods("GET").Name("GetBug")
+	r.HandleFunc("/bugs/{id}", h.UpdateBug).Methods("PUT").Name("UpdateBug")
+	r.HandleFunc("/bugs/{id}", h.PatchBug).Methods("PATCH").Name("PatchBug")
+	r.HandleFunc("/bugs/{id}", h.DeleteBug).Methods("DELETE").Name("DeleteBug")
+	r.HandleFunc("/bugs/{id}/transitions", h.GetTransitions).Methods("GET").Name("GetTransitions")
+	r.HandleFunc("/trash", h.GetTrash).Methods("GET").Name("GetTrash")
+	r.HandleFunc("/trash/{id}/restore", h.RestoreBug).Methods("POST").Name("RestoreBug")
 }
 
-func CreateBug(w http.ResponseWriter, r *http.Request) {
+// CreateBug files a bug in the project named by the {key} route variable,
+// or else by the request body, or else in the default project.
+func (h *Handler) CreateBug(w http.ResponseWriter, r *http.Request) {
 	log.Printf("CreateBug called from %s", r.RemoteAddr)
-	log.Printf("Request headers: %v", r.Header)
 	log.Printf("Request method: %s", r.Method)
 
 	var req models.CreateBugRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.Printf("Failed to decode create bug request: %v", err)
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "invalid request body",
-		})
+		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
-	if req.Title == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "title is required",
-		})
+	// A project named in the URL must exist like any other resource; one
+	// named in the body is a field of the request and validated with it.
+	key, inURL := mux.Vars(r)["key"]
+	if inURL {
+		req.Project = key
+	}
+	projectErr, err := h.bugProjectError(req.Project, inURL)
+	if err != nil {
+		writeStoreError(w, err)
 		return
 	}
 
-	bug := &models.Bug{
-		Title:       req.Title,
-		Description: req.Description,
-		Status:      req.Status,
-		Priority:    req.Priority,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+	now := time.Now()
+	bug := &models.Bug{Project: req.Project, CreatedAt: now, Reporter: requestActor(r)}
+	err = h.applyBugRequest(bug, req, now)
+	var verr *models.ValidationError
+	switch {
+	case projectErr != nil && errors.As(err, &verr):
+		verr.Fields = append([]models.FieldError{*projectErr}, verr.Fields...)
+	case projectErr != nil && err == nil:
+		err = &models.ValidationError{Fields: []models.FieldError{*projectErr}}
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
 	}
 
-	if err := db.CreateBug(bug); err != nil {
+	err = h.store.Update(func(tx db.Tx) error {
+		if err := tx.CreateBug(bug); err != nil {
+			return err
+		}
+		return recordHistory(tx, r, bug.ID, models.HistoryCreated, now, models.DiffBugs(&models.Bug{}, bug))
+	})
+	if err != nil {
 		log.Printf("Failed to create bug: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
-		})
+		writeStoreError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(bug)
+	w.Header().Set("ETag", bugETag(bug))
+	writeJSON(w, http.StatusCreated, bug)
 }
 
-func GetBugs(w http.ResponseWriter, r *http.Request) {
+// bugProjectError returns a field error if key, named in the body of a new
+// bug, is not a project. A key from the URL is left to the store, which
+// reports an unknown project as not found.
+func (h *Handler) bugProjectError(key string, inURL bool) (*models.FieldError, error) {
+	if inURL || key == "" {
+		return nil, nil
+	}
+	_, err := h.store.GetProject(key)
+	if errors.Is(err, db.ErrProjectNotFound) {
+		return &models.FieldError{Field: "project", Message: fmt.Sprintf("unknown project %q", key)}, nil
+	}
+	return nil, err
+}
+
+// GetBugs lists the bugs of the project named by the {key} route variable
+// or the project query parameter, or of every project.
+func (h *Handler) GetBugs(w http.ResponseWriter, r *http.Request) {
 	log.Printf("GetBugs called from %s", r.RemoteAddr)
-	log.Printf("Request headers: %v", r.Header)
-	bugs, err := db.GetAllBugs()
+
+	query, err := parseBugQuery(r.URL.Query())
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	if key, ok := mux.Vars(r)["key"]; ok {
+		project, err := h.store.GetProject(key)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		query.Project = project.Key
+	}
+
+	bugs, next, err := h.store.ListBugs(query)
 	if err != nil {
 		log.Printf("Error getting bugs: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeStoreError(w, err)
 		return
 	}
 	log.Printf("Successfully retrieved %d bugs", len(bugs))
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(bugs)
-}
 
-func GetBug(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	w.Header().Set("Content-Type", "application/json")
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
+	if bugs == nil {
+		bugs = []*models.Bug{}
+	}
+	writeJSON(w, http.StatusOK, bugs)
+}
 
-	idInt, err := strconv.Atoi(id)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "invalid bug ID",
-		})
+func (h *Handler) GetBug(w http.ResponseWriter, r *http.Request) {
+	idInt, ok := h.bugIDFromRequest(w, r)
+	if !ok {
 		return
 	}
 
-	bug, err := db.GetBug(idInt)
+	bug, err := h.store.GetBug(idInt)
 	if err != nil {
-		status := http.StatusInternalServerError
-		if err.Error() == "bug not found" {
-			status = http.StatusNotFound
-		}
-		w.WriteHeader(status)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
-		})
+		writeStoreError(w, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(bug)
+	etag := bugETag(bug)
+	w.Header().Set("ETag", etag)
+	if notModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, http.StatusOK, bug)
 }
 
-func UpdateBug(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+func (h *Handler) UpdateBug(w http.ResponseWriter, r *http.Request) {
+	idInt, ok := h.bugIDFromRequest(w, r)
+	if !ok {
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
+	var req models.CreateBugRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
 
-	idInt, err := strconv.Atoi(id)
+	var existingBug *models.Bug
+	err := h.store.Update(func(tx db.Tx) error {
+		bug, err := tx.GetBug(idInt)
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(r, bug); err != nil {
+			return err
+		}
+		old, now := *bug, time.Now()
+		if err := h.applyBugRequest(bug, req, now); err != nil {
+			return err
+		}
+		existingBug = bug
+		return h.saveBugUpdate(tx, r, &old, bug, now)
+	})
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "invalid bug ID",
-		})
+		writeStoreError(w, err)
 		return
 	}
 
-	var req models.CreateBugRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "invalid request body",
-		})
+	w.Header().Set("ETag", bugETag(existingBug))
+	writeJSON(w, http.StatusOK, existingBug)
+}
+
+// PatchBug updates only the fields named in the request body, which is an
+// RFC 7396 merge patch (application/merge-patch+json or plain JSON) or an
+// RFC 6902 JSON Patch (application/json-patch+json). The patch is applied to
+// the bug's editable fields and the result goes through the same validation
+// as a full update.
+func (h *Handler) PatchBug(w http.ResponseWriter, r *http.Request) {
+	idInt, ok := h.bugIDFromRequest(w, r)
+	if !ok {
 		return
 	}
 
-	existingBug, err := db.GetBug(idInt)
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil && r.Header.Get("Content-Type") != "" {
+		writeError(w, http.StatusUnsupportedMediaType, "invalid Content-Type")
+		return
+	}
+	var apply func(map[string]interface{}, []byte) (map[string]interface{}, error)
+	switch mediaType {
+	case "", "application/json", patch.MergePatchType:
+		apply = patch.Merge
+	case patch.JSONPatchType:
+		apply = patch.Apply
+	default:
+		w.Header().Set("Accept-Patch", patch.MergePatchType+", "+patch.JSONPatchType)
+		writeError(w, http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported patch type %q", mediaType))
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		status := http.StatusInternalServerError
-		if err.Error() == "bug not found" {
-			status = http.StatusNotFound
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	var existingBug *models.Bug
+	err = h.store.Update(func(tx db.Tx) error {
+		bug, err := tx.GetBug(idInt)
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(r, bug); err != nil {
+			return err
+		}
+		doc, err := apply(bugPatchDocument(bug), body)
+		if err != nil {
+			return err
+		}
+		req, err := bugRequestFromPatch(doc)
+		if err != nil {
+			return err
+		}
+		// A resolved bug keeps its resolution until it is reopened, so
+		// removing it alone cannot be done and must not pass as a no-op.
+		if bug.Resolution != "" && req.Resolution == "" && h.workflow.IsResolved(req.Status) {
+			return &models.ValidationError{Fields: []models.FieldError{{
+				Field:   "resolution",
+				Message: "resolution cannot be removed from a resolved bug; reopen it instead",
+			}}}
 		}
-		w.WriteHeader(status)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
-		})
+		// The document carries the current resolution, so an unchanged one
+		// is not a request to set it; this lets {"status": "Open"} reopen a
+		// resolved bug.
+		if req.Resolution == bug.Resolution {
+			req.Resolution = ""
+		}
+		old, now := *bug, time.Now()
+		if err := h.applyBugRequest(bug, req, now); err != nil {
+			return err
+		}
+		existingBug = bug
+		return h.saveBugUpdate(tx, r, &old, bug, now)
+	})
+	if err != nil {
+		writePatchError(w, err)
 		return
 	}
 
-	existingBug.Title = req.Title
-	existingBug.Description = req.Description
-	existingBug.Status = req.Status
-	existingBug.Priority = req.Priority
-	existingBug.UpdatedAt = time.Now()
+	w.Header().Set("ETag", bugETag(existingBug))
+	writeJSON(w, http.StatusOK, existingBug)
+}
 
-	if err := db.UpdateBug(existingBug); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
-		})
-		return
+// patchableFields are the members of the document a PATCH request edits.
+var patchableFields = map[string]bool{
+	"title": true, "description": true, "status": true, "priority": true, "resolution": true,
+}
+
+// bugPatchDocument returns the editable fields of bug as the JSON object a
+// patch is applied to. resolution is only present on resolved bugs.
+func bugPatchDocument(bug *models.Bug) map[string]interface{} {
+	doc := map[string]interface{}{
+		"title":       bug.Title,
+		"description": bug.Description,
+		"status":      bug.Status,
+		"priority":    bug.Priority,
+	}
+	if bug.Resolution != "" {
+		doc["resolution"] = bug.Resolution
 	}
+	return doc
+}
 
-	json.NewEncoder(w).Encode(existingBug)
+// bugRequestFromPatch converts a patched document back into an update
+// request. Read-only or unknown members and removed status or priority are
+// reported as validation errors; a removed title is caught by validation.
+func bugRequestFromPatch(doc map[string]interface{}) (models.CreateBugRequest, error) {
+	var req models.CreateBugRequest
+	verr := &models.ValidationError{}
+	for field := range doc {
+		if !patchableFields[field] {
+			verr.Fields = append(verr.Fields, models.FieldError{Field: field, Message: fmt.Sprintf("%s cannot be changed", field)})
+		}
+	}
+	for _, field := range []string{"status", "priority"} {
+		if _, ok := doc[field]; !ok {
+			verr.Fields = append(verr.Fields, models.FieldError{Field: field, Message: fmt.Sprintf("%s cannot be removed", field)})
+		}
+	}
+	if len(verr.Fields) > 0 {
+		sort.Slice(verr.Fields, func(i, j int) bool { return verr.Fields[i].Field < verr.Fields[j].Field })
+		return req, verr
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return req, err
+	}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, fmt.Errorf("%w: %v", patch.ErrInvalidPatch, err)
+	}
+	return req, nil
 }
 
-func DeleteBug(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+// writePatchError reports a malformed patch as 400 and a failed JSON Patch
+// test as 409, leaving everything else to writeStoreError.
+func writePatchError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, patch.ErrInvalidPatch):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, patch.ErrTestFailed):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeStoreError(w, err)
+	}
+}
+
+// saveBugUpdate stores an updated bug and records the fields that changed
+// in its history. An update that changes nothing is neither stored nor
+// recorded, and bug is reset to old, so that its version and ETag stay
+// the same. Moving the bug through the workflow needs rbac.TransitionBugs
+// on top of the permission to edit it.
+func (h *Handler) saveBugUpdate(tx db.Tx, r *http.Request, old, bug *models.Bug, now time.Time) error {
+	changes := models.DiffBugs(old, bug)
+	if len(changes) == 0 {
+		*bug = *old
+		return nil
+	}
+	if bug.Status != old.Status || bug.Resolution != old.Resolution {
+		if err := h.authorize(r, rbac.TransitionBugs); err != nil {
+			return err
+		}
+	}
+	if err := tx.UpdateBug(bug); err != nil {
+		return err
+	}
+	return recordHistory(tx, r, bug.ID, models.HistoryUpdated, now, changes)
+}
+
+// applyBugRequest is the validation pipeline shared by create and update.
+// Empty status and priority keep the bug's current values, falling back to
+// the defaults for new bugs; every field is then validated, reporting all
+// problems at once, and new bugs must start in the initial status; finally
+// the status change must follow the workflow, which also maintains the
+// resolution fields. bug is only modified if the request is valid.
+func (h *Handler) applyBugRequest(bug *models.Bug, req models.CreateBugRequest, now time.Time) error {
+	changed := models.Bug{
+		Title:       req.Title,
+		Description: req.Description,
+		Status:      req.Status,
+		Priority:    req.Priority,
+		Resolution:  req.Resolution,
+	}
+	if changed.Status == "" {
+		changed.Status = bug.Status
+	}
+	if changed.Priority == "" {
+		changed.Priority = bug.Priority
+	}
+	changed.ApplyDefaults(h.workflow.Initial)
+	var err error
+	if bug.ID == 0 {
+		err = changed.ValidateNew(h.workflow, h.workflow.Initial)
+	} else {
+		err = changed.Validate(h.workflow)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := h.workflow.Apply(bug, changed.Status, req.Resolution, now); err != nil {
+		return err
+	}
+	bug.Title = changed.Title
+	bug.Description = changed.Description
+	bug.Priority = changed.Priority
+	bug.UpdatedAt = now
+	return nil
+}
+
+// transition is one status a bug may move to.
+type transition struct {
+	Status   string `json:"status"`
+	Resolved bool   `json:"resolved"`
+}
 
-	w.Header().Set("Content-Type", "application/json")
+// GetTransitions lists the statuses the bug's current status may move to.
+func (h *Handler) GetTransitions(w http.ResponseWriter, r *http.Request) {
+	idInt, ok := h.bugIDFromRequest(w, r)
+	if !ok {
+		return
+	}
 
-	idInt, err := strconv.Atoi(id)
+	bug, err := h.store.GetBug(idInt)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "invalid bug ID",
-		})
+		writeStoreError(w, err)
 		return
 	}
 
-	if err := db.DeleteBug(idInt); err != nil {
-		status := http.StatusInternalServerError
-		if err.Error() == "bug not found" {
-			status = http.StatusNotFound
+	transitions := []transition{}
+	for _, status := range h.workflow.Transitions(bug.Status) {
+		transitions = append(transitions, transition{Status: status, Resolved: h.workflow.IsResolved(status)})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":      bug.Status,
+		"transitions": transitions,
+	})
+}
+
+// DeleteBug moves a bug to the trash, from which it can be restored until
+// the retention period runs out.
+func (h *Handler) DeleteBug(w http.ResponseWriter, r *http.Request) {
+	idInt, ok := h.bugIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.store.Update(func(tx db.Tx) error {
+		bug, err := tx.GetBug(idInt)
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(r, bug); err != nil {
+			return err
 		}
-		w.WriteHeader(status)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
-		})
+		now := time.Now()
+		if err := tx.TrashBug(idInt, requestActor(r), now); err != nil {
+			return err
+		}
+		return recordHistory(tx, r, idInt, models.HistoryDeleted, now, nil)
+	})
+	if err != nil {
+		writeStoreError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func DeleteAllBugs(w http.ResponseWriter, r *http.Request) {
+// DeleteAllBugs moves every live bug to the trash.
+func (h *Handler) DeleteAllBugs(w http.ResponseWriter, r *http.Request) {
 	log.Printf("DeleteAllBugs called from %s", r.RemoteAddr)
-	
-	count, err := db.DeleteAllBugs()
+
+	var count int
+	err := h.store.Update(func(tx db.Tx) error {
+		bugs, err := tx.GetAllBugs()
+		if err != nil {
+			return err
+		}
+		by, now := requestActor(r), time.Now()
+		for _, bug := range bugs {
+			if err := tx.TrashBug(bug.ID, by, now); err != nil {
+				return err
+			}
+			if err := recordHistory(tx, r, bug.ID, models.HistoryDeleted, now, nil); err != nil {
+				return err
+			}
+		}
+		count = len(bugs)
+		return nil
+	})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
-		})
+		writeStoreError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]int{
+	writeJSON(w, http.StatusOK, map[string]int{
 		"deleted": count,
 	})
 }
+
+// GetTrash lists the bugs in the trash. It accepts the same filters, sorting
+// and paging as GET /bugs.
+func (h *Handler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	query, err := parseBugQuery(r.URL.Query())
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	query.Trashed = true
+
+	bugs, next, err := h.store.ListBugs(query)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
+	if bugs == nil {
+		bugs = []*models.Bug{}
+	}
+	writeJSON(w, http.StatusOK, bugs)
+}
+
+// RestoreBug brings a bug and its comments back from the trash.
+func (h *Handler) RestoreBug(w http.ResponseWriter, r *http.Request) {
+	idInt, ok := h.bugIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var bug *models.Bug
+	err := h.store.Update(func(tx db.Tx) error {
+		var err error
+		if bug, err = tx.RestoreBug(idInt); err != nil {
+			return err
+		}
+		return recordHistory(tx, r, idInt, models.HistoryRestored, bug.UpdatedAt, nil)
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", bugETag(bug))
+	writeJSON(w, http.StatusOK, bug)
+}
+
+// bugIDFromRequest returns the ID of the bug a route names, either in the
+// {id} route variable, as an ID or a key such as WEB-42, or in the {key}
+// and {number} variables of a project route. It writes an error response
+// and returns false when there is no such bug.
+func (h *Handler) bugIDFromRequest(w http.ResponseWriter, r *http.Request) (int, bool) {
+	vars := mux.Vars(r)
+	ref := vars["id"]
+	if number, ok := vars["number"]; ok {
+		ref = vars["key"] + "-" + number
+	}
+	if id, err := strconv.Atoi(ref); err == nil {
+		return id, true
+	}
+
+	project, number, err := models.ParseBugKey(ref)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid bug ID or key")
+		return 0, false
+	}
+	id, err := h.store.GetBugID(project, number)
+	if err != nil {
+		writeStoreError(w, err)
+		return 0, false
+	}
+	return id, true
+}
+
+// maxPageSize caps the limit a client may request from GET /bugs.
+const maxPageSize = 500
+
+// defaultPageSize is the limit of GET /bugs, GET /trash and the flat view
+// of GET /bugs/{id}/comments when the client sets none.
+const defaultPageSize = 100
+
+// parseBugQuery builds a storage query from the GET /bugs query string.
+// Status and priority accept comma separated or repeated values; dates are
+// RFC 3339 timestamps or plain YYYY-MM-DD days. The q parameter holds a
+// query-language expression that is combined with the other filters.
+func parseBugQuery(values url.Values) (db.BugQuery, error) {
+	q := db.BugQuery{
+		Limit:       defaultPageSize,
+		Project:     models.NormalizeProjectKey(values.Get("project")),
+		Statuses:    splitListParam(values["status"]),
+		Priorities:  splitListParam(values["priority"]),
+		Resolutions: splitListParam(values["resolution"]),
+		SortBy:      values.Get("sort"),
+		Cursor:      values.Get("cursor"),
+	}
+
+	switch order := values.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		q.Descending = true
+	default:
+		return q, fmt.Errorf("invalid order %q", order)
+	}
+
+	dates := []struct {
+		param string
+		dest  *time.Time
+	}{
+		{"created_after", &q.CreatedAfter},
+		{"created_before", &q.CreatedBefore},
+		{"updated_after", &q.UpdatedAfter},
+		{"updated_before", &q.UpdatedBefore},
+		{"resolved_after", &q.ResolvedAfter},
+		{"resolved_before", &q.ResolvedBefore},
+	}
+	for _, d := range dates {
+		v := values.Get(d.param)
+		if v == "" {
+			continue
+		}
+		t, err := parseDateParam(v)
+		if err != nil {
+			return q, fmt.Errorf("invalid %s %q", d.param, v)
+		}
+		*d.dest = t
+	}
+
+	if v := values.Get("min_reopen_count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return q, fmt.Errorf("invalid min_reopen_count %q", v)
+		}
+		q.MinReopenCount = n
+	}
+
+	if v := values.Get("q"); v != "" {
+		filter, err := bugquery.Parse(v, time.Now())
+		if err != nil {
+			return q, fmt.Errorf("invalid query: %w", err)
+		}
+		q.Filter = filter
+	}
+
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return q, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		q.Limit = limit
+	}
+
+	return q, q.Validate()
+}
+
+// writeQueryError writes a 400 response for an invalid GET /bugs query,
+// adding the column and text of the offending token when the q parameter
+// failed to parse.
+func writeQueryError(w http.ResponseWriter, err error) {
+	var qerr *bugquery.Error
+	if !errors.As(err, &qerr) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"error":  err.Error(),
+		"column": qerr.Column,
+		"token":  qerr.Token,
+	})
+}
+
+func splitListParam(values []string) []string {
+	var items []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+func parseDateParam(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
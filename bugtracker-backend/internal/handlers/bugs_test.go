@@ -7,26 +7,17 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"net/url"
 	"strconv"
 	"testing"
 
-	"bugtracker-backend/internal/testutil"
-
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestCreateBug(t *testing.T) {
-	os.Setenv("DB_PATH", testutil.GetTestDBPath())
-	defer testutil.CleanupTestDB()
-
-	err := db.Init()
-	assert.NoError(t, err)
-	defer func() {
-		db.CleanupTestDB()
-		db.Cleanup()
-	}()
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
 
 	tests := []struct {
 		name           string
@@ -49,9 +40,18 @@ func TestCreateBug(t *testing.T) {
 			payload: models.CreateBugRequest{
 				Description: "Test Description",
 			},
-			expectedStatus: http.StatusBadRequest,
+			expectedStatus: http.StatusUnprocessableEntity,
 			expectedError:  "title is required",
 		},
+		{
+			name: "Invalid bug - unknown priority",
+			payload: models.CreateBugRequest{
+				Title:    "Test Bug",
+				Priority: "urgent",
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  `invalid priority "urgent"`,
+		},
 		{
 			name:           "Invalid JSON",
 			payload:        `{"invalid": json}`,
@@ -73,12 +73,12 @@ func TestCreateBug(t *testing.T) {
 			req := httptest.NewRequest("POST", "/api/bugs", &body)
 			w := httptest.NewRecorder()
 
-			CreateBug(w, req)
+			h.CreateBug(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Code)
 
 			if tt.expectedError != "" {
-				var resp map[string]string
+				var resp map[string]interface{}
 				err := json.NewDecoder(w.Body).Decode(&resp)
 				assert.NoError(t, err)
 				assert.Contains(t, resp["error"], tt.expectedError)
@@ -93,16 +93,57 @@ func TestCreateBug(t *testing.T) {
 	}
 }
 
-func TestGetBug(t *testing.T) {
-	os.Setenv("DB_PATH", testutil.GetTestDBPath())
-	defer testutil.CleanupTestDB()
+func TestCreateBugValidation(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
 
-	err := db.Init()
-	assert.NoError(t, err)
-	defer func() {
-		db.CleanupTestDB()
-		db.Cleanup()
-	}()
+	create := func(payload string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		h.CreateBug(w, httptest.NewRequest("POST", "/api/bugs", bytes.NewBufferString(payload)))
+		var resp map[string]interface{}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return w, resp
+	}
+
+	t.Run("Defaults", func(t *testing.T) {
+		w, resp := create(`{"title": "Test Bug"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "Open", resp["status"])
+		assert.Equal(t, models.DefaultPriority, resp["priority"])
+	})
+
+	t.Run("Field errors", func(t *testing.T) {
+		w, resp := create(`{"title": " ", "priority": "urgent", "status": "Done"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"field": "title", "message": "title is required"},
+			map[string]interface{}{"field": "priority", "message": `invalid priority "urgent": must be one of Low, Medium, High`},
+			map[string]interface{}{"field": "status", "message": `invalid status "Done"`},
+		}, resp["fields"])
+	})
+
+	t.Run("Initial status only", func(t *testing.T) {
+		w, resp := create(`{"title": "Test Bug", "status": "Closed", "resolution": "Duplicate"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"field": "status", "message": `new bugs start as "Open"`},
+		}, resp["fields"])
+
+		w, resp = create(`{"title": "Test Bug", "resolution": "Duplicate"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"field": "resolution", "message": "resolution is only allowed for resolved statuses"},
+		}, resp["fields"])
+
+		w, resp = create(`{"title": "Test Bug", "status": "Open"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "Open", resp["status"])
+	})
+}
+
+func TestGetBug(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
 
 	// Create a test bug first
 	bug := &models.Bug{
@@ -111,7 +152,7 @@ func TestGetBug(t *testing.T) {
 		Priority:    "High",
 		Status:      "Open",
 	}
-	err = db.CreateBug(bug)
+	err := store.CreateBug(bug)
 	assert.NoError(t, err)
 
 	tests := []struct {
@@ -146,7 +187,7 @@ func TestGetBug(t *testing.T) {
 
 			// Set up router to handle URL parameters
 			router := mux.NewRouter()
-			router.HandleFunc("/api/bugs/{id}", GetBug)
+			router.HandleFunc("/api/bugs/{id}", h.GetBug)
 			router.ServeHTTP(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Code)
@@ -167,15 +208,8 @@ func TestGetBug(t *testing.T) {
 }
 
 func TestUpdateBug(t *testing.T) {
-	os.Setenv("DB_PATH", testutil.GetTestDBPath())
-	defer testutil.CleanupTestDB()
-
-	err := db.Init()
-	assert.NoError(t, err)
-	defer func() {
-		db.CleanupTestDB()
-		db.Cleanup()
-	}()
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
 
 	bug := &models.Bug{
 		Title:       "Original Title",
@@ -183,7 +217,9 @@ func TestUpdateBug(t *testing.T) {
 		Priority:    "Low",
 		Status:      "Open",
 	}
-	err = db.CreateBug(bug)
+	err := store.CreateBug(bug)
+	assert.NoError(t, err)
+	err = store.CreateBug(&models.Bug{Title: "Closed Bug", Status: "Closed"})
 	assert.NoError(t, err)
 
 	tests := []struct {
@@ -214,6 +250,45 @@ func TestUpdateBug(t *testing.T) {
 			expectedStatus: http.StatusNotFound,
 			expectedError:  "bug not found",
 		},
+		{
+			name:  "Unknown status",
+			bugID: "1",
+			payload: models.CreateBugRequest{
+				Title:  "Updated Title",
+				Status: "Done",
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  `invalid status "Done"`,
+		},
+		{
+			name:  "Unknown priority",
+			bugID: "1",
+			payload: models.CreateBugRequest{
+				Title:    "Updated Title",
+				Priority: "urgent",
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  `invalid priority "urgent"`,
+		},
+		{
+			name:  "Missing title",
+			bugID: "1",
+			payload: models.CreateBugRequest{
+				Priority: "High",
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "title is required",
+		},
+		{
+			name:  "Transition not allowed",
+			bugID: "2",
+			payload: models.CreateBugRequest{
+				Title:  "Closed Bug",
+				Status: "In Progress",
+			},
+			expectedStatus: http.StatusConflict,
+			expectedError:  `cannot move bug from "Closed" to "In Progress"`,
+		},
 		{
 			name:           "Invalid bug ID",
 			bugID:          "invalid",
@@ -240,17 +315,17 @@ func TestUpdateBug(t *testing.T) {
 				assert.NoError(t, err)
 			}
 
-			req := httptest.NewRequest("PUT", "/api/bugs/"+tt.bugID, &body)
+			req := withUser(httptest.NewRequest("PUT", "/api/bugs/"+tt.bugID, &body), "alice")
 			w := httptest.NewRecorder()
 
 			router := mux.NewRouter()
-			router.HandleFunc("/api/bugs/{id}", UpdateBug)
+			router.HandleFunc("/api/bugs/{id}", h.UpdateBug)
 			router.ServeHTTP(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Code)
 
 			if tt.expectedError != "" {
-				var resp map[string]string
+				var resp map[string]interface{}
 				err := json.NewDecoder(w.Body).Decode(&resp)
 				assert.NoError(t, err)
 				assert.Contains(t, resp["error"], tt.expectedError)
@@ -268,22 +343,327 @@ func TestUpdateBug(t *testing.T) {
 	}
 }
 
-func TestDeleteBug(t *testing.T) {
-	os.Setenv("DB_PATH", testutil.GetTestDBPath())
-	defer testutil.CleanupTestDB()
+func TestUpdateBugResolution(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	assert.NoError(t, store.CreateBug(&models.Bug{Title: "Crash", Status: "Open", Priority: "High"}))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/bugs/{id}", h.UpdateBug)
+
+	put := func(payload models.CreateBugRequest) (*httptest.ResponseRecorder, map[string]interface{}) {
+		var body bytes.Buffer
+		assert.NoError(t, json.NewEncoder(&body).Encode(payload))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, withUser(httptest.NewRequest("PUT", "/api/bugs/1", &body), "alice"))
+		var resp map[string]interface{}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return w, resp
+	}
+
+	w, resp := put(models.CreateBugRequest{Title: "Crash", Status: "Resolved"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, resp["error"], "resolution is required")
+
+	w, resp = put(models.CreateBugRequest{Title: "Crash", Status: "Resolved", Resolution: "Fixed Somehow"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, resp["error"], "invalid resolution")
 
-	err := db.Init()
+	w, resp = put(models.CreateBugRequest{Title: "Crash", Status: "Resolved", Resolution: models.ResolutionFixed})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.ResolutionFixed, resp["resolution"])
+	assert.NotEmpty(t, resp["resolved_at"])
+
+	w, resp = put(models.CreateBugRequest{Title: "Crash", Status: "Open"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, resp, "resolution")
+	assert.NotContains(t, resp, "resolved_at")
+	assert.Equal(t, float64(1), resp["reopen_count"])
+
+	bugs, _, err := store.ListBugs(db.BugQuery{MinReopenCount: 1})
 	assert.NoError(t, err)
-	defer func() {
-		db.CleanupTestDB()
-		db.Cleanup()
-	}()
+	assert.Len(t, bugs, 1)
+}
+
+func TestPatchBug(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	assert.NoError(t, store.CreateBug(&models.Bug{Title: "Crash", Description: "On login", Status: "Open", Priority: "High"}))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/bugs/{id}", h.PatchBug)
+
+	patchBug := func(contentType, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := withUser(httptest.NewRequest("PATCH", "/api/bugs/1", bytes.NewBufferString(body)), "alice")
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return w, resp
+	}
+
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		expectedStatus int
+		expected       map[string]interface{}
+		expectedError  string
+	}{
+		{
+			name:           "merge patch changes only the given field",
+			contentType:    "application/merge-patch+json",
+			body:           `{"priority": "Low"}`,
+			expectedStatus: http.StatusOK,
+			expected:       map[string]interface{}{"title": "Crash", "description": "On login", "status": "Open", "priority": "Low"},
+		},
+		{
+			name:           "plain JSON is a merge patch",
+			contentType:    "application/json; charset=utf-8",
+			body:           `{"status": "Resolved", "resolution": "Fixed"}`,
+			expectedStatus: http.StatusOK,
+			expected:       map[string]interface{}{"status": "Resolved", "resolution": "Fixed", "priority": "Low"},
+		},
+		{
+			name:           "changing only the status reopens",
+			body:           `{"status": "Open"}`,
+			expectedStatus: http.StatusOK,
+			expected:       map[string]interface{}{"status": "Open", "reopen_count": float64(1)},
+		},
+		{
+			name:           "null clears the description",
+			contentType:    "application/merge-patch+json",
+			body:           `{"description": null}`,
+			expectedStatus: http.StatusOK,
+			expected:       map[string]interface{}{"title": "Crash", "description": ""},
+		},
+		{
+			name:           "JSON patch",
+			contentType:    "application/json-patch+json",
+			body:           `[{"op": "test", "path": "/status", "value": "Open"}, {"op": "replace", "path": "/title", "value": "Crash on login"}]`,
+			expectedStatus: http.StatusOK,
+			expected:       map[string]interface{}{"title": "Crash on login", "status": "Open"},
+		},
+		{
+			name:           "JSON patch test failure",
+			contentType:    "application/json-patch+json",
+			body:           `[{"op": "test", "path": "/status", "value": "Closed"}, {"op": "replace", "path": "/title", "value": "x"}]`,
+			expectedStatus: http.StatusConflict,
+			expectedError:  "patch test failed",
+		},
+		{
+			name:           "merged result is validated",
+			contentType:    "application/merge-patch+json",
+			body:           `{"title": null, "priority": "urgent"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  `title is required; invalid priority "urgent"`,
+		},
+		{
+			name:           "closing requires the workflow resolution",
+			body:           `{"status": "Closed", "resolution": "Fixed"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "merge patch cannot remove the resolution of a resolved bug",
+			contentType:    "application/merge-patch+json",
+			body:           `{"resolution": null}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "resolution cannot be removed from a resolved bug",
+		},
+		{
+			name:           "JSON patch cannot remove the resolution of a resolved bug",
+			contentType:    "application/json-patch+json",
+			body:           `[{"op": "remove", "path": "/resolution"}]`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "resolution cannot be removed from a resolved bug",
+		},
+		{
+			name:           "transition not allowed",
+			body:           `{"status": "In Progress"}`,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "read-only field",
+			body:           `{"id": 7, "created_at": null}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "id cannot be changed",
+		},
+		{
+			name:           "status cannot be removed",
+			body:           `{"status": null}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "status cannot be removed",
+		},
+		{
+			name:           "wrong type",
+			body:           `{"title": 5}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid patch",
+		},
+		{
+			name:           "not an object",
+			body:           `["title"]`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid patch",
+		},
+		{
+			name:           "unsupported content type",
+			contentType:    "text/plain",
+			body:           `title=x`,
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedError:  `unsupported patch type "text/plain"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, resp := patchBug(tt.contentType, tt.body)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			for k, v := range tt.expected {
+				assert.Equal(t, v, resp[k], k)
+			}
+			if tt.expectedError != "" {
+				assert.Contains(t, resp["error"], tt.expectedError)
+			}
+		})
+	}
+
+	req := httptest.NewRequest("PATCH", "/api/bugs/999", bytes.NewBufferString(`{}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestConditionalRequests(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	assert.NoError(t, store.CreateBug(&models.Bug{Title: "Crash", Status: "Open", Priority: "High"}))
+
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	do := func(method, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/bugs/1", bytes.NewBufferString(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("GET", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"1.0"`, w.Header().Get("ETag"))
+
+	w = do("GET", "", map[string]string{"If-None-Match": `"1.0"`})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, `"1.0"`, w.Header().Get("ETag"))
+
+	w = do("GET", "", map[string]string{"If-None-Match": `W/"1.0"`})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = do("PUT", `{"title": "Crash on save"}`, map[string]string{"If-Match": `"1.0"`})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2.0"`, w.Header().Get("ETag"))
+
+	// A second client still holding version 1 must not overwrite the edit.
+	w = do("PUT", `{"title": "Crash"}`, map[string]string{"If-Match": `"1.0"`})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = do("PATCH", `{"priority": "Low"}`, map[string]string{"If-Match": `"1.0"`})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = do("PATCH", `{"priority": "Low"}`, map[string]string{"If-Match": `W/"2.0"`})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = do("GET", "", map[string]string{"If-None-Match": `"1.0"`})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var bug models.Bug
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&bug))
+	assert.Equal(t, "Crash on save", bug.Title)
+	assert.Equal(t, "High", bug.Priority)
+	assert.Equal(t, 2, bug.Version)
+
+	w = do("PATCH", `{"priority": "Low"}`, map[string]string{"If-Match": `"1.0", "2.0"`})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3.0"`, w.Header().Get("ETag"))
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&bug))
+	updatedAt := bug.UpdatedAt
+
+	// An update that changes nothing keeps the version, so other clients'
+	// ETags still match.
+	w = do("PATCH", `{"priority": "Low"}`, map[string]string{"If-Match": `"3.0"`})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3.0"`, w.Header().Get("ETag"))
+	w = do("PUT", `{"title": "Crash on save", "priority": "Low"}`, map[string]string{"If-Match": `"3.0"`})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3.0"`, w.Header().Get("ETag"))
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&bug))
+	assert.Equal(t, 3, bug.Version)
+	assert.True(t, updatedAt.Equal(bug.UpdatedAt))
+	stored, err := store.GetBug(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stored.Version)
+	history, err := store.GetHistory(1)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+
+	// Comments do not change the version, but they do change the bug's
+	// representation and so its ETag.
+	assert.NoError(t, store.CreateComment(1, &models.Comment{Author: "alice", Content: "Seen it too"}))
+	w = do("GET", "", map[string]string{"If-None-Match": `"3.0"`})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3.1"`, w.Header().Get("ETag"))
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&bug))
+	assert.Equal(t, 1, bug.CommentCount)
+	assert.Equal(t, http.StatusNotModified, do("GET", "", map[string]string{"If-None-Match": `"3.1"`}).Code)
+
+	w = do("DELETE", "", map[string]string{"If-Match": `"3.0"`})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = do("DELETE", "", map[string]string{"If-Match": "*"})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestGetTransitions(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	assert.NoError(t, store.CreateBug(&models.Bug{Title: "Resolved Bug", Status: "Resolved"}))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/bugs/{id}/transitions", h.GetTransitions)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/bugs/1/transitions", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"status": "Resolved",
+		"transitions": [
+			{"status": "Open", "resolved": false},
+			{"status": "Closed", "resolved": true}
+		]
+	}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/bugs/999/transitions", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeleteBug(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
 
 	bug := &models.Bug{
 		Title:       "Test Bug",
 		Description: "Test Description",
 	}
-	err = db.CreateBug(bug)
+	err := store.CreateBug(bug)
 	assert.NoError(t, err)
 
 	tests := []struct {
@@ -317,7 +697,7 @@ func TestDeleteBug(t *testing.T) {
 			w := httptest.NewRecorder()
 
 			router := mux.NewRouter()
-			router.HandleFunc("/api/bugs/{id}", DeleteBug)
+			router.HandleFunc("/api/bugs/{id}", h.DeleteBug)
 			router.ServeHTTP(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Code)
@@ -331,10 +711,292 @@ func TestDeleteBug(t *testing.T) {
 
 			if tt.expectedStatus == http.StatusNoContent {
 				idInt, _ := strconv.Atoi(tt.bugID)
-				_, err := db.GetBug(idInt)
+				_, err := store.GetBug(idInt)
 				assert.Error(t, err)
 				assert.Contains(t, err.Error(), "bug not found")
 			}
 		})
 	}
 }
+
+func TestDeleteAllBugsHidesStoreErrors(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+	assert.NoError(t, store.CreateBug(&models.Bug{Title: "Crash"}))
+	assert.NoError(t, store.Close())
+
+	w := httptest.NewRecorder()
+	h.DeleteAllBugs(w, httptest.NewRequest("DELETE", "/api/bugs", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	var resp map[string]string
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "internal server error", resp["error"])
+}
+
+func TestTrashAndRestore(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	for _, title := range []string{"Crash", "Typo", "Slow"} {
+		assert.NoError(t, store.CreateBug(&models.Bug{Title: title, Status: "Open", Priority: "Low"}))
+	}
+
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req = withUser(req, "alice")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	listTrash := func() []models.Bug {
+		w := do("GET", "/trash")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var bugs []models.Bug
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&bugs))
+		return bugs
+	}
+
+	assert.Empty(t, listTrash())
+
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/bugs/1").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/bugs/1").Code)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", "/bugs/1").Code)
+
+	trash := listTrash()
+	if assert.Len(t, trash, 1) {
+		assert.Equal(t, 1, trash[0].ID)
+		assert.Equal(t, "alice", trash[0].DeletedBy)
+		assert.NotNil(t, trash[0].DeletedAt)
+	}
+
+	w := do("POST", "/trash/1/restore")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var restored map[string]interface{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&restored))
+	assert.NotContains(t, restored, "deleted_at")
+	assert.NotContains(t, restored, "deleted_by")
+	assert.Equal(t, http.StatusOK, do("GET", "/bugs/1").Code)
+	assert.Equal(t, http.StatusNotFound, do("POST", "/trash/1/restore").Code)
+
+	w = do("DELETE", "/bugs")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"deleted": 3}`, w.Body.String())
+	assert.Len(t, listTrash(), 3)
+
+	w = do("GET", "/trash?sort=title&limit=2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("X-Next-Cursor"))
+
+	bugs, _, err := store.ListBugs(db.BugQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, bugs)
+}
+
+func TestGetBugs(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	for _, b := range []models.Bug{
+		{Title: "First", Status: "Open", Priority: "High"},
+		{Title: "Second", Status: "Closed", Priority: "Low"},
+		{Title: "Third", Status: "Open", Priority: "Low"},
+	} {
+		b := b
+		assert.NoError(t, store.CreateBug(&b))
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedIDs    []int
+		expectNext     bool
+		expectedError  string
+	}{
+		{
+			name:           "All bugs",
+			query:          "",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int{1, 2, 3},
+		},
+		{
+			name:           "Filter by status and priority",
+			query:          "?status=Open&priority=Low,High&sort=priority&order=desc",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int{1, 3},
+		},
+		{
+			name:           "First page",
+			query:          "?limit=2",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int{1, 2},
+			expectNext:     true,
+		},
+		{
+			name:           "Invalid sort field",
+			query:          "?sort=severity",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid sort field",
+		},
+		{
+			name:           "Invalid date",
+			query:          "?created_after=yesterday",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid created_after",
+		},
+		{
+			name:           "Invalid limit",
+			query:          "?limit=0",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "limit must be between",
+		},
+		{
+			name:           "Query language",
+			query:          "?q=" + url.QueryEscape(`status:Open OR title:"second"`),
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int{1, 2, 3},
+		},
+		{
+			name:           "Query language combined with filters",
+			query:          "?q=-first&status=Open",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int{3},
+		},
+		{
+			name:           "Invalid query language",
+			query:          "?q=" + url.QueryEscape("status:Open severity:High"),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  `invalid query: unknown field "severity" at column 13`,
+		},
+		{
+			name:           "Invalid cursor",
+			query:          "?cursor=garbage",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid cursor",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/bugs"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			h.GetBugs(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedError != "" {
+				var resp map[string]interface{}
+				err := json.NewDecoder(w.Body).Decode(&resp)
+				assert.NoError(t, err)
+				assert.Contains(t, resp["error"], tt.expectedError)
+				return
+			}
+
+			var bugs []models.Bug
+			err := json.NewDecoder(w.Body).Decode(&bugs)
+			assert.NoError(t, err)
+			var ids []int
+			for _, bug := range bugs {
+				ids = append(ids, bug.ID)
+			}
+			assert.Equal(t, tt.expectedIDs, ids)
+			assert.Equal(t, tt.expectNext, w.Header().Get("X-Next-Cursor") != "")
+		})
+	}
+
+	t.Run("Query error points at token", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.GetBugs(w, httptest.NewRequest("GET", "/api/bugs?q="+url.QueryEscape("login (status:Open"), nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var resp struct {
+			Error  string `json:"error"`
+			Column int    `json:"column"`
+			Token  string `json:"token"`
+		}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, "invalid query: missing closing parenthesis at column 7 (\"(\")", resp.Error)
+		assert.Equal(t, 7, resp.Column)
+		assert.Equal(t, "(", resp.Token)
+	})
+
+	t.Run("Follow next cursor", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.GetBugs(w, httptest.NewRequest("GET", "/api/bugs?limit=2", nil))
+		next := w.Header().Get("X-Next-Cursor")
+		assert.NotEmpty(t, next)
+
+		w = httptest.NewRecorder()
+		h.GetBugs(w, httptest.NewRequest("GET", "/api/bugs?limit=2&cursor="+next, nil))
+		var bugs []models.Bug
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&bugs))
+		assert.Len(t, bugs, 1)
+		assert.Equal(t, 3, bugs[0].ID)
+		assert.Empty(t, w.Header().Get("X-Next-Cursor"))
+	})
+
+	t.Run("No matches", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.GetBugs(w, httptest.NewRequest("GET", "/api/bugs?status=In+Progress", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[]`, w.Body.String())
+	})
+}
+
+func TestGetBugsDefaultPageSize(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	for i := 0; i < defaultPageSize+1; i++ {
+		assert.NoError(t, store.CreateBug(&models.Bug{Title: "Bug", Status: "Open", Priority: "Low"}))
+	}
+
+	w := httptest.NewRecorder()
+	h.GetBugs(w, httptest.NewRequest("GET", "/api/bugs", nil))
+	var bugs []models.Bug
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&bugs))
+	assert.Len(t, bugs, defaultPageSize)
+	next := w.Header().Get("X-Next-Cursor")
+	assert.NotEmpty(t, next)
+
+	w = httptest.NewRecorder()
+	h.GetBugs(w, httptest.NewRequest("GET", "/api/bugs?cursor="+next, nil))
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&bugs))
+	assert.Len(t, bugs, 1)
+	assert.Empty(t, w.Header().Get("X-Next-Cursor"))
+
+	w = httptest.NewRecorder()
+	h.GetBugs(w, httptest.NewRequest("GET", "/api/bugs?limit=501", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMarkdownIsReturnedRawAndRendered(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	do := func(method, path, body string) map[string]interface{} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, withUser(httptest.NewRequest(method, path, bytes.NewBufferString(body)), "alice"))
+		var resp map[string]interface{}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return resp
+	}
+
+	bug := do("POST", "/bugs", `{"title": "Crash", "description": "Fails on **save** <script>alert(1)</script>"}`)
+	assert.Equal(t, "Fails on **save** <script>alert(1)</script>", bug["description"])
+	assert.Equal(t, "<p>Fails on <strong>save</strong> alert(1)</p>\n", bug["description_html"])
+
+	bug = do("PATCH", "/bugs/1", `{"description": "[docs](javascript:alert(1))"}`)
+	assert.Equal(t, "<p>docs</p>\n", bug["description_html"])
+
+	comment := do("POST", "/bugs/1/comments", `{"content": "Seen it _too_"}`)
+	assert.Equal(t, "Seen it _too_", comment["content"])
+	assert.Equal(t, "<p>Seen it <em>too</em></p>\n", comment["contentHtml"])
+}
@@ -2,105 +2,348 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"bugtracker-backend/internal/db"
 	"bugtracker-backend/internal/models"
+	"bugtracker-backend/internal/rbac"
 
 	"github.com/gorilla/mux"
 )
 
-func RegisterCommentRoutes(r *mux.Router) {
-	r.HandleFunc("/bugs/{id}/comments", GetComments).Methods("GET")
-	r.HandleFunc("/bugs/{id}/comments", CreateComment).Methods("POST")
+func (h *Handler) registerCommentRoutes(r *mux.Router) {
+	r.HandleFunc("/bugs/{id}/comments", h.GetComments).Methods("GET").Name("GetComments")
+	r.HandleFunc("/bugs/{id}/comments", h.CreateComment).Methods("POST").Name("CreateComment")
+	r.HandleFunc("/bugs/{id}/comments/{commentId}", h.GetComment).Methods("GET").Name("GetComment")
+	r.HandleFunc("/bugs/{id}/comments/{commentId}", h.UpdateComment).Methods("PUT", "PATCH").Name("UpdateComment")
+	r.HandleFunc("/bugs/{id}/comments/{commentId}", h.DeleteComment).Methods("DELETE").Name("DeleteComment")
+	r.HandleFunc("/bugs/{id}/comments/{commentId}/restore", h.RestoreComment).Methods("POST").Name("RestoreComment")
+	r.HandleFunc("/bugs/{id}/comments/{commentId}/revisions", h.GetCommentRevisions).Methods("GET").Name("GetCommentRevisions")
 }
 
-func GetComments(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+// errCommentForbidden is returned when someone other than a comment's
+// author or an admin tries to change it.
+var errCommentForbidden = errors.New("only the comment's author or an admin may change it")
 
-	log.Printf("Getting comments for bug %s", id)
+// mayChangeComment reports whether the request's actor may edit or delete
+// comment.
+func (h *Handler) mayChangeComment(r *http.Request, comment *models.Comment) bool {
+	actor := requestActor(r)
+	return actor != "" && (actor == comment.Author || h.authorize(r, rbac.ModerateComments) == nil)
+}
 
-	if _, err := strconv.Atoi(id); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "invalid bug ID format",
-		})
+// commentFromRequest parses the bug and comment IDs of a comment route.
+func (h *Handler) commentFromRequest(w http.ResponseWriter, r *http.Request) (bugID, commentID int, ok bool) {
+	bugID, ok = h.bugIDFromRequest(w, r)
+	if !ok {
+		return 0, 0, false
+	}
+	commentID, err := strconv.Atoi(mux.Vars(r)["commentId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid comment ID")
+		return 0, 0, false
+	}
+	return bugID, commentID, true
+}
+
+// GetComments lists a bug's comments, oldest first unless order=desc, a page
+// of defaultPageSize unless limit says otherwise. The X-Next-Cursor header
+// holds the cursor for the next page. With view=tree the whole discussion is
+// returned as threads of replies instead.
+func (h *Handler) GetComments(w http.ResponseWriter, r *http.Request) {
+	bugID, ok := h.bugIDFromRequest(w, r)
+	if !ok {
 		return
 	}
 
-	comments, err := db.GetComments(id)
+	log.Printf("Getting comments for bug %d", bugID)
+
+	query, err := parseCommentQuery(r.URL.Query())
 	if err != nil {
-		status := http.StatusInternalServerError
-		if err.Error() == "bug not found" {
-			status = http.StatusNotFound
-		} else if err.Error() == "invalid bug ID format" {
-			status = http.StatusBadRequest
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tree := false
+	switch view := r.URL.Query().Get("view"); view {
+	case "", "flat":
+		if query.Limit == 0 {
+			query.Limit = defaultPageSize
 		}
-		w.WriteHeader(status)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
-		})
+	case "tree":
+		if query.Limit != 0 || query.Cursor != "" {
+			writeError(w, http.StatusBadRequest, "the tree view cannot be paginated")
+			return
+		}
+		tree = true
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid view %q", view))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(comments)
+	comments, next, err := h.store.ListComments(bugID, query)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	for i := range comments {
+		comments[i].HideDeletedContent()
+	}
+
+	if tree {
+		writeJSON(w, http.StatusOK, models.CommentTree(comments))
+		return
+	}
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
+	if comments == nil {
+		comments = []models.Comment{}
+	}
+	writeJSON(w, http.StatusOK, comments)
 }
 
-func CreateComment(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+// parseCommentQuery builds a storage query from the GET
+// /bugs/{id}/comments query string.
+func parseCommentQuery(values url.Values) (db.CommentQuery, error) {
+	q := db.CommentQuery{Cursor: values.Get("cursor")}
 
-	log.Printf("Creating comment for bug %s", id)
+	switch order := values.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		q.Descending = true
+	default:
+		return q, fmt.Errorf("invalid order %q", order)
+	}
 
-	if _, err := strconv.Atoi(id); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "invalid bug ID format",
-		})
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return q, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		q.Limit = limit
+	}
+
+	return q, q.Validate()
+}
+
+func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	bugID, ok := h.bugIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	log.Printf("Creating comment for bug %d", bugID)
+
+	author := requestActor(r)
+	if author == "" {
+		writeUnauthorized(w, "authentication required")
 		return
 	}
 
 	var req models.CreateCommentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "invalid request body",
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	comment := &models.Comment{
+		Content:  req.Content,
+		Author:   author,
+		ParentID: req.ParentID,
+	}
+
+	err := h.store.Update(func(tx db.Tx) error {
+		if err := tx.CreateComment(bugID, comment); err != nil {
+			return err
+		}
+		return tx.AddHistory(&models.HistoryEntry{
+			BugID:     bugID,
+			Action:    models.HistoryCommented,
+			Actor:     author,
+			At:        comment.CreatedAt,
+			CommentID: comment.ID,
 		})
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, comment)
+}
+
+func (h *Handler) GetComment(w http.ResponseWriter, r *http.Request) {
+	bugID, commentID, ok := h.commentFromRequest(w, r)
+	if !ok {
 		return
 	}
 
+	comment, err := h.store.GetComment(bugID, commentID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	comment.HideDeletedContent()
+	writeJSON(w, http.StatusOK, comment)
+}
+
+// UpdateComment replaces a comment's content. PUT and PATCH behave the same,
+// since the content is the only field that can change.
+func (h *Handler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	bugID, commentID, ok := h.commentFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
 	if err := req.Validate(); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var comment *models.Comment
+	err := h.store.Update(func(tx db.Tx) error {
+		old, err := tx.GetComment(bugID, commentID)
+		if err != nil {
+			return err
+		}
+		if !h.mayChangeComment(r, old) {
+			return errCommentForbidden
+		}
+		now := time.Now()
+		comment, err = tx.EditComment(bugID, commentID, req.Content, now)
+		if err != nil || comment.Content == old.Content {
+			return err
+		}
+		return tx.AddHistory(&models.HistoryEntry{
+			BugID:     bugID,
+			Action:    models.HistoryCommentEdited,
+			Actor:     requestActor(r),
+			At:        now,
+			CommentID: commentID,
 		})
+	})
+	if err != nil {
+		writeStoreError(w, err)
 		return
 	}
 
-	comment := &models.Comment{
-		Content: req.Content,
-		Author:  req.Author,
+	writeJSON(w, http.StatusOK, comment)
+}
+
+// DeleteComment replaces a comment with a tombstone until it is restored.
+// Deleting it again succeeds without changing anything.
+func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	bugID, commentID, ok := h.commentFromRequest(w, r)
+	if !ok {
+		return
 	}
 
-	if err := db.CreateComment(id, comment); err != nil {
-		status := http.StatusInternalServerError
-		if err.Error() == "bug not found" {
-			status = http.StatusNotFound
-		} else if err.Error() == "invalid bug ID format" {
-			status = http.StatusBadRequest
+	err := h.store.Update(func(tx db.Tx) error {
+		old, err := tx.GetComment(bugID, commentID)
+		if err != nil {
+			return err
+		}
+		if !h.mayChangeComment(r, old) {
+			return errCommentForbidden
+		}
+		if old.DeletedAt != nil {
+			return nil
+		}
+		now := time.Now()
+		if _, err := tx.DeleteComment(bugID, commentID, requestActor(r), now); err != nil {
+			return err
 		}
-		w.WriteHeader(status)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
+		return tx.AddHistory(&models.HistoryEntry{
+			BugID:     bugID,
+			Action:    models.HistoryCommentDeleted,
+			Actor:     requestActor(r),
+			At:        now,
+			CommentID: commentID,
 		})
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreComment brings back a deleted comment, with the same permissions
+// as deleting it. Restoring it again succeeds without changing anything.
+func (h *Handler) RestoreComment(w http.ResponseWriter, r *http.Request) {
+	bugID, commentID, ok := h.commentFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var comment *models.Comment
+	err := h.store.Update(func(tx db.Tx) error {
+		old, err := tx.GetComment(bugID, commentID)
+		if err != nil {
+			return err
+		}
+		if !h.mayChangeComment(r, old) {
+			return errCommentForbidden
+		}
+		if comment, err = tx.RestoreComment(bugID, commentID); err != nil || old.DeletedAt == nil {
+			return err
+		}
+		return tx.AddHistory(&models.HistoryEntry{
+			BugID:     bugID,
+			Action:    models.HistoryCommentRestored,
+			Actor:     requestActor(r),
+			At:        time.Now(),
+			CommentID: commentID,
+		})
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, comment)
+}
+
+// GetCommentRevisions lists the earlier contents of an edited comment,
+// oldest first. Those of a deleted comment are not shown.
+func (h *Handler) GetCommentRevisions(w http.ResponseWriter, r *http.Request) {
+	bugID, commentID, ok := h.commentFromRequest(w, r)
+	if !ok {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(comment)
+	var revs []models.CommentRevision
+	err := h.store.View(func(tx db.Tx) error {
+		comment, err := tx.GetComment(bugID, commentID)
+		if err != nil || comment.DeletedAt != nil {
+			return err
+		}
+		revs, err = tx.GetCommentRevisions(bugID, commentID)
+		return err
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if revs == nil {
+		revs = []models.CommentRevision{}
+	}
+	writeJSON(w, http.StatusOK, revs)
 }
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"bugtracker-backend/internal/db"
 	"bugtracker-backend/internal/models"
 	"bytes"
 	"encoding/json"
@@ -16,19 +15,20 @@ import (
 )
 
 func TestCreateComment(t *testing.T) {
-	cleanup := setupTestDB(t)
+	h, store, cleanup := setupTestHandler(t)
 	defer cleanup()
 
 	bug := &models.Bug{
 		Title:       "Test Bug",
 		Description: "Test Description",
 	}
-	err := db.CreateBug(bug)
+	err := store.CreateBug(bug)
 	assert.NoError(t, err)
 
 	tests := []struct {
 		name           string
 		bugID          string
+		user           string
 		requestBody    interface{}
 		expectedStatus int
 		expectedError  string
@@ -36,34 +36,53 @@ func TestCreateComment(t *testing.T) {
 		{
 			name:  "Valid comment creation",
 			bugID: strconv.Itoa(bug.ID),
+			user:  "tester",
 			requestBody: models.CreateCommentRequest{
 				Content: "Test Comment",
-				Author:  "Test User",
 			},
 			expectedStatus: http.StatusCreated,
 		},
+		{
+			name:  "Author in the body is ignored",
+			bugID: strconv.Itoa(bug.ID),
+			user:  "tester",
+			requestBody: map[string]string{
+				"content": "Test Comment",
+				"author":  "someone-else",
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:  "Anonymous",
+			bugID: strconv.Itoa(bug.ID),
+			requestBody: models.CreateCommentRequest{
+				Content: "Test Comment",
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "authentication required",
+		},
 		{
 			name:  "Invalid bug ID",
 			bugID: "999",
+			user:  "tester",
 			requestBody: models.CreateCommentRequest{
 				Content: "Test Comment",
-				Author:  "Test User",
 			},
 			expectedStatus: http.StatusNotFound,
 			expectedError: "bug not found",
 		},
 		{
-			name:  "Missing content",
-			bugID: "1",
-			requestBody: models.CreateCommentRequest{
-				Author: "Test User",
-			},
+			name:           "Missing content",
+			bugID:          "1",
+			user:           "tester",
+			requestBody:    models.CreateCommentRequest{},
 			expectedStatus: http.StatusBadRequest,
 			expectedError: "content is required",
 		},
 		{
 			name:           "Invalid JSON",
 			bugID:          "1",
+			user:           "tester",
 			requestBody:    `{"invalid": json}`,
 			expectedStatus: http.StatusBadRequest,
 			expectedError:  "invalid request body",
@@ -81,11 +100,14 @@ func TestCreateComment(t *testing.T) {
 			}
 
 			req := httptest.NewRequest("POST", fmt.Sprintf("/api/bugs/%s/comments", tt.bugID), &body)
+			if tt.user != "" {
+				req = withUser(req, tt.user)
+			}
 			w := httptest.NewRecorder()
 
 			// Set up router to handle URL parameters
 			router := mux.NewRouter()
-			router.HandleFunc("/api/bugs/{id}/comments", CreateComment)
+			router.HandleFunc("/api/bugs/{id}/comments", h.CreateComment)
 			router.ServeHTTP(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Code)
@@ -101,6 +123,7 @@ func TestCreateComment(t *testing.T) {
 				assert.NoError(t, err)
 				assert.NotEmpty(t, comment.ID)
 				assert.NotEmpty(t, comment.CreatedAt)
+				assert.Equal(t, tt.user, comment.Author)
 				expectedBugID, _ := strconv.Atoi(tt.bugID)
 				assert.Equal(t, expectedBugID, comment.BugID)
 			}
@@ -109,14 +132,14 @@ func TestCreateComment(t *testing.T) {
 }
 
 func TestGetComments(t *testing.T) {
-	cleanup := setupTestDB(t)
+	h, store, cleanup := setupTestHandler(t)
 	defer cleanup()
 
 	bug := &models.Bug{
 		Title:       "Test Bug",
 		Description: "Test Description",
 	}
-	err := db.CreateBug(bug)
+	err := store.CreateBug(bug)
 	assert.NoError(t, err)
 
 	testComments := []*models.Comment{
@@ -126,7 +149,7 @@ func TestGetComments(t *testing.T) {
 	}
 
 	for _, comment := range testComments {
-		err := db.CreateComment(strconv.Itoa(bug.ID), comment)
+		err := store.CreateComment(bug.ID, comment)
 		assert.NoError(t, err)
 	}
 
@@ -163,7 +186,7 @@ func TestGetComments(t *testing.T) {
 			w := httptest.NewRecorder()
 
 			router := mux.NewRouter()
-			router.HandleFunc("/api/bugs/{id}/comments", GetComments)
+			router.HandleFunc("/api/bugs/{id}/comments", h.GetComments)
 			router.ServeHTTP(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Code)
@@ -182,3 +205,254 @@ func TestGetComments(t *testing.T) {
 		})
 	}
 }
+
+func TestGetCommentsPaginated(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	bug := &models.Bug{Title: "Test Bug"}
+	assert.NoError(t, store.CreateBug(bug))
+	for i := 1; i <= 5; i++ {
+		assert.NoError(t, store.CreateComment(bug.ID, &models.Comment{Author: "a", Content: fmt.Sprintf("Comment %d", i)}))
+	}
+
+	get := func(query string) ([]string, string, int) {
+		req := httptest.NewRequest("GET", "/bugs/1/comments"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var comments []models.Comment
+		if w.Code == http.StatusOK {
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&comments))
+		}
+		contents := []string{}
+		for _, c := range comments {
+			contents = append(contents, c.Content)
+		}
+		return contents, w.Header().Get("X-Next-Cursor"), w.Code
+	}
+
+	page, next, _ := get("?limit=2")
+	assert.Equal(t, []string{"Comment 1", "Comment 2"}, page)
+	page, next, _ = get("?limit=2&cursor=" + next)
+	assert.Equal(t, []string{"Comment 3", "Comment 4"}, page)
+	page, next, _ = get("?limit=2&cursor=" + next)
+	assert.Equal(t, []string{"Comment 5"}, page)
+	assert.Empty(t, next)
+
+	page, next, _ = get("?order=desc&limit=3")
+	assert.Equal(t, []string{"Comment 5", "Comment 4", "Comment 3"}, page)
+	page, _, _ = get("?order=desc&cursor=" + next)
+	assert.Equal(t, []string{"Comment 2", "Comment 1"}, page)
+
+	// Without a limit the flat view is paged too; the tree view is whole.
+	for i := 6; i <= defaultPageSize+1; i++ {
+		assert.NoError(t, store.CreateComment(bug.ID, &models.Comment{Author: "a", Content: fmt.Sprintf("Comment %d", i)}))
+	}
+	page, next, _ = get("")
+	assert.Len(t, page, defaultPageSize)
+	assert.NotEmpty(t, next)
+	page, next, _ = get("?cursor=" + next)
+	assert.Equal(t, []string{fmt.Sprintf("Comment %d", defaultPageSize+1)}, page)
+	assert.Empty(t, next)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/bugs/1/comments?view=tree", nil))
+	var tree []models.Comment
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&tree))
+	assert.Len(t, tree, defaultPageSize+1)
+
+	// A bug without comments has an empty list, not null.
+	assert.NoError(t, store.CreateBug(&models.Bug{Title: "Quiet Bug"}))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/bugs/2/comments", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+
+	for _, query := range []string{"?limit=0", "?order=sideways", "?cursor=bogus"} {
+		_, _, code := get(query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+
+	// Bug responses include the number of comments.
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/bugs/1", nil))
+	var got models.Bug
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, defaultPageSize+1, got.CommentCount)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/bugs", nil))
+	var bugs []models.Bug
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&bugs))
+	if assert.Len(t, bugs, 2) {
+		assert.Equal(t, defaultPageSize+1, bugs[0].CommentCount)
+		assert.Equal(t, 0, bugs[1].CommentCount)
+	}
+}
+
+func TestEditAndDeleteComment(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+	h.SetAdmins([]string{"root"})
+
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	do := func(method, path, actor, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if actor != "" {
+			req = withUser(req, actor)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.NoError(t, store.CreateBug(&models.Bug{Title: "Crash"}))
+	assert.Equal(t, http.StatusCreated, do("POST", "/bugs/1/comments", "alice", `{"author": "alice", "content": "Typo hree"}`).Code)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		actor          string
+		body           string
+		expectedStatus int
+	}{
+		{"anonymous edit", "PUT", "/bugs/1/comments/1", "", `{"content": "x"}`, http.StatusForbidden},
+		{"edit by someone else", "PATCH", "/bugs/1/comments/1", "bob", `{"content": "x"}`, http.StatusForbidden},
+		{"empty content", "PUT", "/bugs/1/comments/1", "alice", `{"content": ""}`, http.StatusBadRequest},
+		{"unknown comment", "PUT", "/bugs/1/comments/9", "alice", `{"content": "x"}`, http.StatusNotFound},
+		{"invalid comment ID", "PUT", "/bugs/1/comments/abc", "alice", `{"content": "x"}`, http.StatusBadRequest},
+		{"edit by author", "PUT", "/bugs/1/comments/1", "alice", `{"content": "Typo here"}`, http.StatusOK},
+		{"edit by admin", "PATCH", "/bugs/1/comments/1", "root", `{"content": "Typo fixed"}`, http.StatusOK},
+		{"delete by someone else", "DELETE", "/bugs/1/comments/1", "bob", "", http.StatusForbidden},
+		{"delete by author", "DELETE", "/bugs/1/comments/1", "alice", "", http.StatusNoContent},
+		{"delete again", "DELETE", "/bugs/1/comments/1", "alice", "", http.StatusNoContent},
+		{"edit deleted", "PUT", "/bugs/1/comments/1", "alice", `{"content": "back"}`, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(tt.method, tt.path, tt.actor, tt.body)
+			assert.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+		})
+	}
+
+	w := do("GET", "/bugs/1/comments/1", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var comment models.Comment
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&comment))
+	assert.Empty(t, comment.Content)
+	assert.True(t, comment.Edited)
+	assert.NotNil(t, comment.DeletedAt)
+	assert.Equal(t, "alice", comment.DeletedBy)
+
+	w = do("GET", "/bugs/1/comments/1/revisions", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+	w = do("GET", "/bugs/1/comments?view=tree", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "Typo fixed")
+
+	// A deleted comment can be restored, with the same permissions.
+	assert.Equal(t, http.StatusForbidden, do("POST", "/bugs/1/comments/1/restore", "bob", "").Code)
+	w = do("POST", "/bugs/1/comments/1/restore", "root", "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	comment = models.Comment{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&comment))
+	assert.Equal(t, "Typo fixed", comment.Content)
+	assert.Nil(t, comment.DeletedAt)
+	assert.Equal(t, http.StatusOK, do("POST", "/bugs/1/comments/1/restore", "root", "").Code)
+	w = do("GET", "/bugs/1/comments/1/revisions", "", "")
+	assert.Contains(t, w.Body.String(), "Typo hree")
+
+	history, err := store.GetHistory(1)
+	assert.NoError(t, err)
+	actions := []string{}
+	for _, e := range history {
+		actions = append(actions, e.Action+" by "+e.Actor)
+	}
+	assert.Equal(t, []string{"commented by alice", "comment_edited by alice", "comment_edited by root", "comment_deleted by alice", "comment_restored by root"}, actions)
+}
+
+func TestGetCommentRevisions(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	assert.NoError(t, store.CreateBug(&models.Bug{Title: "Crash"}))
+	comment := &models.Comment{Author: "alice", Content: "v1"}
+	assert.NoError(t, store.CreateComment(1, comment))
+
+	for _, content := range []string{"v2", "v3"} {
+		req := httptest.NewRequest("PUT", "/bugs/1/comments/1", bytes.NewBufferString(`{"content": "`+content+`"}`))
+		req = withUser(req, "alice")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/bugs/1/comments/1/revisions", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var revs []models.CommentRevision
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&revs))
+	contents := []string{}
+	for _, rev := range revs {
+		contents = append(contents, rev.Content)
+	}
+	assert.Equal(t, []string{"v1", "v2"}, contents)
+}
+
+func TestCommentThreads(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, withUser(httptest.NewRequest(method, path, bytes.NewBufferString(body)), "a"))
+		return w
+	}
+
+	assert.NoError(t, store.CreateBug(&models.Bug{Title: "Crash"}))
+	assert.NoError(t, store.CreateBug(&models.Bug{Title: "Other"}))
+	assert.Equal(t, http.StatusCreated, do("POST", "/bugs/1/comments", `{"content": "Question"}`).Code)
+	assert.Equal(t, http.StatusCreated, do("POST", "/bugs/1/comments", `{"content": "Answer", "parentId": 1}`).Code)
+	assert.Equal(t, http.StatusCreated, do("POST", "/bugs/1/comments", `{"content": "Unrelated"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, do("POST", "/bugs/2/comments", `{"content": "Wrong bug", "parentId": 1}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/bugs/1/comments", `{"content": "Bad", "parentId": -1}`).Code)
+
+	w := do("GET", "/bugs/1/comments?view=tree", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tree []models.CommentNode
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&tree))
+	if assert.Len(t, tree, 2) {
+		assert.Equal(t, "Question", tree[0].Content)
+		if assert.Len(t, tree[0].Replies, 1) {
+			assert.Equal(t, "Answer", tree[0].Replies[0].Content)
+			assert.Equal(t, 1, tree[0].Replies[0].Depth)
+		}
+		assert.Equal(t, "Unrelated", tree[1].Content)
+		assert.Empty(t, tree[1].Replies)
+	}
+
+	// The flat view lists replies in order with their parent and depth.
+	w = do("GET", "/bugs/1/comments", "")
+	var flat []models.Comment
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&flat))
+	if assert.Len(t, flat, 3) {
+		assert.Equal(t, 1, flat[1].ParentID)
+		assert.Equal(t, 1, flat[1].Depth)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, do("GET", "/bugs/1/comments?view=tree&limit=1", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do("GET", "/bugs/1/comments?view=graph", "").Code)
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"bugtracker-backend/internal/models"
+)
+
+// errPreconditionFailed is returned when an If-Match header does not name
+// the bug's current ETag.
+var errPreconditionFailed = errors.New("bug has been modified since it was read")
+
+// bugETag is the strong entity tag of a bug's current representation: its
+// version and, as comments do not change the version, its comment count.
+func bugETag(bug *models.Bug) string {
+	return `"` + strconv.Itoa(bug.Version) + "." + strconv.Itoa(bug.CommentCount) + `"`
+}
+
+// checkIfMatch fails with errPreconditionFailed when the request has an
+// If-Match header that does not list the bug's ETag.
+func checkIfMatch(r *http.Request, bug *models.Bug) error {
+	header := r.Header.Get("If-Match")
+	if header == "" || etagListMatches(header, bugETag(bug), false) {
+		return nil
+	}
+	return errPreconditionFailed
+}
+
+// notModified reports whether the request's If-None-Match header lists
+// etag, in which case the client's copy is current.
+func notModified(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	return header != "" && etagListMatches(header, etag, true)
+}
+
+// etagListMatches reports whether a comma separated If-Match or
+// If-None-Match header lists etag or is "*". Weak tags (W/"...") only match
+// when weak comparison is allowed, as it is for If-None-Match.
+func etagListMatches(header, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = tag[2:]
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
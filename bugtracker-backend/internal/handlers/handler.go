@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"bugtracker-backend/internal/auth"
+	"bugtracker-backend/internal/config"
+	"bugtracker-backend/internal/db"
+	"bugtracker-backend/internal/models"
+	"bugtracker-backend/internal/rbac"
+	"bugtracker-backend/internal/workflow"
+)
+
+// Handler serves the bug and comment API on top of a Store, enforcing the
+// given status workflow.
+type Handler struct {
+	store      db.Store
+	workflow   *workflow.Workflow
+	admins     map[string]bool
+	sessionTTL time.Duration
+	sso        *SSO
+}
+
+func NewHandler(store db.Store, wf *workflow.Workflow) *Handler {
+	return &Handler{store: store, workflow: wf, sessionTTL: config.DefaultSessionTTL}
+}
+
+// SetAdmins names the users who have the admin role whatever role is
+// stored for them, so that a first admin can be configured. Names are
+// normalised like stored usernames.
+func (h *Handler) SetAdmins(names []string) {
+	h.admins = make(map[string]bool, len(names))
+	for _, name := range names {
+		if name = models.NormalizeUsername(name); name != "" {
+			h.admins[name] = true
+		}
+	}
+}
+
+// SetSessionTTL sets how long a login stays valid.
+func (h *Handler) SetSessionTTL(ttl time.Duration) {
+	h.sessionTTL = ttl
+}
+
+func (h *Handler) RegisterRoutes(r *mux.Router) {
+	h.registerAuditRoutes(r)
+	h.registerAuthRoutes(r)
+	h.registerSSORoutes(r)
+	h.registerTokenRoutes(r)
+	h.registerProjectRoutes(r)
+	h.registerBugRoutes(r)
+	h.registerCommentRoutes(r)
+	h.registerSearchRoutes(r)
+	h.registerHistoryRoutes(r)
+}
+
+// requestActor names who made the request, for fields such as deleted_by:
+// the username of the user Authenticate found, or "" for an anonymous
+// request.
+func requestActor(r *http.Request) string {
+	if user := auth.UserFromContext(r.Context()); user != nil {
+		return user.Username
+	}
+	return ""
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{
+		"error": message,
+	})
+}
+
+// writeStoreError maps a storage, validation or workflow error onto the
+// matching HTTP status. Validation failures also list the invalid fields and
+// rejected transitions the statuses that are allowed.
+func writeStoreError(w http.ResponseWriter, err error) {
+	var verr *models.ValidationError
+	if errors.As(err, &verr) {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":  err.Error(),
+			"fields": verr.Fields,
+		})
+		return
+	}
+
+	if errors.Is(err, errAuthRequired) {
+		writeUnauthorized(w, err.Error())
+		return
+	}
+
+	var terr *workflow.TransitionError
+	if errors.As(err, &terr) {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":   err.Error(),
+			"allowed": terr.Allowed,
+		})
+		return
+	}
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, db.ErrBugNotFound), errors.Is(err, db.ErrCommentNotFound), errors.Is(err, db.ErrUserNotFound),
+		errors.Is(err, db.ErrTokenNotFound), errors.Is(err, db.ErrProjectNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, db.ErrUserExists), errors.Is(err, db.ErrProjectExists),
+		errors.Is(err, errSSOUsernameTaken):
+		status = http.StatusConflict
+	case errors.Is(err, errCommentForbidden), errors.As(err, new(*rbac.DeniedError)),
+		errors.Is(err, errNoSSOUsername), errors.Is(err, errSSOUsernameReserved):
+		status = http.StatusForbidden
+	case errors.Is(err, db.ErrCommentDeleted):
+		status = http.StatusConflict
+	case errors.Is(err, db.ErrCommentPurged):
+		status = http.StatusGone
+	case errors.Is(err, db.ErrInvalidParent):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, errPreconditionFailed), errors.Is(err, db.ErrVersionConflict):
+		status = http.StatusPreconditionFailed
+	case errors.Is(err, workflow.ErrUnknownStatus),
+		errors.Is(err, workflow.ErrResolutionRequired),
+		errors.Is(err, workflow.ErrInvalidResolution):
+		status = http.StatusUnprocessableEntity
+	}
+	if status == http.StatusInternalServerError {
+		// Unexpected errors are logged rather than shown, as their text
+		// may describe the server's internals.
+		log.Printf("Internal error: %v", err)
+		writeError(w, status, "internal server error")
+		return
+	}
+	writeError(w, status, err.Error())
+}
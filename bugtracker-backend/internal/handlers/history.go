@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"bugtracker-backend/internal/db"
+	"bugtracker-backend/internal/models"
+)
+
+func (h *Handler) registerHistoryRoutes(r *mux.Router) {
+	r.HandleFunc("/bugs/{id}/history", h.GetHistory).Methods("GET").Name("GetHistory")
+}
+
+// GetHistory returns the bug's change timeline, oldest first.
+func (h *Handler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	idInt, ok := h.bugIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	history, err := h.store.GetHistory(idInt)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if history == nil {
+		history = []models.HistoryEntry{}
+	}
+
+	writeJSON(w, http.StatusOK, history)
+}
+
+// recordHistory appends an entry to a bug's history in the transaction that
+// made the change, attributing it to the request's actor.
+func recordHistory(tx db.Tx, r *http.Request, bugID int, action string, at time.Time, changes []models.FieldChange) error {
+	return tx.AddHistory(&models.HistoryEntry{
+		BugID:   bugID,
+		Action:  action,
+		Actor:   requestActor(r),
+		At:      at,
+		Changes: changes,
+	})
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bugtracker-backend/internal/models"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetHistory(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	do := func(method, path, actor, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if actor != "" {
+			req = withUser(req, actor)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusCreated, do("POST", "/bugs", "alice", `{"title": "Crash", "priority": "High"}`).Code)
+	assert.Equal(t, http.StatusOK, do("PATCH", "/bugs/1", "bob", `{"status": "Resolved", "resolution": "Fixed"}`).Code)
+	// An update that changes nothing is not recorded.
+	assert.Equal(t, http.StatusOK, do("PATCH", "/bugs/1", "bob", `{"priority": "High"}`).Code)
+	assert.Equal(t, http.StatusCreated, do("POST", "/bugs/1/comments", "carol", `{"content": "Confirmed"}`).Code)
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/bugs/1", "alice", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/bugs/1/history", "", "").Code)
+	assert.Equal(t, http.StatusOK, do("POST", "/trash/1/restore", "alice", "").Code)
+
+	w := do("GET", "/bugs/1/history", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var history []models.HistoryEntry
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&history))
+
+	actions := []string{}
+	actors := []string{}
+	for _, e := range history {
+		actions = append(actions, e.Action)
+		actors = append(actors, e.Actor)
+		assert.Equal(t, 1, e.BugID)
+		assert.False(t, e.At.IsZero())
+	}
+	assert.Equal(t, []string{"created", "updated", "commented", "deleted", "restored"}, actions)
+	assert.Equal(t, []string{"alice", "bob", "carol", "alice", "alice"}, actors)
+
+	if len(history) == 5 {
+		assert.Equal(t, []models.FieldChange{
+			{Field: "project", New: "BUG"},
+			{Field: "title", New: "Crash"},
+			{Field: "status", New: "Open"},
+			{Field: "priority", New: "High"},
+		}, history[0].Changes)
+
+		fields := []string{}
+		for _, c := range history[1].Changes {
+			fields = append(fields, c.Field)
+		}
+		assert.Equal(t, []string{"status", "resolution", "resolved_at"}, fields)
+		assert.Equal(t, models.FieldChange{Field: "status", Old: "Open", New: "Resolved"}, history[1].Changes[0])
+
+		assert.NotZero(t, history[2].CommentID)
+	}
+
+	assert.Equal(t, http.StatusNotFound, do("GET", "/bugs/999/history", "", "").Code)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"bugtracker-backend/internal/auth"
+	"bugtracker-backend/internal/models"
+	"bugtracker-backend/internal/rbac"
+)
+
+// routePermissions names the permission each route needs, by route name.
+// Routes missing from it are refused, so that a new route cannot be left
+// unguarded by mistake.
+var routePermissions = map[string]rbac.Permission{
+	"GetAudit":    rbac.ReadAudit,
+	"VerifyAudit": rbac.ReadAudit,
+	"ExportAudit": rbac.ReadAudit,
+
+	"Login":          rbac.SignIn,
+	"OIDCLogin":      rbac.SignIn,
+	"OIDCCallback":   rbac.SignIn,
+	"Logout":         rbac.UseAccount,
+	"GetCurrentUser": rbac.UseAccount,
+	"GetUsers":       rbac.ReadUsers,
+	"CreateUser":     rbac.ManageUsers,
+	"SetUserRole":    rbac.ManageUsers,
+
+	"GetAPITokens":   rbac.UseAccount,
+	"CreateAPIToken": rbac.UseAccount,
+	"DeleteAPIToken": rbac.UseAccount,
+
+	"GetProjects":      rbac.ReadBugs,
+	"CreateProject":    rbac.ManageProjects,
+	"GetProject":       rbac.ReadBugs,
+	"GetProjectBugs":   rbac.ReadBugs,
+	"CreateProjectBug": rbac.ReportBugs,
+	"GetProjectBug":    rbac.ReadBugs,
+
+	"CreateBug":      rbac.ReportBugs,
+	"GetBugs":        rbac.ReadBugs,
+	"DeleteAllBugs":  rbac.DeleteAllBugs,
+	"GetBug":         rbac.ReadBugs,
+	"UpdateBug":      rbac.EditBugs,
+	"PatchBug":       rbac.EditBugs,
+	"DeleteBug":      rbac.DeleteBugs,
+	"GetTransitions": rbac.ReadBugs,
+	"GetTrash":       rbac.ReadBugs,
+	"RestoreBug":     rbac.DeleteBugs,
+	"GetHistory":     rbac.ReadBugs,
+	"SearchBugs":     rbac.ReadBugs,
+
+	"GetComments":         rbac.ReadBugs,
+	"CreateComment":       rbac.Comment,
+	"GetComment":          rbac.ReadBugs,
+	"UpdateComment":       rbac.Comment,
+	"DeleteComment":       rbac.Comment,
+	"RestoreComment":      rbac.Comment,
+	"GetCommentRevisions": rbac.ReadBugs,
+}
+
+// errNoPolicy is returned for a route without an entry in routePermissions.
+var errNoPolicy = errors.New("no permission is defined for this route")
+
+// Authorize checks the role of the request's user against the permission
+// its route needs, answering 401 to anonymous clients and 403 with the
+// reason to users whose role falls short. It runs after Authenticate.
+func (h *Handler) Authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var perm rbac.Permission
+		if route := mux.CurrentRoute(r); route != nil {
+			perm = routePermissions[route.GetName()]
+		}
+		if perm == "" {
+			writeError(w, http.StatusForbidden, errNoPolicy.Error())
+			return
+		}
+		if err := h.authorize(r, perm); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestRole returns the role the request's user acts with: their own
+// role, or admin if they are named in ADMIN_USERS, limited by the scope of
+// the API token used. Anonymous requests have rbac.Anonymous.
+func (h *Handler) requestRole(r *http.Request) string {
+	user := auth.UserFromContext(r.Context())
+	if user == nil {
+		return rbac.Anonymous
+	}
+	role := user.Role
+	if h.admins[user.Username] {
+		role = models.RoleAdmin
+	}
+	return rbac.ScopedRole(role, auth.ScopeFromContext(r.Context()))
+}
+
+// authorize returns a *rbac.DeniedError unless the request's user has
+// permission p, or errAuthRequired if an anonymous client lacks it.
+func (h *Handler) authorize(r *http.Request, p rbac.Permission) error {
+	role := h.requestRole(r)
+	err := rbac.Check(role, p)
+	if err != nil && role == rbac.Anonymous {
+		return errAuthRequired
+	}
+	return err
+}
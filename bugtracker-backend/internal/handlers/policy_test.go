@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"bugtracker-backend/internal/models"
+)
+
+func TestEveryRouteHasAPolicy(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		name := route.GetName()
+		tmpl, _ := route.GetPathTemplate()
+		assert.Contains(t, routePermissions, name, "route %s has no permission", tmpl)
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestAuthorize(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+	h.SetAdmins([]string{"root"})
+
+	router := mux.NewRouter()
+	router.Use(h.Authorize)
+	h.RegisterRoutes(router)
+
+	do := func(role, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if role != "" {
+			req = withRole(req, role+"-user", role)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.NoError(t, store.CreateBug(&models.Bug{Title: "Crash", Status: "Open", Priority: "High"}))
+
+	// Anonymous clients may read but must sign in for anything else.
+	assert.Equal(t, http.StatusOK, do("", "GET", "/bugs", "").Code)
+	w := do("", "GET", "/admin/audit", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+
+	w = do(models.RoleViewer, "POST", "/bugs", `{"title": "Another"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "the viewer role may not report bugs; this needs the reporter role or higher")
+	assert.Equal(t, http.StatusOK, do(models.RoleViewer, "GET", "/auth/me", "").Code)
+
+	// Reporters may edit bugs but not move them through the workflow.
+	assert.Equal(t, http.StatusCreated, do(models.RoleReporter, "POST", "/bugs", `{"title": "Another"}`).Code)
+	assert.Equal(t, http.StatusOK, do(models.RoleReporter, "PATCH", "/bugs/1", `{"title": "Crash on start"}`).Code)
+	w = do(models.RoleReporter, "PATCH", "/bugs/1", `{"status": "In Progress"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "may not change the status of bugs")
+	// Nor file bugs that skip the workflow.
+	w = do(models.RoleReporter, "POST", "/bugs", `{"title": "Done already", "status": "Closed", "resolution": "Fixed"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, http.StatusForbidden, do(models.RoleReporter, "DELETE", "/bugs/2", "").Code)
+
+	// Developers may, and may delete single bugs, but not all of them.
+	assert.Equal(t, http.StatusOK, do(models.RoleDeveloper, "PATCH", "/bugs/1", `{"status": "In Progress"}`).Code)
+	assert.Equal(t, http.StatusNoContent, do(models.RoleDeveloper, "DELETE", "/bugs/2", "").Code)
+	w = do(models.RoleDeveloper, "DELETE", "/bugs", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "this needs the admin role")
+	assert.Equal(t, http.StatusForbidden, do(models.RoleDeveloper, "GET", "/admin/audit", "").Code)
+
+	assert.Equal(t, http.StatusOK, do(models.RoleAdmin, "DELETE", "/bugs", "").Code)
+	assert.Equal(t, http.StatusOK, do(models.RoleAdmin, "GET", "/admin/audit", "").Code)
+
+	// ADMIN_USERS are admins whatever their stored role.
+	req := withRole(httptest.NewRequest("GET", "/admin/audit", nil), "root", models.RoleViewer)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAdminUsersAreNormalised(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+	h.SetAdmins([]string{" Root ", ""})
+
+	router := mux.NewRouter()
+	router.Use(h.Authorize)
+	h.RegisterRoutes(router)
+
+	do := func(username string) int {
+		req := withRole(httptest.NewRequest("GET", "/admin/audit", nil), username, models.RoleViewer)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, do("root"))
+	assert.Equal(t, http.StatusForbidden, do(""))
+	assert.Equal(t, http.StatusForbidden, do("alice"))
+}
+
+func TestSetUserRole(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := mux.NewRouter()
+	router.Use(h.Authorize)
+	h.RegisterRoutes(router)
+
+	do := func(role, path, body string) *httptest.ResponseRecorder {
+		req := withRole(httptest.NewRequest("PUT", path, bytes.NewBufferString(body)), "someone", role)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	bob := createTestUser(t, store, "bob", "correct horse")
+	assert.Equal(t, models.RoleReporter, bob.Role)
+
+	assert.Equal(t, http.StatusForbidden, do(models.RoleDeveloper, "/users/1/role", `{"role": "admin"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, do(models.RoleAdmin, "/users/1/role", `{"role": "owner"}`).Code)
+	assert.Equal(t, http.StatusNotFound, do(models.RoleAdmin, "/users/99/role", `{"role": "viewer"}`).Code)
+
+	w := do(models.RoleAdmin, "/users/1/role", `{"role": "developer"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var user models.User
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&user))
+	assert.Equal(t, models.RoleDeveloper, user.Role)
+	stored, err := store.GetUser(bob.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleDeveloper, stored.Role)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"bugtracker-backend/internal/models"
+)
+
+// registerProjectRoutes adds the project routes. The bug routes under a
+// project share their handlers with the /bugs routes, which read the
+// project from the {key} route variable.
+func (h *Handler) registerProjectRoutes(r *mux.Router) {
+	r.HandleFunc("/projects", h.GetProjects).Methods("GET").Name("GetProjects")
+	r.HandleFunc("/projects", h.CreateProject).Methods("POST").Name("CreateProject")
+	r.HandleFunc("/projects/{key}", h.GetProject).Methods("GET").Name("GetProject")
+	r.HandleFunc("/projects/{key}/bugs", h.GetBugs).Methods("GET").Name("GetProjectBugs")
+	r.HandleFunc("/projects/{key}/bugs", h.CreateBug).Methods("POST").Name("CreateProjectBug")
+	r.HandleFunc("/projects/{key}/bugs/{number}", h.GetBug).Methods("GET").Name("GetProjectBug")
+}
+
+// GetProjects lists every project, oldest first.
+func (h *Handler) GetProjects(w http.ResponseWriter, r *http.Request) {
+	projects, err := h.store.ListProjects()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if projects == nil {
+		projects = []models.Project{}
+	}
+	writeJSON(w, http.StatusOK, projects)
+}
+
+// CreateProject adds a project. Its key cannot be changed later, since it
+// is part of the key of every bug in the project.
+func (h *Handler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	project := &models.Project{Key: req.Key, Name: req.Name, Description: req.Description}
+	if err := h.store.CreateProject(project); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, project)
+}
+
+// GetProject returns the project named by the {key} route variable.
+func (h *Handler) GetProject(w http.ResponseWriter, r *http.Request) {
+	project, err := h.store.GetProject(mux.Vars(r)["key"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, project)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"bugtracker-backend/internal/models"
+)
+
+func TestProjects(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := mux.NewRouter()
+	router.Use(h.Authorize)
+	h.RegisterRoutes(router)
+
+	do := func(role, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if role != "" {
+			req = withRole(req, role+"-user", role)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Only admins create projects.
+	w := do(models.RoleDeveloper, "POST", "/projects", `{"key": "web", "name": "Website"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = do(models.RoleAdmin, "POST", "/projects", `{"key": "web", "name": "Website"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var project models.Project
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&project))
+	assert.Equal(t, "WEB", project.Key)
+	assert.Equal(t, http.StatusConflict, do(models.RoleAdmin, "POST", "/projects", `{"key": "WEB", "name": "Again"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, do(models.RoleAdmin, "POST", "/projects", `{"key": "W", "name": "Short"}`).Code)
+
+	w = do("", "GET", "/projects", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var projects []models.Project
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&projects))
+	assert.Len(t, projects, 2)
+	assert.Equal(t, http.StatusOK, do("", "GET", "/projects/web", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("", "GET", "/projects/NOPE", "").Code)
+
+	// Bugs are numbered per project.
+	create := func(path, body string) models.Bug {
+		w := do(models.RoleReporter, "POST", path, body)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var bug models.Bug
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&bug))
+		return bug
+	}
+	assert.Equal(t, "WEB-1", create("/projects/WEB/bugs", `{"title": "Crash"}`).Key)
+	assert.Equal(t, "BUG-1", create("/bugs", `{"title": "Crash"}`).Key)
+	bug := create("/bugs", `{"project": "web", "title": "Hang"}`)
+	assert.Equal(t, "WEB-2", bug.Key)
+	assert.Equal(t, 2, bug.Number)
+	assert.Equal(t, http.StatusNotFound, do(models.RoleReporter, "POST", "/projects/NOPE/bugs", `{"title": "Crash"}`).Code)
+
+	// An unknown project in the body is a field of the request, reported
+	// with any other invalid fields.
+	w = do(models.RoleReporter, "POST", "/bugs", `{"project": "NOPE", "title": ""}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var verr struct {
+		Fields []models.FieldError `json:"fields"`
+	}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&verr))
+	assert.Equal(t, []models.FieldError{
+		{Field: "project", Message: `unknown project "NOPE"`},
+		{Field: "title", Message: "title is required"},
+	}, verr.Fields)
+	w = do(models.RoleReporter, "POST", "/bugs", `{"project": "NOPE", "title": "Crash"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"field":"project"`)
+
+	w = do("", "GET", "/projects/web/bugs", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var bugs []models.Bug
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&bugs))
+	assert.Len(t, bugs, 2)
+	assert.Equal(t, http.StatusNotFound, do("", "GET", "/projects/NOPE/bugs", "").Code)
+	w = do("", "GET", "/bugs?project=BUG", "")
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&bugs))
+	assert.Len(t, bugs, 1)
+
+	// A bug can be named by its key wherever its ID is accepted.
+	w = do("", "GET", "/projects/web/bugs/2", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got models.Bug
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, bug.ID, got.ID)
+	assert.Equal(t, http.StatusOK, do("", "GET", "/bugs/web-2", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("", "GET", "/bugs/WEB-3", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do("", "GET", "/bugs/WEB-x", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do("", "GET", "/projects/web/bugs/x", "").Code)
+
+	w = do(models.RoleReporter, "PATCH", "/bugs/WEB-2", `{"title": "Hangs on save"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, "Hangs on save", got.Title)
+	assert.Equal(t, "WEB-2", got.Key)
+	assert.Equal(t, http.StatusCreated, do(models.RoleReporter, "POST", "/bugs/WEB-2/comments", `{"content": "Seen it"}`).Code)
+}
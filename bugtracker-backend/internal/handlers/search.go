@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultSearchLimit is the number of hits GET /search returns when the
+// client does not ask for a limit.
+const defaultSearchLimit = 20
+
+func (h *Handler) registerSearchRoutes(r *mux.Router) {
+	r.HandleFunc("/search", h.SearchBugs).Methods("GET").Name("SearchBugs")
+}
+
+// SearchBugs ranks bugs by how well their title, description and comments
+// match the q parameter.
+func (h *Handler) SearchBugs(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageSize))
+			return
+		}
+		limit = n
+	}
+
+	hits, err := h.store.SearchBugs(q, limit)
+	if err != nil {
+		log.Printf("Error searching bugs: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, hits)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bugtracker-backend/internal/db"
+	"bugtracker-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchBugs(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	for _, b := range []models.Bug{
+		{Title: "Login fails", Description: "Password reset link is broken"},
+		{Title: "Broken layout", Description: "Sidebar overlaps content"},
+		{Title: "Slow search"},
+	} {
+		b := b
+		assert.NoError(t, store.CreateBug(&b))
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedIDs    []int
+		expectedError  string
+	}{
+		{
+			name:           "Ranked hits",
+			query:          "?q=broken",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int{2, 1},
+		},
+		{
+			name:           "Limit",
+			query:          "?q=broken&limit=1",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int{2},
+		},
+		{
+			name:           "No hits",
+			query:          "?q=database",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int{},
+		},
+		{
+			name:           "Missing query",
+			query:          "?q=%20",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "q is required",
+		},
+		{
+			name:           "Invalid limit",
+			query:          "?q=broken&limit=abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "limit must be between",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/search"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			h.SearchBugs(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedError != "" {
+				var resp map[string]string
+				err := json.NewDecoder(w.Body).Decode(&resp)
+				assert.NoError(t, err)
+				assert.Contains(t, resp["error"], tt.expectedError)
+				return
+			}
+
+			var hits []db.SearchHit
+			err := json.NewDecoder(w.Body).Decode(&hits)
+			assert.NoError(t, err)
+			ids := []int{}
+			for _, hit := range hits {
+				ids = append(ids, hit.Bug.ID)
+				assert.NotEmpty(t, hit.Highlights)
+			}
+			assert.Equal(t, tt.expectedIDs, ids)
+		})
+	}
+}
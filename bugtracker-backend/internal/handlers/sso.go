@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"bugtracker-backend/internal/db"
+	"bugtracker-backend/internal/models"
+	"bugtracker-backend/internal/oidc"
+)
+
+// SSO configures sign-in through an OpenID Connect provider.
+type SSO struct {
+	Provider *oidc.Provider
+	// UsernameClaim names the ID token claim that holds the username.
+	UsernameClaim string
+	// GroupsClaim names the ID token claim that lists the user's groups.
+	GroupsClaim string
+	// RoleGroups maps provider groups to the role they grant. When it is
+	// set, the provider decides users' roles: every sign-in sets the role
+	// to the highest one granted, or the default role if none is.
+	RoleGroups map[string]string
+	// LoginRedirect is where the browser is sent once signed in, with the
+	// session token in the URL fragment. If empty, the callback answers
+	// with the same JSON as POST /auth/login.
+	LoginRedirect string
+}
+
+// SetSSO turns on sign-in through an OpenID Connect provider.
+func (h *Handler) SetSSO(sso *SSO) {
+	h.sso = sso
+}
+
+func (h *Handler) registerSSORoutes(r *mux.Router) {
+	r.HandleFunc("/auth/oidc/login", h.OIDCLogin).Methods("GET").Name("OIDCLogin")
+	r.HandleFunc("/auth/oidc/callback", h.OIDCCallback).Methods("GET").Name("OIDCCallback")
+}
+
+const (
+	// oidcCookie carries the state, nonce and PKCE verifier of a sign-in
+	// from OIDCLogin to OIDCCallback.
+	oidcCookie = "bugtracker_oidc"
+	// oidcLoginTimeout is how long the user has to sign in at the provider.
+	oidcLoginTimeout = 10 * time.Minute
+)
+
+var errSSODisabled = errors.New("single sign-on is not configured")
+
+// OIDCLogin sends the browser to the provider to sign in.
+func (h *Handler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if h.sso == nil {
+		writeError(w, http.StatusNotFound, errSSODisabled.Error())
+		return
+	}
+
+	login, err := oidc.NewLoginState()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	authURL, err := h.sso.Provider.AuthCodeURL(r.Context(), login)
+	if err != nil {
+		log.Printf("Failed to start single sign-on: %v", err)
+		writeError(w, http.StatusBadGateway, "identity provider is unavailable")
+		return
+	}
+	encoded, err := json.Marshal(login)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Value:    base64.RawURLEncoding.EncodeToString(encoded),
+		Path:     "/",
+		MaxAge:   int(oidcLoginTimeout / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback finishes a sign-in: it checks the state, exchanges the code
+// for a validated ID token, finds or creates the user and starts a session.
+func (h *Handler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if h.sso == nil {
+		writeError(w, http.StatusNotFound, errSSODisabled.Error())
+		return
+	}
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		writeUnauthorized(w, fmt.Sprintf("sign-in failed at the identity provider: %s %s", e, q.Get("error_description")))
+		return
+	}
+
+	login, err := oidcLoginFromCookie(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "sign-in expired or was started in another browser; start again")
+		return
+	}
+	// The cookie is single use.
+	http.SetCookie(w, &http.Cookie{Name: oidcCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+	if q.Get("state") == "" || q.Get("state") != login.State {
+		writeError(w, http.StatusBadRequest, "sign-in state does not match; start again")
+		return
+	}
+
+	claims, err := h.sso.Provider.Exchange(r.Context(), q.Get("code"), login)
+	if errors.Is(err, oidc.ErrInvalidIDToken) {
+		writeUnauthorized(w, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to finish single sign-on: %v", err)
+		writeError(w, http.StatusBadGateway, "could not finish signing in with the identity provider")
+		return
+	}
+
+	var user *models.User
+	err = h.store.Update(func(tx db.Tx) error {
+		user, err = h.ssoUser(tx, claims)
+		return err
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	session, err := h.startSession(user)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if h.sso.LoginRedirect == "" {
+		writeJSON(w, http.StatusOK, session)
+		return
+	}
+	fragment := url.Values{
+		"token":      {session.Token},
+		"expires_at": {session.ExpiresAt.Format(time.RFC3339)},
+	}
+	http.Redirect(w, r, h.sso.LoginRedirect+"#"+fragment.Encode(), http.StatusFound)
+}
+
+func oidcLoginFromCookie(r *http.Request) (*oidc.LoginState, error) {
+	cookie, err := r.Cookie(oidcCookie)
+	if err != nil {
+		return nil, err
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+	var login oidc.LoginState
+	if err := json.Unmarshal(b, &login); err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
+
+// errNoSSOUsername is returned when the provider's ID token does not carry
+// a username the tracker accepts.
+var errNoSSOUsername = errors.New("the identity provider did not supply a usable username")
+
+// errSSOUsernameTaken is returned when a provider identity signs in for the
+// first time with the username of an existing local user. Identities are
+// never linked to existing users by name, as whoever controls the name at
+// the provider would take over the account.
+var errSSOUsernameTaken = errors.New("the username is already taken by another user")
+
+// errSSOUsernameReserved is returned when a provider identity signs in for
+// the first time with a username listed in ADMIN_USERS. Those names are
+// admins by name alone, so they must be created locally, not claimed by
+// whoever first signs in with them.
+var errSSOUsernameReserved = errors.New("the username is reserved for a configured admin")
+
+// ssoUser returns the local user for a provider identity, found by the
+// provider's issuer and subject. On the first sign-in a new user is
+// created for the identity. Roles follow the groups claim when RoleGroups
+// is configured.
+func (h *Handler) ssoUser(tx db.Tx, claims *oidc.Claims) (*models.User, error) {
+	var user *models.User
+	identity, err := tx.GetIdentity(claims.Issuer, claims.Subject)
+	switch {
+	case err == nil:
+		if user, err = tx.GetUser(identity.UserID); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, db.ErrIdentityNotFound):
+		if user, err = h.createSSOUser(tx, claims); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if len(h.sso.RoleGroups) > 0 {
+		if role := h.roleForGroups(claims.Strings(h.sso.GroupsClaim)); role != user.Role {
+			user.Role = role
+			if err := tx.UpdateUser(user); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return user, nil
+}
+
+// createSSOUser creates the user for a provider identity signing in for the
+// first time.
+func (h *Handler) createSSOUser(tx db.Tx, claims *oidc.Claims) (*models.User, error) {
+	var username string
+	if v := claims.Strings(h.sso.UsernameClaim); len(v) == 1 {
+		username = models.NormalizeUsername(v[0])
+	}
+	if !models.ValidUsername(username) {
+		return nil, fmt.Errorf("%w in the %q claim", errNoSSOUsername, h.sso.UsernameClaim)
+	}
+	if h.admins[username] {
+		return nil, fmt.Errorf("%w: %q", errSSOUsernameReserved, username)
+	}
+
+	user := &models.User{Username: username, DisplayName: claims.Name}
+	err := tx.CreateUser(user)
+	if errors.Is(err, db.ErrUserExists) {
+		return nil, fmt.Errorf("%w: %q", errSSOUsernameTaken, username)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = tx.CreateIdentity(&models.Identity{
+		Issuer:    claims.Issuer,
+		Subject:   claims.Subject,
+		UserID:    user.ID,
+		CreatedAt: time.Now().UTC(),
+	})
+	return user, err
+}
+
+// roleForGroups returns the highest role RoleGroups grants any of groups,
+// or the default role.
+func (h *Handler) roleForGroups(groups []string) string {
+	best, rank := models.DefaultRole, -1
+	for _, group := range groups {
+		role, ok := h.sso.RoleGroups[group]
+		if !ok {
+			continue
+		}
+		for i, r := range models.Roles {
+			if r == role && i > rank {
+				best, rank = role, i
+			}
+		}
+	}
+	return best
+}
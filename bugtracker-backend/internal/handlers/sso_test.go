@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"bugtracker-backend/internal/db"
+	"bugtracker-backend/internal/models"
+	"bugtracker-backend/internal/oidc"
+	"bugtracker-backend/internal/oidc/oidctest"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+const ssoCallback = "http://tracker.test/api/auth/oidc/callback"
+
+// signIn runs a sign-in against idp, returning the tracker's answer to
+// the callback.
+func signIn(t *testing.T, router http.Handler, idp *oidctest.Server) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/auth/oidc/login", nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+
+	// The browser signs in at the provider, which sends it back with a code.
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(w.Header().Get("Location"))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/auth/oidc/callback?"+callback.RawQuery, nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func setupSSO(h *Handler, idp *oidctest.Server) *SSO {
+	sso := &SSO{
+		Provider: oidc.NewProvider(oidc.Config{
+			Issuer:       idp.Issuer(),
+			ClientID:     idp.ClientID,
+			ClientSecret: idp.ClientSecret,
+			RedirectURL:  ssoCallback,
+		}, idp.Client()),
+		UsernameClaim: "preferred_username",
+		GroupsClaim:   "groups",
+		RoleGroups:    map[string]string{"eng": models.RoleDeveloper, "ops": models.RoleAdmin, "support": models.RoleViewer},
+	}
+	h.SetSSO(sso)
+	return sso
+}
+
+func TestSSO(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+	idp := oidctest.NewServer("bugtracker", "s3cret")
+	defer idp.Close()
+	setupSSO(h, idp)
+
+	router := mux.NewRouter()
+	router.Use(h.Authenticate, h.Authorize)
+	h.RegisterRoutes(router)
+
+	// The first sign-in creates the user, with the highest role its groups
+	// grant.
+	idp.SetClaims(map[string]interface{}{
+		"sub": "u-1", "preferred_username": "Alice", "name": "Alice Liddell",
+		"groups": []string{"support", "eng"},
+	})
+	w := signIn(t, router, idp)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var login models.LoginResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&login))
+	assert.NotEmpty(t, login.Token)
+	assert.Equal(t, "alice", login.User.Username)
+	assert.Equal(t, "Alice Liddell", login.User.DisplayName)
+	assert.Equal(t, models.RoleDeveloper, login.User.Role)
+
+	req := httptest.NewRequest("GET", "/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Later sign-ins find the user by subject, even if the username
+	// changes, and follow the groups.
+	idp.SetClaims(map[string]interface{}{"sub": "u-1", "preferred_username": "alice2", "groups": []string{"ops"}})
+	w = signIn(t, router, idp)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&login))
+	assert.Equal(t, "alice", login.User.Username)
+	assert.Equal(t, models.RoleAdmin, login.User.Role)
+
+	idp.SetClaims(map[string]interface{}{"sub": "u-1"})
+	w = signIn(t, router, idp)
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&login))
+	assert.Equal(t, models.DefaultRole, login.User.Role)
+
+	// An existing local user is never taken over by a provider identity
+	// with the same username.
+	bob := createTestUser(t, store, "bob", "correct horse")
+	idp.SetClaims(map[string]interface{}{"sub": "u-2", "preferred_username": "bob", "groups": []string{"ops"}})
+	w = signIn(t, router, idp)
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	_, err := store.GetIdentity(idp.Issuer(), "u-2")
+	assert.ErrorIs(t, err, db.ErrIdentityNotFound)
+	stored, err := store.GetUser(bob.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, bob.Role, stored.Role)
+
+	users, err := store.ListUsers()
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+
+	// Without a usable username there is no one to sign in as.
+	idp.SetClaims(map[string]interface{}{"sub": "u-3", "preferred_username": "not a username"})
+	assert.Equal(t, http.StatusForbidden, signIn(t, router, idp).Code)
+}
+
+func TestSSORefusesAdminUsernames(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+	h.SetAdmins([]string{"root"})
+	idp := oidctest.NewServer("bugtracker", "s3cret")
+	defer idp.Close()
+	setupSSO(h, idp)
+
+	router := mux.NewRouter()
+	router.Use(h.Authenticate, h.Authorize)
+	h.RegisterRoutes(router)
+
+	// A name listed in ADMIN_USERS that no local user has yet must not be
+	// claimed by a provider identity, which would then be an admin.
+	idp.SetClaims(map[string]interface{}{"sub": "u-1", "preferred_username": "Root"})
+	w := signIn(t, router, idp)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "reserved")
+	_, err := store.GetIdentity(idp.Issuer(), "u-1")
+	assert.ErrorIs(t, err, db.ErrIdentityNotFound)
+	users, err := store.ListUsers()
+	assert.NoError(t, err)
+	assert.Empty(t, users)
+}
+
+func TestSSOCallbackChecksState(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+	idp := oidctest.NewServer("bugtracker", "s3cret")
+	defer idp.Close()
+	setupSSO(h, idp)
+
+	router := mux.NewRouter()
+	router.Use(h.Authenticate, h.Authorize)
+	h.RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/auth/oidc/login", nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	cookie := w.Result().Cookies()[0]
+	assert.True(t, cookie.HttpOnly)
+
+	callback := func(query string, cookies ...*http.Cookie) int {
+		req := httptest.NewRequest("GET", "/auth/oidc/callback?"+query, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusBadRequest, callback("code=x&state=y"))
+	assert.Equal(t, http.StatusBadRequest, callback("code=x&state=forged", cookie))
+	assert.Equal(t, http.StatusUnauthorized, callback("error=access_denied", cookie))
+}
+
+func TestSSOLoginRedirect(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+	idp := oidctest.NewServer("bugtracker", "s3cret")
+	defer idp.Close()
+	setupSSO(h, idp).LoginRedirect = "https://tracker.test/signed-in"
+
+	router := mux.NewRouter()
+	router.Use(h.Authenticate, h.Authorize)
+	h.RegisterRoutes(router)
+
+	idp.SetClaims(map[string]interface{}{"sub": "u-1", "preferred_username": "alice"})
+	w := signIn(t, router, idp)
+	assert.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "/signed-in", location.Path)
+	fragment, err := url.ParseQuery(location.Fragment)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, fragment.Get("token"))
+	assert.NotEmpty(t, fragment.Get("expires_at"))
+}
+
+func TestSSONotConfigured(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := mux.NewRouter()
+	router.Use(h.Authenticate, h.Authorize)
+	h.RegisterRoutes(router)
+
+	for _, path := range []string{"/auth/oidc/login", "/auth/oidc/callback"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	}
+}
@@ -1,11 +1,28 @@
 package handlers
 
 import (
+	"bugtracker-backend/internal/auth"
+	"bugtracker-backend/internal/config"
 	"bugtracker-backend/internal/db"
+	"bugtracker-backend/internal/models"
+	"bugtracker-backend/internal/workflow"
+	"net/http"
 	"testing"
 )
 
-func setupTestDB(t *testing.T) func() {
-	cleanup := db.SetupTestDB(t)
-	return cleanup
-} 
\ No newline at end of file
+func setupTestHandler(t *testing.T) (*Handler, db.Store, func()) {
+	store, cleanup := db.SetupTestStore(t, config.BackendMemory)
+	return NewHandler(store, workflow.Default()), store, cleanup
+}
+
+// withUser returns r as if Authenticate had signed in the named user, as a
+// developer.
+func withUser(r *http.Request, username string) *http.Request {
+	return withRole(r, username, models.RoleDeveloper)
+}
+
+// withRole returns r as if Authenticate had signed in the named user with
+// role.
+func withRole(r *http.Request, username, role string) *http.Request {
+	return r.WithContext(auth.WithUser(r.Context(), &models.User{Username: username, Role: role}))
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"bugtracker-backend/internal/auth"
+	"bugtracker-backend/internal/models"
+)
+
+func (h *Handler) registerTokenRoutes(r *mux.Router) {
+	r.HandleFunc("/tokens", h.GetAPITokens).Methods("GET").Name("GetAPITokens")
+	r.HandleFunc("/tokens", h.CreateAPIToken).Methods("POST").Name("CreateAPIToken")
+	r.HandleFunc("/tokens/{id}", h.DeleteAPIToken).Methods("DELETE").Name("DeleteAPIToken")
+}
+
+// GetAPITokens lists the signed-in user's API tokens, without the tokens
+// themselves.
+func (h *Handler) GetAPITokens(w http.ResponseWriter, r *http.Request) {
+	user := auth.UserFromContext(r.Context())
+	if user == nil {
+		writeUnauthorized(w, "authentication required")
+		return
+	}
+	tokens, err := h.store.ListAPITokens(user.ID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if tokens == nil {
+		tokens = []models.APIToken{}
+	}
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+// CreateAPIToken issues an API token to the signed-in user. The token is
+// only ever returned here. Tokens are created from a login session, so that
+// a leaked token cannot be used to mint more.
+func (h *Handler) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	user := auth.UserFromContext(r.Context())
+	if user == nil {
+		writeUnauthorized(w, "authentication required")
+		return
+	}
+	if strings.HasPrefix(bearerToken(r), auth.APITokenPrefix) {
+		writeError(w, http.StatusForbidden, "API tokens may not create API tokens; sign in instead")
+		return
+	}
+
+	var req models.CreateAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(time.Now()); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	token, err := auth.NewAPIToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	apiToken := &models.APIToken{
+		UserID:    user.ID,
+		Name:      req.Name,
+		Scope:     req.Scope,
+		ExpiresAt: req.ExpiresAt,
+		TokenHash: auth.HashToken(token),
+	}
+	if err := h.store.CreateAPIToken(apiToken); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, models.CreateAPITokenResponse{APIToken: *apiToken, Token: token})
+}
+
+// DeleteAPIToken revokes one of the signed-in user's API tokens.
+func (h *Handler) DeleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	user := auth.UserFromContext(r.Context())
+	if user == nil {
+		writeUnauthorized(w, "authentication required")
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid token ID")
+		return
+	}
+	if err := h.store.DeleteAPIToken(user.ID, id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
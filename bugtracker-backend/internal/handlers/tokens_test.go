@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bugtracker-backend/internal/auth"
+	"bugtracker-backend/internal/models"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPITokens(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+	h.SetAdmins([]string{"alice"})
+
+	router := mux.NewRouter()
+	router.Use(h.Authenticate, h.Authorize)
+	h.RegisterRoutes(router)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	create := func(session, body string) models.CreateAPITokenResponse {
+		w := do("POST", "/tokens", session, body)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp models.CreateAPITokenResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return resp
+	}
+
+	createTestUser(t, store, "alice", "correct horse")
+	w := do("POST", "/auth/login", "", `{"username": "alice", "password": "correct horse"}`)
+	var login models.LoginResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&login))
+	session := login.Token
+
+	assert.Equal(t, http.StatusUnauthorized, do("POST", "/tokens", "", `{"name": "ci", "scope": "write"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, do("POST", "/tokens", session, `{"name": "", "scope": "root"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, do("POST", "/tokens", session, `{"name": "old", "scope": "read", "expires_at": "2020-01-01T00:00:00Z"}`).Code)
+
+	writer := create(session, `{"name": "ci", "scope": "write"}`)
+	assert.Contains(t, writer.Token, "btk_")
+	assert.Equal(t, "ci", writer.Name)
+	reader := create(session, `{"name": "dashboard", "scope": "read"}`)
+
+	// The write scope may change things, as the token's user.
+	w = do("POST", "/bugs", writer.Token, `{"title": "Crash"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var bug models.Bug
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&bug))
+	assert.Equal(t, "alice", bug.Reporter)
+
+	// The read scope may only read.
+	assert.Equal(t, http.StatusOK, do("GET", "/bugs", reader.Token, "").Code)
+	w = do("POST", "/bugs", reader.Token, `{"title": "Crash"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "read scope")
+
+	// Admin operations need the admin scope even for admins.
+	assert.Equal(t, http.StatusForbidden, do("POST", "/users", writer.Token, `{"username": "bob", "password": "long enough"}`).Code)
+	admin := create(session, `{"name": "provisioning", "scope": "admin"}`)
+	assert.Equal(t, http.StatusCreated, do("POST", "/users", admin.Token, `{"username": "bob", "password": "long enough"}`).Code)
+
+	// Tokens cannot mint more tokens.
+	assert.Equal(t, http.StatusForbidden, do("POST", "/tokens", admin.Token, `{"name": "more", "scope": "admin"}`).Code)
+
+	w = do("GET", "/tokens", reader.Token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "btk_")
+	assert.NotContains(t, w.Body.String(), "hash")
+	var tokens []models.APIToken
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&tokens))
+	if assert.Len(t, tokens, 3) {
+		assert.Equal(t, "ci", tokens[0].Name)
+		for _, token := range tokens {
+			assert.NotNil(t, token.LastUsedAt, token.Name)
+		}
+	}
+
+	assert.Equal(t, http.StatusNotFound, do("DELETE", "/tokens/99", session, "").Code)
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/tokens/1", session, "").Code)
+	w = do("GET", "/bugs", writer.Token, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "revoked")
+}
+
+func TestExpiredAPIToken(t *testing.T) {
+	h, store, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := mux.NewRouter()
+	router.Use(h.Authenticate, h.Authorize)
+	h.RegisterRoutes(router)
+
+	user := createTestUser(t, store, "alice", "correct horse")
+	expired := time.Now().Add(-time.Minute)
+	token := "btk_expired"
+	assert.NoError(t, store.CreateAPIToken(&models.APIToken{
+		UserID:    user.ID,
+		Name:      "expired",
+		Scope:     models.ScopeWrite,
+		ExpiresAt: &expired,
+		TokenHash: auth.HashToken(token),
+	}))
+
+	req := httptest.NewRequest("GET", "/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+}
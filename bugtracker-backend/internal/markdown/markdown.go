@@ -0,0 +1,47 @@
+// Package markdown renders the Markdown of bug descriptions and comments to
+// HTML that is safe to embed in a page.
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// GitHub Flavored Markdown: tables, task lists, strikethrough and bare
+// links. Raw HTML in the source is dropped by the renderer.
+var md = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// policy is applied to the rendered HTML as well, so that nothing the
+// renderer lets through, such as a javascript: link, reaches a browser.
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	// Fenced code blocks name their language as a class.
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	// Task list items render as disabled checkboxes.
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^(|checked|disabled)$`)).OnElements("input")
+	p.RequireNoReferrerOnLinks(true)
+	return p
+}
+
+// Render converts src to sanitised HTML. Empty input renders as an empty
+// string.
+func Render(src string) string {
+	if src == "" {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(src), &buf); err != nil {
+		// Converting to a buffer does not fail; fall back to escaped text
+		// all the same rather than lose the content.
+		return "<p>" + html.EscapeString(src) + "</p>\n"
+	}
+	return string(policy.SanitizeBytes(buf.Bytes()))
+}
@@ -0,0 +1,47 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"empty", "", ""},
+		{"emphasis", "Crashes on **save**", "<p>Crashes on <strong>save</strong></p>\n"},
+		{"code block", "```go\nx := 1\n```", "<pre><code class=\"language-go\">x := 1\n</code></pre>\n"},
+		{"task list", "- [x] done\n- [ ] todo",
+			"<ul>\n<li><input checked=\"\" disabled=\"\" type=\"checkbox\"> done</li>\n<li><input disabled=\"\" type=\"checkbox\"> todo</li>\n</ul>\n"},
+		{"table", "| a | b |\n|---|---|\n| 1 | 2 |",
+			"<table>\n<thead>\n<tr>\n<th>a</th>\n<th>b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>1</td>\n<td>2</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"link", "[docs](https://example.com/docs)", "<p><a href=\"https://example.com/docs\" rel=\"nofollow noreferrer\">docs</a></p>\n"},
+		{"bare link", "see https://example.com", "<p>see <a href=\"https://example.com\" rel=\"nofollow noreferrer\">https://example.com</a></p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Render(tt.src))
+		})
+	}
+}
+
+func TestRenderStripsUnsafeContent(t *testing.T) {
+	for _, src := range []string{
+		"<script>alert(1)</script>",
+		"<img src=x onerror=alert(1)>",
+		"[click](javascript:alert(1))",
+		"<a href=\"#\" onclick=\"alert(1)\">click</a>",
+		"```\" onmouseover=\"alert(1)\n```",
+	} {
+		out := Render(src)
+		assert.NotContains(t, out, "<script", src)
+		assert.NotContains(t, out, "onerror", src)
+		assert.NotContains(t, out, "onclick", src)
+		assert.NotContains(t, out, "javascript:", src)
+		assert.NotContains(t, out, "onmouseover=", src)
+	}
+}
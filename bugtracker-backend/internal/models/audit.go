@@ -0,0 +1,67 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AuditEntry records one write request. Entries form a hash chain: each
+// holds the hash of its predecessor and a hash over its own fields, so
+// editing, removing or reordering stored entries is detectable.
+type AuditEntry struct {
+	Seq   int       `json:"seq"`
+	At    time.Time `json:"at"`
+	Actor string    `json:"actor,omitempty"`
+	// Action is the method and route of the request, such as
+	// "DELETE /api/bugs/{id}".
+	Action     string `json:"action"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Status     int    `json:"status"`
+	RemoteAddr string `json:"remote_addr,omitempty"`
+	PrevHash   string `json:"prev_hash"`
+	Hash       string `json:"hash"`
+}
+
+// ComputeHash returns the SHA-256 over the entry's fields and PrevHash,
+// ignoring Hash itself.
+func (e *AuditEntry) ComputeHash() string {
+	data, _ := json.Marshal([]interface{}{
+		e.Seq, e.At.UTC().Format(time.RFC3339Nano), e.Actor, e.Action,
+		e.Method, e.Path, e.Status, e.RemoteAddr, e.PrevHash,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditChainError reports the first entry at which an audit chain breaks.
+type AuditChainError struct {
+	Seq    int
+	Reason string
+}
+
+func (e *AuditChainError) Error() string {
+	return fmt.Sprintf("audit chain broken at entry %d: %s", e.Seq, e.Reason)
+}
+
+// VerifyAuditChain checks that entries, in order, form an unbroken chain
+// starting at sequence number 1. It returns an *AuditChainError for the
+// first entry that was altered, removed or inserted out of order.
+func VerifyAuditChain(entries []AuditEntry) error {
+	prev := ""
+	for i, e := range entries {
+		switch {
+		case e.Seq != i+1:
+			return &AuditChainError{Seq: i + 1, Reason: fmt.Sprintf("found entry %d instead", e.Seq)}
+		case e.PrevHash != prev:
+			return &AuditChainError{Seq: e.Seq, Reason: "previous hash does not match"}
+		case e.Hash != e.ComputeHash():
+			return &AuditChainError{Seq: e.Seq, Reason: "entry hash does not match its contents"}
+		}
+		prev = e.Hash
+	}
+	return nil
+}
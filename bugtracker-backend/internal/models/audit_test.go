@@ -0,0 +1,96 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testAuditChain() []AuditEntry {
+	at := time.Date(2025, 2, 12, 16, 0, 0, 0, time.UTC)
+	entries := []AuditEntry{
+		{Seq: 1, At: at, Actor: "alice", Action: "POST /api/bugs", Method: "POST", Path: "/api/bugs", Status: 201},
+		{Seq: 2, At: at.Add(time.Minute), Actor: "bob", Action: "DELETE /api/bugs", Method: "DELETE", Path: "/api/bugs", Status: 200},
+		{Seq: 3, At: at.Add(2 * time.Minute), Action: "PUT /api/bugs/{id}", Method: "PUT", Path: "/api/bugs/1", Status: 404},
+	}
+	prev := ""
+	for i := range entries {
+		entries[i].PrevHash = prev
+		entries[i].Hash = entries[i].ComputeHash()
+		prev = entries[i].Hash
+	}
+	return entries
+}
+
+func TestAuditEntryHash(t *testing.T) {
+	e := testAuditChain()[0]
+	hash := e.ComputeHash()
+	assert.Len(t, hash, 64)
+
+	// The hash does not depend on the time zone the time was read in.
+	e.At = e.At.In(time.FixedZone("CET", 3600))
+	assert.Equal(t, hash, e.ComputeHash())
+
+	e.Actor = "mallory"
+	assert.NotEqual(t, hash, e.ComputeHash())
+}
+
+func TestVerifyAuditChain(t *testing.T) {
+	assert.NoError(t, VerifyAuditChain(nil))
+	assert.NoError(t, VerifyAuditChain(testAuditChain()))
+
+	tests := []struct {
+		name   string
+		tamper func(entries []AuditEntry) []AuditEntry
+		seq    int
+		reason string
+	}{
+		{
+			name: "edited entry",
+			tamper: func(entries []AuditEntry) []AuditEntry {
+				entries[1].Actor = "mallory"
+				return entries
+			},
+			seq:    2,
+			reason: "entry hash does not match its contents",
+		},
+		{
+			name: "edited and rehashed entry",
+			tamper: func(entries []AuditEntry) []AuditEntry {
+				entries[1].Status = 204
+				entries[1].Hash = entries[1].ComputeHash()
+				return entries
+			},
+			seq:    3,
+			reason: "previous hash does not match",
+		},
+		{
+			name: "removed entry",
+			tamper: func(entries []AuditEntry) []AuditEntry {
+				return append(entries[:1], entries[2:]...)
+			},
+			seq:    2,
+			reason: "found entry 3 instead",
+		},
+		{
+			name: "reordered entries",
+			tamper: func(entries []AuditEntry) []AuditEntry {
+				entries[1], entries[2] = entries[2], entries[1]
+				return entries
+			},
+			seq:    2,
+			reason: "found entry 3 instead",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyAuditChain(tt.tamper(testAuditChain()))
+			if assert.IsType(t, &AuditChainError{}, err) {
+				assert.Equal(t, tt.seq, err.(*AuditChainError).Seq)
+				assert.Equal(t, tt.reason, err.(*AuditChainError).Reason)
+			}
+		})
+	}
+}
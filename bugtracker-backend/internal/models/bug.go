@@ -1,55 +1,107 @@
 package models
 
-import (
-	"fmt"
-	"time"
+import "time"
+
+// Priorities a bug may have, from least to most severe.
+const (
+	PriorityLow    = "Low"
+	PriorityMedium = "Medium"
+	PriorityHigh   = "High"
 )
 
+var Priorities = []string{PriorityLow, PriorityMedium, PriorityHigh}
+
+// PriorityRank orders priorities by severity rather than alphabetically: 1
+// for the least severe of Priorities, counting up, and 0 for anything else.
+func PriorityRank(priority string) int {
+	for i, p := range Priorities {
+		if p == priority {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// DefaultPriority is given to bugs created without one.
+const DefaultPriority = PriorityMedium
+
 type Bug struct {
-	ID          int       `json:"id"`
+	ID int `json:"id"`
+	// Project is the key of the project the bug belongs to, and Number
+	// counts the bugs of that project. Key joins them, as in WEB-42. None
+	// of them change after the bug is created.
+	Project string `json:"project"`
+	Number  int    `json:"number"`
+	Key     string `json:"key"`
+
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
 	Status      string    `json:"status"`
 	Priority    string    `json:"priority"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// DescriptionHTML is the description rendered from Markdown and
+	// sanitised. It is set whenever the description is written.
+	DescriptionHTML string `json:"description_html"`
+
+	// Reporter is the username of the user who filed the bug.
+	Reporter string `json:"reporter,omitempty"`
+
+	// Resolution says why the bug was resolved. It is set while the bug is
+	// in a resolved status and cleared when it is reopened.
+	Resolution string     `json:"resolution,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	// ReopenCount counts moves from a resolved status back to an
+	// unresolved one.
+	ReopenCount int `json:"reopen_count"`
+
+	// Version starts at 1 and is incremented by every update. It is the
+	// bug's ETag and guards against overwriting concurrent edits.
+	Version int `json:"version"`
+
+	// DeletedAt is set while the bug is in the trash. Trashed bugs are
+	// hidden from everything but the trash and purged after a retention
+	// period.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy string     `json:"deleted_by,omitempty"`
+
+	// CommentCount is filled in when a bug is read and is not stored. It
+	// is not part of the version, since comments do not change the bug,
+	// but it is part of the bug's ETag.
+	CommentCount int `json:"comment_count"`
+}
+
+// Resolutions a bug may be given when it is resolved.
+const (
+	ResolutionFixed           = "Fixed"
+	ResolutionWontFix         = "Won't Fix"
+	ResolutionDuplicate       = "Duplicate"
+	ResolutionCannotReproduce = "Cannot Reproduce"
+	ResolutionWorksAsDesigned = "Works As Designed"
+)
+
+var Resolutions = []string{
+	ResolutionFixed,
+	ResolutionWontFix,
+	ResolutionDuplicate,
+	ResolutionCannotReproduce,
+	ResolutionWorksAsDesigned,
 }
 
 type CreateBugRequest struct {
+	// Project is only read when a bug is created, and defaults to
+	// DefaultProjectKey.
+	Project     string `json:"project,omitempty"`
 	Title       string `json:"title"`
 	Description string `json:"description"`
 	Status      string `json:"status"`
 	Priority    string `json:"priority"`
+	Resolution  string `json:"resolution,omitempty"`
 }
 
-func (b *Bug) Validate() error {
-	if b.Title == "" {
-		return fmt.Errorf("title is required")
-	}
-	if !isValidPriority(b.Priority) {
-		return fmt.Errorf("invalid priority")
-	}
-	if !isValidStatus(b.Status) {
-		return fmt.Errorf("invalid status")
-	}
-	return nil
-}
-
-func (r *CreateBugRequest) Validate() error {
-	if r.Title == "" {
-		return fmt.Errorf("title is required")
-	}
-	return nil
-}
-
-func isValidPriority(p string) bool {
-	validPriorities := []string{"Low", "Medium", "High"}
-	return contains(validPriorities, p)
-}
-
-func isValidStatus(s string) bool {
-	validStatuses := []string{"Open", "In Progress", "Closed"}
-	return contains(validStatuses, s)
+func IsValidResolution(r string) bool {
+	return contains(Resolutions, r)
 }
 
 func contains(slice []string, item string) bool {
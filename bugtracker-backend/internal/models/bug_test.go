@@ -7,6 +7,19 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// statusList is a StatusSet for tests in which only "Closed" is resolved.
+type statusList []string
+
+func (l statusList) HasStatus(status string) bool {
+	return contains(l, status)
+}
+
+func (l statusList) IsResolved(status string) bool {
+	return status == "Closed"
+}
+
+var testStatuses = statusList{"Open", "In Progress", "Closed"}
+
 func TestBugValidation(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -58,51 +71,33 @@ func TestBugValidation(t *testing.T) {
 			isValid: false,
 			errMsg:  "invalid status",
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := tt.bug.Validate()
-			if tt.isValid {
-				assert.NoError(t, err)
-			} else {
-				assert.Error(t, err)
-				assert.Contains(t, err.Error(), tt.errMsg)
-			}
-		})
-	}
-}
-
-func TestCreateBugRequest(t *testing.T) {
-	tests := []struct {
-		name    string
-		request CreateBugRequest
-		isValid bool
-		errMsg  string
-	}{
 		{
-			name: "Valid request",
-			request: CreateBugRequest{
-				Title:       "Test Bug",
-				Description: "Test Description",
-				Priority:    "High",
-				Status:      "Open",
+			name: "Invalid resolution",
+			bug: Bug{
+				Title:      "Test Bug",
+				Priority:   "High",
+				Status:     "Closed",
+				Resolution: "Sorted",
 			},
-			isValid: true,
+			isValid: false,
+			errMsg:  `invalid resolution "Sorted"`,
 		},
 		{
-			name: "Missing title",
-			request: CreateBugRequest{
-				Description: "Test Description",
+			name: "Resolution on unresolved status",
+			bug: Bug{
+				Title:      "Test Bug",
+				Priority:   "High",
+				Status:     "Open",
+				Resolution: ResolutionFixed,
 			},
 			isValid: false,
-			errMsg:  "title is required",
+			errMsg:  "resolution is only allowed for resolved statuses",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := tt.request.Validate()
+			err := tt.bug.Validate(testStatuses)
 			if tt.isValid {
 				assert.NoError(t, err)
 			} else {
@@ -112,3 +107,55 @@ func TestCreateBugRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestBugValidationReportsEveryField(t *testing.T) {
+	bug := Bug{Priority: "urgent", Status: "Done"}
+
+	err := bug.Validate(testStatuses)
+
+	var verr *ValidationError
+	if assert.ErrorAs(t, err, &verr) {
+		assert.Equal(t, []FieldError{
+			{Field: "title", Message: "title is required"},
+			{Field: "priority", Message: `invalid priority "urgent": must be one of Low, Medium, High`},
+			{Field: "status", Message: `invalid status "Done"`},
+		}, verr.Fields)
+	}
+	assert.EqualError(t, err, `title is required; invalid priority "urgent": must be one of Low, Medium, High; invalid status "Done"`)
+}
+
+func TestBugValidateNew(t *testing.T) {
+	bug := Bug{Title: "Test Bug", Status: "Open", Priority: "Low"}
+	assert.NoError(t, bug.ValidateNew(testStatuses, "Open"))
+
+	bug.Status, bug.Resolution = "Closed", ResolutionDuplicate
+	err := bug.ValidateNew(testStatuses, "Open")
+	var verr *ValidationError
+	if assert.ErrorAs(t, err, &verr) {
+		assert.Equal(t, []FieldError{{Field: "status", Message: `new bugs start as "Open"`}}, verr.Fields)
+	}
+
+	// Unknown statuses are reported once.
+	bug.Status, bug.Resolution = "Done", ""
+	assert.EqualError(t, bug.ValidateNew(testStatuses, "Open"), `invalid status "Done"`)
+}
+
+func TestBugApplyDefaults(t *testing.T) {
+	bug := Bug{Title: "Test Bug"}
+	bug.ApplyDefaults("Open")
+	assert.Equal(t, "Open", bug.Status)
+	assert.Equal(t, DefaultPriority, bug.Priority)
+
+	bug = Bug{Title: "Test Bug", Status: "Closed", Priority: "Low"}
+	bug.ApplyDefaults("Open")
+	assert.Equal(t, "Closed", bug.Status)
+	assert.Equal(t, "Low", bug.Priority)
+}
+
+func TestPriorityRank(t *testing.T) {
+	assert.Equal(t, 1, PriorityRank(PriorityLow))
+	assert.Equal(t, 2, PriorityRank(PriorityMedium))
+	assert.Equal(t, 3, PriorityRank(PriorityHigh))
+	assert.Equal(t, 0, PriorityRank("high"))
+	assert.Equal(t, 0, PriorityRank(""))
+}
@@ -11,11 +11,91 @@ type Comment struct {
 	Content   string    `json:"content"`
 	Author    string    `json:"author"`
 	CreatedAt time.Time `json:"createdAt"`
+
+	// ContentHTML is the content rendered from Markdown and sanitised. It
+	// is set whenever the content is written.
+	ContentHTML string `json:"contentHtml"`
+
+	// ParentID is the comment this one replies to, or 0 for a top-level
+	// comment. Depth counts the replies above it, starting at 0.
+	ParentID int `json:"parentId,omitempty"`
+	Depth    int `json:"depth"`
+
+	// UpdatedAt is set when the comment is edited. Earlier versions of the
+	// content are kept as revisions.
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	Edited    bool       `json:"edited"`
+
+	// A deleted comment stays in its place as a tombstone and DeletedAt
+	// records when. Its content and revisions are kept, but not shown, so
+	// that it can be restored until the trash is purged.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	DeletedBy string     `json:"deletedBy,omitempty"`
+}
+
+// HideDeletedContent blanks the content of a deleted comment before it is
+// sent to a client.
+func (c *Comment) HideDeletedContent() {
+	if c.DeletedAt != nil {
+		c.Content = ""
+		c.ContentHTML = ""
+	}
 }
 
+// CommentRevision is the content a comment had before an edit.
+type CommentRevision struct {
+	CommentID int    `json:"commentId"`
+	Revision  int    `json:"revision"`
+	Content   string `json:"content"`
+	// CreatedAt is when this content was written: when the comment was
+	// created for the first revision, at the previous edit for later ones.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreateCommentRequest adds a comment. Its author is the signed-in user.
 type CreateCommentRequest struct {
+	Content  string `json:"content"`
+	ParentID int    `json:"parentId,omitempty"`
+}
+
+// CommentNode is a comment with its replies, for returning a discussion as
+// a tree.
+type CommentNode struct {
+	Comment
+	Replies []*CommentNode `json:"replies"`
+}
+
+// CommentTree arranges comments into threads. Replies keep the order of
+// comments, and a reply whose parent is not among them becomes a root.
+func CommentTree(comments []Comment) []*CommentNode {
+	nodes := make(map[int]*CommentNode, len(comments))
+	for _, c := range comments {
+		nodes[c.ID] = &CommentNode{Comment: c, Replies: []*CommentNode{}}
+	}
+
+	roots := []*CommentNode{}
+	for _, c := range comments {
+		node := nodes[c.ID]
+		if parent, ok := nodes[c.ParentID]; ok && c.ParentID != c.ID {
+			parent.Replies = append(parent.Replies, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
+
+// UpdateCommentRequest is the body of PUT and PATCH requests for a comment.
+// Only the content can be changed.
+type UpdateCommentRequest struct {
 	Content string `json:"content"`
-	Author  string `json:"author"`
+}
+
+func (r *UpdateCommentRequest) Validate() error {
+	if r.Content == "" {
+		return fmt.Errorf("content is required")
+	}
+	return nil
 }
 
 func (c *Comment) Validate() error {
@@ -32,11 +112,11 @@ func (c *Comment) Validate() error {
 }
 
 func (r *CreateCommentRequest) Validate() error {
-	if r.Author == "" {
-		return fmt.Errorf("author is required")
-	}
 	if r.Content == "" {
 		return fmt.Errorf("content is required")
 	}
+	if r.ParentID < 0 {
+		return fmt.Errorf("invalid parent comment ID")
+	}
 	return nil
 }
@@ -77,24 +77,13 @@ func TestCreateCommentRequest(t *testing.T) {
 		{
 			name: "Valid request",
 			request: CreateCommentRequest{
-				Author:  "Test Author",
 				Content: "Test Content",
 			},
 			isValid: true,
 		},
 		{
-			name: "Missing author",
-			request: CreateCommentRequest{
-				Content: "Test Content",
-			},
-			isValid: false,
-			errMsg:  "author is required",
-		},
-		{
-			name: "Missing content",
-			request: CreateCommentRequest{
-				Author: "Test Author",
-			},
+			name:    "Missing content",
+			request: CreateCommentRequest{},
 			isValid: false,
 			errMsg:  "content is required",
 		},
@@ -112,3 +101,36 @@ func TestCreateCommentRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestCommentTree(t *testing.T) {
+	comments := []Comment{
+		{ID: 1, Content: "root"},
+		{ID: 2, Content: "second root"},
+		{ID: 3, ParentID: 1, Depth: 1, Content: "reply"},
+		{ID: 4, ParentID: 3, Depth: 2, Content: "nested reply"},
+		{ID: 5, ParentID: 1, Depth: 1, Content: "later reply"},
+		{ID: 6, ParentID: 42, Depth: 1, Content: "lost reply"},
+	}
+
+	var render func(nodes []*CommentNode) []string
+	render = func(nodes []*CommentNode) []string {
+		out := []string{}
+		for _, n := range nodes {
+			out = append(out, n.Content)
+			for _, r := range render(n.Replies) {
+				out = append(out, "  "+r)
+			}
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{
+		"root",
+		"  reply",
+		"    nested reply",
+		"  later reply",
+		"second root",
+		"lost reply",
+	}, render(CommentTree(comments)))
+	assert.Empty(t, CommentTree(nil))
+}
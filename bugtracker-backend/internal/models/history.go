@@ -0,0 +1,74 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// Actions recorded in a bug's history.
+const (
+	HistoryCreated   = "created"
+	HistoryUpdated   = "updated"
+	HistoryDeleted   = "deleted"
+	HistoryRestored  = "restored"
+	HistoryCommented = "commented"
+	// HistoryCommentEdited, HistoryCommentDeleted and
+	// HistoryCommentRestored name the comment in CommentID.
+	HistoryCommentEdited   = "comment_edited"
+	HistoryCommentDeleted  = "comment_deleted"
+	HistoryCommentRestored = "comment_restored"
+)
+
+// HistoryEntry records one change to a bug: who made it, when, and the old
+// and new value of every field it touched.
+type HistoryEntry struct {
+	ID        int           `json:"id"`
+	BugID     int           `json:"bug_id"`
+	Action    string        `json:"action"`
+	Actor     string        `json:"actor,omitempty"`
+	At        time.Time     `json:"at"`
+	CommentID int           `json:"comment_id,omitempty"`
+	Changes   []FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange is the old and new value of one field, rendered as text.
+// Empty means the field was unset.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// DiffBugs lists the user-visible fields that differ between two versions
+// of a bug. Bookkeeping such as updated_at and version is left out. Diffing
+// against an empty Bug lists the fields a new bug was created with.
+func DiffBugs(old, new *Bug) []FieldChange {
+	fields := []struct {
+		name     string
+		old, new string
+	}{
+		{"project", old.Project, new.Project},
+		{"title", old.Title, new.Title},
+		{"description", old.Description, new.Description},
+		{"status", old.Status, new.Status},
+		{"priority", old.Priority, new.Priority},
+		{"resolution", old.Resolution, new.Resolution},
+		{"resolved_at", formatOptionalTime(old.ResolvedAt), formatOptionalTime(new.ResolvedAt)},
+		{"reopen_count", strconv.Itoa(old.ReopenCount), strconv.Itoa(new.ReopenCount)},
+	}
+
+	var changes []FieldChange
+	for _, f := range fields {
+		if f.old != f.new {
+			changes = append(changes, FieldChange{Field: f.name, Old: f.old, New: f.new})
+		}
+	}
+	return changes
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffBugs(t *testing.T) {
+	resolvedAt := time.Date(2025, 2, 12, 16, 0, 0, 0, time.UTC)
+	old := &Bug{ID: 1, Title: "Crash", Status: "Open", Priority: "High", Version: 1}
+	updated := *old
+	updated.Status = "Resolved"
+	updated.Resolution = ResolutionFixed
+	updated.ResolvedAt = &resolvedAt
+	updated.Version = 2
+	updated.UpdatedAt = resolvedAt
+
+	assert.Equal(t, []FieldChange{
+		{Field: "status", Old: "Open", New: "Resolved"},
+		{Field: "resolution", Old: "", New: "Fixed"},
+		{Field: "resolved_at", Old: "", New: "2025-02-12T16:00:00Z"},
+	}, DiffBugs(old, &updated))
+
+	assert.Empty(t, DiffBugs(old, old))
+
+	assert.Equal(t, []FieldChange{
+		{Field: "title", Old: "", New: "Crash"},
+		{Field: "status", Old: "", New: "Open"},
+		{Field: "priority", Old: "", New: "High"},
+	}, DiffBugs(&Bug{}, old))
+}
@@ -0,0 +1,13 @@
+package models
+
+import "bugtracker-backend/internal/markdown"
+
+// RenderDescription sets DescriptionHTML from the Markdown description.
+func (b *Bug) RenderDescription() {
+	b.DescriptionHTML = markdown.Render(b.Description)
+}
+
+// RenderContent sets ContentHTML from the Markdown content.
+func (c *Comment) RenderContent() {
+	c.ContentHTML = markdown.Render(c.Content)
+}
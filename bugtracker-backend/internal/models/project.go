@@ -0,0 +1,81 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Project groups bugs under a short key, such as WEB or API, which prefixes
+// the keys of its bugs. Keys cannot be changed once a project exists.
+type Project struct {
+	ID          int       `json:"id"`
+	Key         string    `json:"key"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DefaultProjectKey names the project bugs are filed in when they do not
+// name one, which also holds the bugs filed before projects existed.
+const DefaultProjectKey = "BUG"
+
+// DefaultProject returns the project every store starts with.
+func DefaultProject() *Project {
+	return &Project{Key: DefaultProjectKey, Name: "Default"}
+}
+
+var projectKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]{1,9}$`)
+
+// NormalizeProjectKey returns the stored form of a project key.
+func NormalizeProjectKey(key string) string {
+	return strings.ToUpper(strings.TrimSpace(key))
+}
+
+// BugKey returns the key of a project's bug, such as WEB-42.
+func BugKey(project string, number int) string {
+	return project + "-" + strconv.Itoa(number)
+}
+
+// ParseBugKey splits a bug key such as WEB-42, in any case, into its
+// project key and number.
+func ParseBugKey(key string) (project string, number int, err error) {
+	project, n, ok := strings.Cut(key, "-")
+	project = NormalizeProjectKey(project)
+	if ok && projectKeyPattern.MatchString(project) {
+		if number, err = strconv.Atoi(n); err == nil && number > 0 {
+			return project, number, nil
+		}
+	}
+	return "", 0, fmt.Errorf("invalid bug key %q", key)
+}
+
+type CreateProjectRequest struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Validate normalises the key and checks every field, returning a
+// *ValidationError listing all problems, or nil.
+func (r *CreateProjectRequest) Validate() error {
+	verr := &ValidationError{}
+	r.Key = NormalizeProjectKey(r.Key)
+	r.Name = strings.TrimSpace(r.Name)
+	r.Description = strings.TrimSpace(r.Description)
+	switch {
+	case r.Key == "":
+		verr.add("key", "key is required")
+	case !projectKeyPattern.MatchString(r.Key):
+		verr.add("key", "invalid key %q: use 2 to 10 letters and digits, starting with a letter", r.Key)
+	}
+	switch {
+	case r.Name == "":
+		verr.add("name", "name is required")
+	case len(r.Name) > 100:
+		verr.add("name", "name must be at most 100 characters")
+	}
+	return verr.err()
+}
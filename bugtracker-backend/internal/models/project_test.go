@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBugKey(t *testing.T) {
+	project, number, err := ParseBugKey("web-42")
+	assert.NoError(t, err)
+	assert.Equal(t, "WEB", project)
+	assert.Equal(t, 42, number)
+	assert.Equal(t, "WEB-42", BugKey(project, number))
+
+	for _, key := range []string{"", "42", "WEB", "WEB-", "WEB-0", "WEB-x", "W-1", "1WEB-1", "WEB-1-2"} {
+		_, _, err := ParseBugKey(key)
+		assert.Error(t, err, key)
+	}
+}
+
+func TestCreateProjectRequest(t *testing.T) {
+	req := CreateProjectRequest{Key: " api ", Name: " Public API "}
+	assert.NoError(t, req.Validate())
+	assert.Equal(t, "API", req.Key)
+	assert.Equal(t, "Public API", req.Name)
+
+	req = CreateProjectRequest{Key: "web-app"}
+	err := req.Validate()
+	if assert.Error(t, err) {
+		var verr *ValidationError
+		assert.ErrorAs(t, err, &verr)
+		assert.Len(t, verr.Fields, 2)
+	}
+}
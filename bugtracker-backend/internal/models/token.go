@@ -0,0 +1,72 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Scopes limit what an API token may be used for. A scope never grants more
+// than its user may do: the admin scope only matters for admins.
+const (
+	// ScopeRead allows only reading.
+	ScopeRead = "read"
+	// ScopeWrite allows everything a user may do except admin operations.
+	ScopeWrite = "write"
+	// ScopeAdmin allows everything, as does a login session.
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists every valid scope, narrowest first.
+var Scopes = []string{ScopeRead, ScopeWrite, ScopeAdmin}
+
+// APIToken is a long-lived credential for scripts and CI. Only the hash of
+// the token is stored; the token itself is shown once, when it is created.
+type APIToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Scope      string     `json:"scope"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+
+	TokenHash string `json:"-"`
+}
+
+// Expired reports whether the token is no longer valid at now. Tokens
+// without an expiry time never expire.
+func (t *APIToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+type CreateAPITokenRequest struct {
+	Name      string     `json:"name"`
+	Scope     string     `json:"scope"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Validate checks every field against the current time, returning a
+// *ValidationError listing all problems, or nil.
+func (r *CreateAPITokenRequest) Validate(now time.Time) error {
+	verr := &ValidationError{}
+	r.Name = strings.TrimSpace(r.Name)
+	switch {
+	case r.Name == "":
+		verr.add("name", "name is required")
+	case len(r.Name) > 100:
+		verr.add("name", "name must be at most 100 characters")
+	}
+	if !contains(Scopes, r.Scope) {
+		verr.add("scope", "invalid scope %q: must be one of %s", r.Scope, strings.Join(Scopes, ", "))
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(now) {
+		verr.add("expires_at", "expires_at must be in the future")
+	}
+	return verr.err()
+}
+
+// CreateAPITokenResponse is the new token together with its details.
+type CreateAPITokenResponse struct {
+	APIToken
+	Token string `json:"token"`
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Roles decide what a user may do; package rbac holds the permissions of
+// each. Every role may do everything the roles before it may.
+const (
+	// RoleViewer may only read.
+	RoleViewer = "viewer"
+	// RoleReporter may also report, edit and comment on bugs.
+	RoleReporter = "reporter"
+	// RoleDeveloper may also move bugs through the workflow and delete
+	// them.
+	RoleDeveloper = "developer"
+	// RoleAdmin may do everything, including managing users.
+	RoleAdmin = "admin"
+)
+
+// Roles lists every role, least privileged first.
+var Roles = []string{RoleViewer, RoleReporter, RoleDeveloper, RoleAdmin}
+
+// DefaultRole is given to users created without a role, and to users
+// stored before roles existed.
+const DefaultRole = RoleReporter
+
+// User is an account that can sign in to the tracker. Usernames are stored
+// in lower case and identify the user as the author of comments and the
+// reporter of bugs.
+type User struct {
+	ID          int       `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name,omitempty"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// PasswordHash is the bcrypt hash of the user's password. It is never
+	// sent to clients.
+	PasswordHash string `json:"-"`
+}
+
+// Session is a signed-in client. The token handed to the client is stored
+// only as its hash.
+type Session struct {
+	TokenHash string    `json:"token_hash"`
+	UserID    int       `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Expired reports whether the session is no longer valid at now.
+func (s *Session) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// Identity links a user of an OpenID Connect provider, named by the
+// provider's issuer and the user's subject there, to a local user.
+type Identity struct {
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	UserID    int       `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MinPasswordLength is the shortest password accepted for a new user.
+const MinPasswordLength = 8
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+type CreateUserRequest struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Role        string `json:"role,omitempty"`
+	Password    string `json:"password"`
+}
+
+// NormalizeUsername returns the stored form of a username.
+func NormalizeUsername(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ValidUsername reports whether a normalised username may be used.
+func ValidUsername(name string) bool {
+	return usernamePattern.MatchString(name)
+}
+
+// Validate normalises the username, defaults the role and checks every
+// field, returning a *ValidationError listing all problems, or nil.
+func (r *CreateUserRequest) Validate() error {
+	verr := &ValidationError{}
+	r.Username = NormalizeUsername(r.Username)
+	r.DisplayName = strings.TrimSpace(r.DisplayName)
+	switch {
+	case r.Username == "":
+		verr.add("username", "username is required")
+	case !ValidUsername(r.Username):
+		verr.add("username", "invalid username %q: use up to 64 letters, digits, '.', '_' or '-'", r.Username)
+	}
+	if r.Role == "" {
+		r.Role = DefaultRole
+	}
+	validateRole(verr, r.Role)
+	if len(r.Password) < MinPasswordLength {
+		verr.add("password", "password must be at least %d characters", MinPasswordLength)
+	}
+	return verr.err()
+}
+
+func validateRole(verr *ValidationError, role string) {
+	if !contains(Roles, role) {
+		verr.add("role", "invalid role %q: must be one of %s", role, strings.Join(Roles, ", "))
+	}
+}
+
+type SetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// Validate returns a *ValidationError unless Role names a role.
+func (r *SetRoleRequest) Validate() error {
+	verr := &ValidationError{}
+	validateRole(verr, r.Role)
+	return verr.err()
+}
+
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginResponse carries a new session token, to be sent as
+// "Authorization: Bearer <token>".
+type LoginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      *User     `json:"user"`
+}
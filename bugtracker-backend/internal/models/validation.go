@@ -0,0 +1,95 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError describes one invalid field of a request or record.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects every invalid field found by a validation pass.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) add(field, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns e if any field was invalid and nil otherwise.
+func (e *ValidationError) err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// StatusSet is the set of statuses a bug may have, normally the configured
+// workflow.
+type StatusSet interface {
+	HasStatus(status string) bool
+	IsResolved(status string) bool
+}
+
+// ApplyDefaults fills in the status and priority of a bug that has none.
+func (b *Bug) ApplyDefaults(initialStatus string) {
+	if b.Status == "" {
+		b.Status = initialStatus
+	}
+	if b.Priority == "" {
+		b.Priority = DefaultPriority
+	}
+}
+
+// Validate checks every field of the bug and returns a *ValidationError
+// listing all problems, or nil. Whether a resolved bug must have a
+// resolution is a workflow rule checked when its status changes, so bugs
+// resolved before resolutions existed remain valid.
+func (b *Bug) Validate(statuses StatusSet) error {
+	return b.validate(statuses).err()
+}
+
+// ValidateNew checks a bug about to be filed like Validate, and that it is
+// in the workflow's initial status: bugs only move on through the workflow
+// once they exist.
+func (b *Bug) ValidateNew(statuses StatusSet, initialStatus string) error {
+	verr := b.validate(statuses)
+	if statuses.HasStatus(b.Status) && b.Status != initialStatus {
+		verr.add("status", "new bugs start as %q", initialStatus)
+	}
+	return verr.err()
+}
+
+func (b *Bug) validate(statuses StatusSet) *ValidationError {
+	verr := &ValidationError{}
+	if strings.TrimSpace(b.Title) == "" {
+		verr.add("title", "title is required")
+	}
+	if !contains(Priorities, b.Priority) {
+		verr.add("priority", "invalid priority %q: must be one of %s", b.Priority, strings.Join(Priorities, ", "))
+	}
+	if !statuses.HasStatus(b.Status) {
+		verr.add("status", "invalid status %q", b.Status)
+	}
+	if b.Resolution != "" {
+		switch {
+		case !IsValidResolution(b.Resolution):
+			verr.add("resolution", "invalid resolution %q: must be one of %s", b.Resolution, strings.Join(Resolutions, ", "))
+		case statuses.HasStatus(b.Status) && !statuses.IsResolved(b.Status):
+			verr.add("resolution", "resolution is only allowed for resolved statuses")
+		}
+	}
+	return verr
+}
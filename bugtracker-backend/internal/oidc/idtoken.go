@@ -0,0 +1,214 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ErrInvalidIDToken is wrapped by every ID token validation failure.
+var ErrInvalidIDToken = errors.New("invalid ID token")
+
+// clockSkew is how far the provider's clock may be ahead of or behind ours.
+const clockSkew = time.Minute
+
+// Claims are the validated claims of an ID token.
+type Claims struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	// Raw holds every claim, for those the tracker is configured to read
+	// such as the groups claim.
+	Raw map[string]interface{}
+}
+
+// Strings returns a claim holding a string or a list of strings, such as a
+// groups claim, as a list.
+func (c *Claims) Strings(name string) []string {
+	switch v := c.Raw[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var list []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// idTokenClaims are the registered claims checked by Verify.
+type idTokenClaims struct {
+	Issuer            string          `json:"iss"`
+	Subject           string          `json:"sub"`
+	Audience          audience        `json:"aud"`
+	AuthorizedParty   string          `json:"azp"`
+	Expiry            float64         `json:"exp"`
+	IssuedAt          float64         `json:"iat"`
+	Nonce             string          `json:"nonce"`
+	Email             string          `json:"email"`
+	EmailVerified     json.RawMessage `json:"email_verified"`
+	Name              string          `json:"name"`
+	PreferredUsername string          `json:"preferred_username"`
+}
+
+// audience is an aud claim, which may be one string or a list.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a audience) contains(s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks an ID token's signature against the provider's keys and
+// its issuer, audience, lifetime and nonce, returning its claims.
+func (p *Provider) Verify(ctx context.Context, rawIDToken, nonce string) (*Claims, error) {
+	md, keys, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(rawIDToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a JWS compact serialization", ErrInvalidIDToken)
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidIDToken, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidIDToken, err)
+	}
+	key, err := keys.key(ctx, header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	var claims idTokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidIDToken, err)
+	}
+	var raw map[string]interface{}
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidIDToken, err)
+	}
+
+	now := p.now()
+	switch {
+	// The configured issuer may differ from the discovered one by a
+	// trailing slash; ID tokens carry the discovered one exactly.
+	case claims.Issuer != md.Issuer:
+		return nil, fmt.Errorf("%w: issued by %q, not %q", ErrInvalidIDToken, claims.Issuer, md.Issuer)
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	case !claims.Audience.contains(p.cfg.ClientID):
+		return nil, fmt.Errorf("%w: not issued to this client", ErrInvalidIDToken)
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != "" && claims.AuthorizedParty != p.cfg.ClientID:
+		return nil, fmt.Errorf("%w: authorized party is %q", ErrInvalidIDToken, claims.AuthorizedParty)
+	case claims.Expiry == 0 || !now.Before(unixTime(claims.Expiry).Add(clockSkew)):
+		return nil, fmt.Errorf("%w: expired", ErrInvalidIDToken)
+	case claims.IssuedAt != 0 && unixTime(claims.IssuedAt).After(now.Add(clockSkew)):
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidIDToken)
+	case claims.Nonce != nonce:
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidIDToken)
+	}
+
+	return &Claims{
+		Issuer:            claims.Issuer,
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		EmailVerified:     isTrue(claims.EmailVerified),
+		Name:              claims.Name,
+		PreferredUsername: claims.PreferredUsername,
+		Raw:               raw,
+	}, nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func unixTime(secs float64) time.Time {
+	return time.Unix(int64(secs), 0)
+}
+
+// isTrue reads a boolean claim, which some providers send as a string.
+func isTrue(v json.RawMessage) bool {
+	s := string(v)
+	return s == "true" || s == `"true"`
+}
+
+// verifySignature checks a JWS signature. Only the asymmetric algorithms
+// providers sign ID tokens with are accepted, so that neither "none" nor a
+// symmetric algorithm keyed with the public key can pass.
+func verifySignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	sum := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("RS256 token signed with a non-RSA key")
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig); err != nil {
+			return errors.New("bad signature")
+		}
+		return nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return errors.New("ES256 token signed with a non-P-256 key")
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, sum[:], r, s) {
+			return errors.New("bad signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported signing algorithm %q", alg)
+}
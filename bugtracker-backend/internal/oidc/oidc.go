@@ -0,0 +1,214 @@
+// Package oidc signs users in through an OpenID Connect provider with the
+// authorization code flow: it discovers the provider's endpoints, protects
+// the flow with PKCE, exchanges codes for tokens and validates ID tokens
+// against the provider's cached signing keys.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"bugtracker-backend/internal/auth"
+)
+
+// Config names the provider and how the tracker is registered with it.
+type Config struct {
+	// Issuer is the provider's issuer URL; discovery reads
+	// Issuer + "/.well-known/openid-configuration".
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the tracker's callback URL registered with the
+	// provider.
+	RedirectURL string
+	// Scopes are requested in addition to "openid".
+	Scopes []string
+}
+
+// Metadata is the part of the provider's discovery document the flow uses.
+type Metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	SigningAlgs           []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// Provider runs the authorization code flow against one provider. Discovery
+// happens on first use and is retried until it succeeds, so the tracker
+// starts even while the provider is unreachable.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+	now    func() time.Time
+
+	mu       sync.Mutex
+	metadata *Metadata
+	keys     *keySet
+}
+
+// NewProvider returns a Provider for cfg that makes its requests with
+// client, or http.DefaultClient if client is nil.
+func NewProvider(cfg Config, client *http.Client) *Provider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	return &Provider{cfg: cfg, client: client, now: time.Now}
+}
+
+// discover returns the provider's metadata, fetching it the first time.
+func (p *Provider) discover(ctx context.Context) (*Metadata, *keySet, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, p.keys, nil
+	}
+
+	var md Metadata
+	if err := p.getJSON(ctx, p.cfg.Issuer+"/.well-known/openid-configuration", &md); err != nil {
+		return nil, nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if strings.TrimSuffix(md.Issuer, "/") != p.cfg.Issuer {
+		return nil, nil, fmt.Errorf("oidc discovery: issuer %q does not match %q", md.Issuer, p.cfg.Issuer)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return nil, nil, errors.New("oidc discovery: document lacks an authorization, token or jwks_uri endpoint")
+	}
+	p.metadata = &md
+	p.keys = newKeySet(md.JWKSURI, p.getJSON, p.now)
+	return p.metadata, p.keys, nil
+}
+
+// getJSON fetches url and decodes its JSON body into v.
+func (p *Provider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// LoginState is what the tracker remembers between sending the browser to
+// the provider and the provider sending it back.
+type LoginState struct {
+	// State guards the callback against forged requests.
+	State string `json:"state"`
+	// Nonce ties the ID token to this login.
+	Nonce string `json:"nonce"`
+	// Verifier is the PKCE code verifier, sent only with the code.
+	Verifier string `json:"verifier"`
+}
+
+// NewLoginState returns fresh random values for one login.
+func NewLoginState() (*LoginState, error) {
+	var values [3]string
+	for i := range values {
+		v, err := auth.NewToken()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return &LoginState{State: values[0], Nonce: values[1], Verifier: values[2]}, nil
+}
+
+// PKCEChallenge returns the S256 code challenge for verifier.
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the provider URL to send the browser to for login.
+func (p *Provider) AuthCodeURL(ctx context.Context, login *LoginState) (string, error) {
+	md, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(append([]string{"openid"}, p.cfg.Scopes...), " ")},
+		"state":                 {login.State},
+		"nonce":                 {login.Nonce},
+		"code_challenge":        {PKCEChallenge(login.Verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(md.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return md.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// tokenResponse is the token endpoint's answer.
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange trades an authorization code for the provider's tokens and
+// returns the validated claims of the ID token.
+func (p *Provider) Exchange(ctx context.Context, code string, login *LoginState) (*Claims, error) {
+	md, _, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {login.Verifier},
+	}
+	if p.cfg.ClientSecret == "" {
+		form.Set("client_id", p.cfg.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc token exchange: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokens tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("oidc token exchange: %s: %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK || tokens.Error != "" {
+		return nil, fmt.Errorf("oidc token exchange: %s: %s %s", resp.Status, tokens.Error, tokens.ErrorDescription)
+	}
+	if tokens.IDToken == "" {
+		return nil, errors.New("oidc token exchange: response has no id_token")
+	}
+	return p.Verify(ctx, tokens.IDToken, login.Nonce)
+}
@@ -0,0 +1,211 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"bugtracker-backend/internal/oidc/oidctest"
+)
+
+func newTestProvider(idp *oidctest.Server) *Provider {
+	return NewProvider(Config{
+		Issuer:       idp.Issuer(),
+		ClientID:     idp.ClientID,
+		ClientSecret: idp.ClientSecret,
+		RedirectURL:  "http://tracker.test/api/auth/oidc/callback",
+		Scopes:       []string{"profile", "groups"},
+	}, nil)
+}
+
+// authorize follows the provider's authorization URL and returns the
+// parameters of its redirect back to the tracker.
+func authorize(t *testing.T, authURL string) url.Values {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "tracker.test", loc.Host)
+	return loc.Query()
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp := oidctest.NewServer("tracker", "s3cret")
+	defer idp.Close()
+	idp.SetClaims(map[string]interface{}{
+		"sub":                "abc123",
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"groups":             []string{"devs", "qa"},
+	})
+	p := newTestProvider(idp)
+	ctx := context.Background()
+
+	login, err := NewLoginState()
+	assert.NoError(t, err)
+	authURL, err := p.AuthCodeURL(ctx, login)
+	assert.NoError(t, err)
+	q, _ := url.ParseQuery(authURL[strings.Index(authURL, "?")+1:])
+	assert.Equal(t, "openid profile groups", q.Get("scope"))
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	assert.Equal(t, PKCEChallenge(login.Verifier), q.Get("code_challenge"))
+	assert.NotContains(t, authURL, login.Verifier)
+
+	back := authorize(t, authURL)
+	assert.Equal(t, login.State, back.Get("state"))
+
+	claims, err := p.Exchange(ctx, back.Get("code"), login)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", claims.Subject)
+	assert.Equal(t, "alice", claims.PreferredUsername)
+	assert.True(t, claims.EmailVerified)
+	assert.Equal(t, []string{"devs", "qa"}, claims.Strings("groups"))
+
+	// A code is only good once, and only with its verifier.
+	_, err = p.Exchange(ctx, back.Get("code"), login)
+	assert.ErrorContains(t, err, "invalid_grant")
+	back = authorize(t, authURL)
+	_, err = p.Exchange(ctx, back.Get("code"), &LoginState{Verifier: "guessed", Nonce: login.Nonce})
+	assert.ErrorContains(t, err, "PKCE")
+}
+
+func TestVerify(t *testing.T) {
+	idp := oidctest.NewServer("tracker", "s3cret")
+	defer idp.Close()
+	p := newTestProvider(idp)
+	ctx := context.Background()
+
+	valid := map[string]interface{}{"sub": "abc123", "nonce": "n"}
+	_, err := p.Verify(ctx, idp.IDToken(valid), "n")
+	assert.NoError(t, err)
+
+	with := func(k string, v interface{}) map[string]interface{} {
+		claims := map[string]interface{}{}
+		for k, v := range valid {
+			claims[k] = v
+		}
+		claims[k] = v
+		return claims
+	}
+	tests := []struct {
+		name   string
+		token  string
+		reason string
+	}{
+		{"wrong nonce", idp.IDToken(valid), "nonce"},
+		{"wrong issuer", idp.IDToken(with("iss", "https://evil.test")), "issued by"},
+		{"wrong audience", idp.IDToken(with("aud", "other-client")), "not issued to this client"},
+		{"several audiences", idp.IDToken(with("aud", []string{"tracker", "other"})), ""},
+		{"other authorized party", idp.IDToken(map[string]interface{}{
+			"sub": "abc123", "nonce": "n", "aud": []string{"tracker", "other"}, "azp": "other",
+		}), "authorized party"},
+		{"expired", idp.IDToken(with("exp", time.Now().Add(-time.Hour).Unix())), "expired"},
+		{"issued in the future", idp.IDToken(with("iat", time.Now().Add(time.Hour).Unix())), "future"},
+		{"no subject", idp.IDToken(with("sub", "")), "subject"},
+		{"not a JWT", "abc.def", "compact"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonce := "n"
+			if tt.name == "wrong nonce" {
+				nonce = "other"
+			}
+			_, err := p.Verify(ctx, tt.token, nonce)
+			if tt.reason == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrInvalidIDToken)
+			assert.ErrorContains(t, err, tt.reason)
+		})
+	}
+
+	t.Run("tampered claims", func(t *testing.T) {
+		parts := strings.Split(idp.IDToken(valid), ".")
+		parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin","nonce":"n"}`))
+		_, err := p.Verify(ctx, strings.Join(parts, "."), "n")
+		assert.ErrorContains(t, err, "bad signature")
+	})
+	t.Run("unsigned", func(t *testing.T) {
+		parts := strings.Split(idp.IDToken(valid), ".")
+		parts[0] = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+		_, err := p.Verify(ctx, parts[0]+"."+parts[1]+".", "n")
+		assert.ErrorContains(t, err, "unsupported signing algorithm")
+	})
+}
+
+func TestSigningKeysAreCached(t *testing.T) {
+	idp := oidctest.NewServer("tracker", "s3cret")
+	defer idp.Close()
+	p := newTestProvider(idp)
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	ctx := context.Background()
+	claims := map[string]interface{}{"sub": "abc123", "nonce": "n", "exp": now.Add(24 * time.Hour).Unix()}
+
+	for i := 0; i < 3; i++ {
+		_, err := p.Verify(ctx, idp.IDToken(claims), "n")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, idp.JWKSRequests())
+
+	// A token signed with a new key makes us fetch the keys again, but only
+	// once a minute.
+	idp.RotateKey()
+	_, err := p.Verify(ctx, idp.IDToken(claims), "n")
+	assert.ErrorContains(t, err, "unknown signing key")
+	assert.Equal(t, 1, idp.JWKSRequests())
+	now = now.Add(2 * time.Minute)
+	_, err = p.Verify(ctx, idp.IDToken(claims), "n")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, idp.JWKSRequests())
+
+	// The cache expires after an hour even if the key is known.
+	now = now.Add(2 * time.Hour)
+	_, err = p.Verify(ctx, idp.IDToken(claims), "n")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, idp.JWKSRequests())
+}
+
+func TestDiscoveryChecksIssuer(t *testing.T) {
+	idp := oidctest.NewServer("tracker", "s3cret")
+	defer idp.Close()
+	p := NewProvider(Config{Issuer: strings.Replace(idp.Issuer(), "127.0.0.1", "localhost", 1), ClientID: "tracker"}, nil)
+	_, err := p.AuthCodeURL(context.Background(), &LoginState{})
+	assert.ErrorContains(t, err, "does not match")
+
+	p = NewProvider(Config{Issuer: "http://127.0.0.1:1", ClientID: "tracker"}, nil)
+	_, err = p.AuthCodeURL(context.Background(), &LoginState{})
+	assert.ErrorContains(t, err, "oidc discovery")
+}
+
+func TestIssuerWithTrailingSlash(t *testing.T) {
+	idp := oidctest.NewServer("tracker", "s3cret")
+	defer idp.Close()
+	idp.SetIssuer(idp.URL + "/")
+	ctx := context.Background()
+
+	// The issuer may be configured with or without the slash; ID tokens
+	// carry it as the provider announces it.
+	for _, issuer := range []string{idp.URL + "/", idp.URL} {
+		p := NewProvider(Config{Issuer: issuer, ClientID: idp.ClientID}, nil)
+		claims, err := p.Verify(ctx, idp.IDToken(map[string]interface{}{"sub": "abc123", "nonce": "n"}), "n")
+		if assert.NoError(t, err) {
+			assert.Equal(t, idp.URL+"/", claims.Issuer)
+		}
+		_, err = p.Verify(ctx, idp.IDToken(map[string]interface{}{"sub": "abc123", "nonce": "n", "iss": idp.URL}), "n")
+		assert.ErrorIs(t, err, ErrInvalidIDToken)
+	}
+}
@@ -0,0 +1,264 @@
+// Package oidctest runs a mock OpenID Connect provider for tests. It
+// approves every authorization request at once, as if the user had signed
+// in, and issues RS256 ID tokens with the claims the test sets.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Server is a mock provider listening on a local address.
+type Server struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	mu     sync.Mutex
+	issuer string
+	key    *rsa.PrivateKey
+	keyID  string
+	claims map[string]interface{}
+	codes  map[string]authorization
+	// jwksRequests counts fetches of the key set.
+	jwksRequests int
+}
+
+// authorization is an issued code waiting to be exchanged.
+type authorization struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+}
+
+// NewServer starts a provider that accepts the given client. Call Close
+// when done.
+func NewServer(clientID, clientSecret string) *Server {
+	s := &Server{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		claims:       map[string]interface{}{"sub": "user-1"},
+		codes:        make(map[string]authorization),
+	}
+	s.RotateKey()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/jwks", s.jwks)
+	s.Server = httptest.NewServer(mux)
+	s.issuer = s.URL
+	return s
+}
+
+// Issuer returns the provider's issuer URL.
+func (s *Server) Issuer() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.issuer
+}
+
+// SetIssuer sets the issuer URL the provider announces and puts in ID
+// tokens, such as its URL with a trailing slash. Discovery is still served
+// from its URL.
+func (s *Server) SetIssuer(issuer string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.issuer = issuer
+}
+
+// SetClaims sets the claims of the signed-in user, such as sub,
+// preferred_username and groups, for the ID tokens issued from now on.
+func (s *Server) SetClaims(claims map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claims = claims
+}
+
+// RotateKey replaces the signing key with a new one under a new key ID.
+func (s *Server) RotateKey() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.key = key
+	s.keyID = fmt.Sprintf("key-%d", time.Now().UnixNano())
+}
+
+// JWKSRequests returns how many times the key set has been fetched.
+func (s *Server) JWKSRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jwksRequests
+}
+
+// IDToken signs an ID token with the given claims, adding iss, aud, iat and
+// exp unless they are set.
+func (s *Server) IDToken(claims map[string]interface{}) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sign(claims)
+}
+
+func (s *Server) sign(claims map[string]interface{}) string {
+	full := map[string]interface{}{
+		"iss": s.issuer,
+		"aud": s.ClientID,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		full[k] = v
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": s.keyID})
+	payload, _ := json.Marshal(full)
+	signed := encode(header) + "." + encode(payload)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, sum[:])
+	if err != nil {
+		panic(err)
+	}
+	return signed + "." + encode(sig)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.Issuer(),
+		"authorization_endpoint":                s.URL + "/authorize",
+		"token_endpoint":                        s.URL + "/token",
+		"jwks_uri":                              s.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// authorize signs the user in at once and redirects back with a code.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	switch {
+	case q.Get("client_id") != s.ClientID:
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	case q.Get("response_type") != "code":
+		http.Error(w, "unsupported response_type", http.StatusBadRequest)
+		return
+	case q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "":
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || !redirect.IsAbs() {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := encode(randomBytes())
+	s.mu.Lock()
+	s.codes[code] = authorization{redirectURI: q.Get("redirect_uri"), challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	s.mu.Unlock()
+
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if id != s.ClientID || secret != s.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := r.PostFormValue("code")
+	authz, ok := s.codes[code]
+	delete(s.codes, code)
+	switch {
+	case r.PostFormValue("grant_type") != "authorization_code" || !ok:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	case r.PostFormValue("redirect_uri") != authz.redirectURI:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "redirect_uri mismatch"})
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if encode(sum[:]) != authz.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	claims := map[string]interface{}{"nonce": authz.nonce}
+	for k, v := range s.claims {
+		claims[k] = v
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": encode(randomBytes()),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     s.sign(claims),
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jwksRequests++
+	pub := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": s.keyID,
+			"n":   encode(pub.N.Bytes()),
+			"e":   encode(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func randomBytes() []byte {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
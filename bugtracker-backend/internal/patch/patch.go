@@ -0,0 +1,152 @@
+// Package patch applies JSON Merge Patch (RFC 7396) and JSON Patch
+// (RFC 6902) documents to decoded JSON objects.
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Media types of the two patch formats.
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+var (
+	// ErrInvalidPatch is returned for a malformed patch document or one that
+	// refers to members that do not exist.
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrTestFailed is returned when a JSON Patch "test" operation does not
+	// match, in which case none of the operations are applied.
+	ErrTestFailed = errors.New("patch test failed")
+)
+
+// Merge applies an RFC 7396 merge patch to target and returns the result.
+// Members of patch replace those of target, null members remove them and
+// nested objects are merged recursively. target is modified in place.
+func Merge(target map[string]interface{}, data []byte) (map[string]interface{}, error) {
+	var p interface{}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	obj, ok := p.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: merge patch must be a JSON object", ErrInvalidPatch)
+	}
+	return mergeObject(target, obj), nil
+}
+
+func mergeObject(target, p map[string]interface{}) map[string]interface{} {
+	if target == nil {
+		target = make(map[string]interface{})
+	}
+	for k, v := range p {
+		switch v := v.(type) {
+		case nil:
+			delete(target, k)
+		case map[string]interface{}:
+			existing, _ := target[k].(map[string]interface{})
+			target[k] = mergeObject(existing, v)
+		default:
+			target[k] = v
+		}
+	}
+	return target
+}
+
+// Operation is one step of an RFC 6902 JSON Patch.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply applies an RFC 6902 JSON Patch to doc and returns the result. Only
+// members of the top-level object can be addressed, which is all a flat
+// resource needs. The operations are applied to a copy, so doc is left
+// untouched if any of them fails.
+func Apply(doc map[string]interface{}, data []byte) (map[string]interface{}, error) {
+	var ops []Operation
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	result := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		result[k] = v
+	}
+	for i, op := range ops {
+		if err := applyOperation(result, op); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return result, nil
+}
+
+func applyOperation(doc map[string]interface{}, op Operation) error {
+	key, err := memberName(op.Path)
+	if err != nil {
+		return err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return fmt.Errorf("%w: missing value", ErrInvalidPatch)
+		}
+		var value interface{}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		current, exists := doc[key]
+		if op.Op != "add" && !exists {
+			return fmt.Errorf("%w: no member at path", ErrInvalidPatch)
+		}
+		if op.Op == "test" {
+			if !reflect.DeepEqual(current, value) {
+				return ErrTestFailed
+			}
+			return nil
+		}
+		doc[key] = value
+	case "remove":
+		if _, ok := doc[key]; !ok {
+			return fmt.Errorf("%w: no member at path", ErrInvalidPatch)
+		}
+		delete(doc, key)
+	case "move", "copy":
+		from, err := memberName(op.From)
+		if err != nil {
+			return err
+		}
+		value, ok := doc[from]
+		if !ok {
+			return fmt.Errorf("%w: no member at from", ErrInvalidPatch)
+		}
+		if op.Op == "move" {
+			delete(doc, from)
+		}
+		doc[key] = value
+	default:
+		return fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+	}
+	return nil
+}
+
+// memberName decodes a JSON Pointer that names a top-level member.
+func memberName(pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return "", fmt.Errorf("%w: path %s must start with /", ErrInvalidPatch, strconv.Quote(pointer))
+	}
+	name := pointer[1:]
+	if strings.Contains(name, "/") {
+		return "", fmt.Errorf("%w: nested path %s is not supported", ErrInvalidPatch, strconv.Quote(pointer))
+	}
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(name), nil
+}
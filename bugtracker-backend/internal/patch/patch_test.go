@@ -0,0 +1,125 @@
+package patch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testDoc() map[string]interface{} {
+	return map[string]interface{}{
+		"title":  "Crash",
+		"status": "Open",
+		"labels": map[string]interface{}{"area": "ui", "os": "linux"},
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    string
+		expected map[string]interface{}
+	}{
+		{
+			name:  "replace member",
+			patch: `{"status": "Closed"}`,
+			expected: map[string]interface{}{
+				"title": "Crash", "status": "Closed",
+				"labels": map[string]interface{}{"area": "ui", "os": "linux"},
+			},
+		},
+		{
+			name:  "null removes member",
+			patch: `{"title": null, "missing": null}`,
+			expected: map[string]interface{}{
+				"status": "Open",
+				"labels": map[string]interface{}{"area": "ui", "os": "linux"},
+			},
+		},
+		{
+			name:  "nested objects merge",
+			patch: `{"labels": {"os": null, "team": "web"}}`,
+			expected: map[string]interface{}{
+				"title": "Crash", "status": "Open",
+				"labels": map[string]interface{}{"area": "ui", "team": "web"},
+			},
+		},
+		{
+			name:     "empty patch",
+			patch:    `{}`,
+			expected: testDoc(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Merge(testDoc(), []byte(tt.patch))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestMergeInvalid(t *testing.T) {
+	for _, p := range []string{`[]`, `"x"`, `{`} {
+		_, err := Merge(testDoc(), []byte(p))
+		assert.True(t, errors.Is(err, ErrInvalidPatch), p)
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    string
+		expected map[string]interface{}
+		err      error
+	}{
+		{
+			name:     "replace",
+			patch:    `[{"op": "replace", "path": "/status", "value": "Closed"}]`,
+			expected: map[string]interface{}{"title": "Crash", "status": "Closed"},
+		},
+		{
+			name:     "add and remove",
+			patch:    `[{"op": "add", "path": "/a~1b", "value": 1}, {"op": "remove", "path": "/title"}]`,
+			expected: map[string]interface{}{"status": "Open", "a/b": float64(1)},
+		},
+		{
+			name:     "test then replace",
+			patch:    `[{"op": "test", "path": "/status", "value": "Open"}, {"op": "replace", "path": "/status", "value": "Closed"}]`,
+			expected: map[string]interface{}{"title": "Crash", "status": "Closed"},
+		},
+		{
+			name:     "move and copy",
+			patch:    `[{"op": "copy", "from": "/title", "path": "/summary"}, {"op": "move", "from": "/status", "path": "/state"}]`,
+			expected: map[string]interface{}{"title": "Crash", "summary": "Crash", "state": "Open"},
+		},
+		{
+			name:  "failed test",
+			patch: `[{"op": "replace", "path": "/status", "value": "Closed"}, {"op": "test", "path": "/title", "value": "Hang"}]`,
+			err:   ErrTestFailed,
+		},
+		{name: "replace missing member", patch: `[{"op": "replace", "path": "/owner", "value": "x"}]`, err: ErrInvalidPatch},
+		{name: "remove missing member", patch: `[{"op": "remove", "path": "/owner"}]`, err: ErrInvalidPatch},
+		{name: "missing value", patch: `[{"op": "add", "path": "/owner"}]`, err: ErrInvalidPatch},
+		{name: "unknown op", patch: `[{"op": "merge", "path": "/title"}]`, err: ErrInvalidPatch},
+		{name: "nested path", patch: `[{"op": "remove", "path": "/labels/area"}]`, err: ErrInvalidPatch},
+		{name: "relative path", patch: `[{"op": "remove", "path": "title"}]`, err: ErrInvalidPatch},
+		{name: "not an array", patch: `{"op": "remove", "path": "/title"}`, err: ErrInvalidPatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := map[string]interface{}{"title": "Crash", "status": "Open"}
+			result, err := Apply(doc, []byte(tt.patch))
+			if tt.err != nil {
+				assert.True(t, errors.Is(err, tt.err), "got %v", err)
+				assert.Equal(t, map[string]interface{}{"title": "Crash", "status": "Open"}, doc)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
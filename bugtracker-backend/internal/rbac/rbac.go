@@ -0,0 +1,116 @@
+// Package rbac holds the permission matrix: which roles may perform which
+// operations on the tracker.
+package rbac
+
+import (
+	"fmt"
+
+	"bugtracker-backend/internal/models"
+)
+
+// Permission is an operation guarded by a role. Its value completes the
+// sentence "may not ..." in denial messages.
+type Permission string
+
+const (
+	SignIn           Permission = "sign in"
+	ReadBugs         Permission = "read bugs"
+	UseAccount       Permission = "use an account"
+	ReadUsers        Permission = "list users"
+	ReportBugs       Permission = "report bugs"
+	EditBugs         Permission = "edit bugs"
+	Comment          Permission = "comment on bugs"
+	TransitionBugs   Permission = "change the status of bugs"
+	DeleteBugs       Permission = "delete or restore bugs"
+	ModerateComments Permission = "change other users' comments"
+	DeleteAllBugs    Permission = "delete all bugs"
+	ManageUsers      Permission = "manage users"
+	ManageProjects   Permission = "manage projects"
+	ReadAudit        Permission = "read the audit log"
+)
+
+// Anonymous is the role of a request made without signing in.
+const Anonymous = ""
+
+// matrix lists what each role may do. Roles are ordered, and each may also
+// do everything the roles before it in models.Roles may.
+var matrix = map[string][]Permission{
+	Anonymous:            {SignIn, ReadBugs},
+	models.RoleViewer:    {UseAccount, ReadUsers},
+	models.RoleReporter:  {ReportBugs, EditBugs, Comment},
+	models.RoleDeveloper: {TransitionBugs, DeleteBugs},
+	models.RoleAdmin:     {ModerateComments, DeleteAllBugs, ManageUsers, ManageProjects, ReadAudit},
+}
+
+// ranks orders the roles, anonymous first.
+var ranks = func() map[string]int {
+	ranks := map[string]int{Anonymous: 0}
+	for i, role := range models.Roles {
+		ranks[role] = i + 1
+	}
+	return ranks
+}()
+
+// requiredRole is the least role granted each permission.
+var requiredRole = func() map[Permission]string {
+	required := make(map[Permission]string)
+	for role, perms := range matrix {
+		for _, p := range perms {
+			required[p] = role
+		}
+	}
+	return required
+}()
+
+// RequiredRole returns the least role that has permission p.
+func RequiredRole(p Permission) string {
+	return requiredRole[p]
+}
+
+// DeniedError reports a role lacking a permission.
+type DeniedError struct {
+	Role       string
+	Permission Permission
+}
+
+func (e *DeniedError) Error() string {
+	role := e.Role
+	if role == Anonymous {
+		role = "anonymous"
+	}
+	return fmt.Sprintf("the %s role may not %s; this needs the %s role or higher",
+		role, e.Permission, RequiredRole(e.Permission))
+}
+
+// Check returns a *DeniedError unless role has permission p. Unknown roles
+// and permissions are denied.
+func Check(role string, p Permission) error {
+	required, ok := requiredRole[p]
+	rank, known := ranks[role]
+	if !ok || !known || rank < ranks[required] {
+		return &DeniedError{Role: role, Permission: p}
+	}
+	return nil
+}
+
+// Allowed reports whether role has permission p.
+func Allowed(role string, p Permission) bool {
+	return Check(role, p) == nil
+}
+
+// ScopedRole returns the role a user with role acts with through an API
+// token of the given scope: read tokens act as viewers and write tokens at
+// most as developers, while the admin scope keeps the user's role.
+func ScopedRole(role, scope string) string {
+	limit := role
+	switch scope {
+	case models.ScopeRead:
+		limit = models.RoleViewer
+	case models.ScopeWrite:
+		limit = models.RoleDeveloper
+	}
+	if ranks[limit] < ranks[role] {
+		return limit
+	}
+	return role
+}
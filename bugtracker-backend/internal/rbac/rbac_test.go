@@ -0,0 +1,51 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"bugtracker-backend/internal/models"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		role    string
+		allowed []Permission
+		denied  []Permission
+	}{
+		{Anonymous, []Permission{ReadBugs}, []Permission{UseAccount, ReportBugs}},
+		{models.RoleViewer, []Permission{ReadBugs, UseAccount, ReadUsers}, []Permission{ReportBugs, Comment}},
+		{models.RoleReporter, []Permission{ReportBugs, EditBugs, Comment}, []Permission{TransitionBugs, DeleteBugs}},
+		{models.RoleDeveloper, []Permission{TransitionBugs, DeleteBugs}, []Permission{DeleteAllBugs, ModerateComments, ReadAudit}},
+		{models.RoleAdmin, []Permission{ReadBugs, DeleteAllBugs, ManageUsers, ReadAudit}, nil},
+		{"root", nil, []Permission{ReadBugs}},
+	}
+	for _, tt := range tests {
+		for _, p := range tt.allowed {
+			assert.True(t, Allowed(tt.role, p), "%q should %s", tt.role, p)
+		}
+		for _, p := range tt.denied {
+			assert.False(t, Allowed(tt.role, p), "%q should not %s", tt.role, p)
+		}
+	}
+	assert.False(t, Allowed(models.RoleAdmin, Permission("launch missiles")))
+}
+
+func TestDeniedError(t *testing.T) {
+	err := Check(models.RoleReporter, DeleteAllBugs)
+	var denied *DeniedError
+	if assert.ErrorAs(t, err, &denied) {
+		assert.Equal(t, DeleteAllBugs, denied.Permission)
+	}
+	assert.EqualError(t, err, "the reporter role may not delete all bugs; this needs the admin role or higher")
+	assert.EqualError(t, Check(Anonymous, Comment), "the anonymous role may not comment on bugs; this needs the reporter role or higher")
+}
+
+func TestScopedRole(t *testing.T) {
+	assert.Equal(t, models.RoleViewer, ScopedRole(models.RoleAdmin, models.ScopeRead))
+	assert.Equal(t, models.RoleDeveloper, ScopedRole(models.RoleAdmin, models.ScopeWrite))
+	assert.Equal(t, models.RoleReporter, ScopedRole(models.RoleReporter, models.ScopeWrite))
+	assert.Equal(t, models.RoleAdmin, ScopedRole(models.RoleAdmin, models.ScopeAdmin))
+	assert.Equal(t, models.RoleViewer, ScopedRole(models.RoleViewer, models.ScopeAdmin))
+}
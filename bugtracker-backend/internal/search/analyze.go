@@ -0,0 +1,83 @@
+// Package search implements the text analysis, ranking and highlighting
+// behind the full-text bug search. The inverted index itself is persisted by
+// the storage backends.
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Token is an indexed term together with the byte range of the word it was
+// derived from.
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "for": true, "from": true, "has": true,
+	"have": true, "i": true, "if": true, "in": true, "into": true, "is": true,
+	"it": true, "its": true, "of": true, "on": true, "or": true, "so": true,
+	"that": true, "the": true, "their": true, "then": true, "there": true,
+	"these": true, "they": true, "this": true, "to": true, "was": true,
+	"we": true, "were": true, "when": true, "which": true, "will": true,
+	"with": true,
+}
+
+// Tokenize splits text into lower-cased, stemmed terms, dropping common
+// English stop words.
+func Tokenize(text string) []Token {
+	var tokens []Token
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = appendToken(tokens, text, start, i)
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = appendToken(tokens, text, start, len(text))
+	}
+	return tokens
+}
+
+func appendToken(tokens []Token, text string, start, end int) []Token {
+	word := strings.ToLower(text[start:end])
+	if stopWords[word] {
+		return tokens
+	}
+	return append(tokens, Token{Term: Stem(word), Start: start, End: end})
+}
+
+// Terms returns just the terms of Tokenize(text).
+func Terms(text string) []string {
+	tokens := Tokenize(text)
+	terms := make([]string, len(tokens))
+	for i, t := range tokens {
+		terms[i] = t.Term
+	}
+	return terms
+}
+
+// QueryTerms returns the distinct terms of a search query in order of
+// first appearance.
+func QueryTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, term := range Terms(query) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
@@ -0,0 +1,64 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens := Tokenize("The Login page crashes, on Safari!")
+
+	assert.Equal(t, []Token{
+		{Term: "login", Start: 4, End: 9},
+		{Term: "page", Start: 10, End: 14},
+		{Term: "crash", Start: 15, End: 22},
+		{Term: "safari", Start: 27, End: 33},
+	}, tokens)
+}
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{"Empty", "", nil},
+		{"Only stop words", "the and of", []string{}},
+		{"Punctuation and digits", "HTTP-500 error(s)", []string{"http", "500", "error", "s"}},
+		{"Unicode", "Überweisung fehlgeschlagen", []string{"überweisung", "fehlgeschlagen"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terms := Terms(tt.text)
+			if tt.expected == nil {
+				assert.Empty(t, terms)
+			} else {
+				assert.Equal(t, tt.expected, terms)
+			}
+		})
+	}
+}
+
+func TestQueryTerms(t *testing.T) {
+	assert.Equal(t, []string{"crash", "login"}, QueryTerms("crashes crashed the login"))
+}
+
+func TestDocumentAndBM25(t *testing.T) {
+	doc := NewDocument(1)
+	doc.AddField("Login crash", TitleWeight)
+	doc.AddField("crash when logging in", BodyWeight)
+
+	assert.Equal(t, map[string]int{"login": 3, "crash": 4, "log": 1}, doc.Terms)
+	assert.Equal(t, 8, doc.Length)
+
+	stats := Stats{Docs: 10, TotalLength: 80}
+	rare := BM25(1, 8, 1, stats)
+	common := BM25(1, 8, 9, stats)
+	frequent := BM25(4, 8, 1, stats)
+
+	assert.Greater(t, rare, common)
+	assert.Greater(t, frequent, rare)
+	assert.Zero(t, BM25(0, 8, 1, stats))
+}
@@ -0,0 +1,65 @@
+package search
+
+import (
+	"html"
+	"strings"
+)
+
+const (
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+	ellipsis  = "…"
+)
+
+// Highlight returns an HTML-escaped excerpt of text of roughly width bytes
+// around the first word matching one of terms, with every matching word
+// wrapped in <mark> tags. A width of zero keeps the whole text. It returns
+// an empty string when no word matches.
+func Highlight(text string, terms map[string]bool, width int) string {
+	tokens := Tokenize(text)
+
+	first := -1
+	for i, t := range tokens {
+		if terms[t.Term] {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	start, end := 0, len(text)
+	if width > 0 && len(text) > width {
+		// Show some context before the match, starting on a word.
+		start = tokens[first].Start
+		for i := first; i >= 0 && tokens[first].Start-tokens[i].Start <= width/4; i-- {
+			start = tokens[i].Start
+		}
+		end = tokens[first].End
+		for i := first; i < len(tokens) && tokens[i].End-start <= width; i++ {
+			end = tokens[i].End
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString(ellipsis)
+	}
+	pos := start
+	for _, t := range tokens {
+		if t.Start < start || t.End > end || !terms[t.Term] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:t.Start]))
+		b.WriteString(markOpen)
+		b.WriteString(html.EscapeString(text[t.Start:t.End]))
+		b.WriteString(markClose)
+		pos = t.End
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString(ellipsis)
+	}
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlight(t *testing.T) {
+	terms := map[string]bool{"crash": true}
+
+	tests := []struct {
+		name     string
+		text     string
+		width    int
+		expected string
+	}{
+		{
+			name:     "Whole text",
+			text:     "App crashes when it crashed",
+			expected: "App <mark>crashes</mark> when it <mark>crashed</mark>",
+		},
+		{
+			name:     "No match",
+			text:     "Everything works",
+			expected: "",
+		},
+		{
+			name:     "Escapes HTML",
+			text:     "<b>crash</b> & burn",
+			expected: "&lt;b&gt;<mark>crash</mark>&lt;/b&gt; &amp; burn",
+		},
+		{
+			name:     "Excerpt",
+			text:     strings.Repeat("lorem ", 20) + "sudden crash " + strings.Repeat("ipsum ", 20),
+			width:    40,
+			expected: "…sudden <mark>crash</mark> ipsum ipsum ipsum ipsum…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Highlight(tt.text, terms, tt.width))
+		})
+	}
+}
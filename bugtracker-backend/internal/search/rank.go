@@ -0,0 +1,59 @@
+package search
+
+import "math"
+
+// Field weights used when building documents: a term in the title counts
+// as much as three occurrences in the description or comments.
+const (
+	TitleWeight = 3
+	BodyWeight  = 1
+)
+
+// BM25 tuning constants.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Document is the indexed form of one bug: weighted term frequencies and
+// the total weighted length.
+type Document struct {
+	ID     int
+	Terms  map[string]int
+	Length int
+}
+
+// Stats describes the indexed collection as a whole.
+type Stats struct {
+	Docs        int
+	TotalLength int
+}
+
+func NewDocument(id int) *Document {
+	return &Document{ID: id, Terms: make(map[string]int)}
+}
+
+// AddField indexes text with the given weight.
+func (d *Document) AddField(text string, weight int) {
+	for _, term := range Terms(text) {
+		d.Terms[term] += weight
+		d.Length += weight
+	}
+}
+
+// BM25 scores a term occurring tf times in a document of docLength, where
+// df documents out of the collection contain the term.
+func BM25(tf, docLength, df int, stats Stats) float64 {
+	if tf == 0 || df == 0 || stats.Docs == 0 {
+		return 0
+	}
+	n := float64(stats.Docs)
+	idf := math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+
+	avgLength := float64(stats.TotalLength) / n
+	if avgLength == 0 {
+		avgLength = 1
+	}
+	norm := 1 - bm25B + bm25B*float64(docLength)/avgLength
+	return idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*norm)
+}
@@ -0,0 +1,254 @@
+package search
+
+// Stem reduces an English word to its stem using the Porter (1980)
+// algorithm. The word is expected to be lower case; words of one or two
+// letters are returned unchanged.
+func Stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			// Only plain ASCII words follow the English rules.
+			return word
+		}
+	}
+
+	w := []byte(word)
+	w = step1a(w)
+	w = step1b(w)
+	w = step1c(w)
+	w = step2(w)
+	w = step3(w)
+	w = step4(w)
+	w = step5(w)
+	return string(w)
+}
+
+type suffixRule struct {
+	suffix      string
+	replacement string
+}
+
+// isConsonant reports whether w[i] is a consonant. A 'y' counts as a
+// consonant at the start of a word or after a vowel.
+func isConsonant(w []byte, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(w, i-1)
+	}
+	return true
+}
+
+// measure counts the vowel-consonant sequences in w, the m of [C](VC)^m[V].
+func measure(w []byte) int {
+	m := 0
+	i := 0
+	for i < len(w) && isConsonant(w, i) {
+		i++
+	}
+	for i < len(w) {
+		for i < len(w) && !isConsonant(w, i) {
+			i++
+		}
+		if i >= len(w) {
+			break
+		}
+		m++
+		for i < len(w) && isConsonant(w, i) {
+			i++
+		}
+	}
+	return m
+}
+
+func containsVowel(w []byte) bool {
+	for i := range w {
+		if !isConsonant(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func endsDoubleConsonant(w []byte) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && isConsonant(w, n-1)
+}
+
+// endsCVC reports whether w ends consonant-vowel-consonant where the final
+// consonant is not w, x or y, as in "hop" but not "snow".
+func endsCVC(w []byte) bool {
+	n := len(w)
+	if n < 3 || !isConsonant(w, n-1) || isConsonant(w, n-2) || !isConsonant(w, n-3) {
+		return false
+	}
+	switch w[n-1] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+func hasSuffix(w []byte, suffix string) bool {
+	return len(w) >= len(suffix) && string(w[len(w)-len(suffix):]) == suffix
+}
+
+func replaceSuffix(w []byte, suffix, replacement string) []byte {
+	return append(w[:len(w)-len(suffix)], replacement...)
+}
+
+// applyRules replaces the first matching suffix whose stem has a measure
+// above minMeasure. As in the reference implementation, only the first
+// matching suffix is considered even if its condition fails.
+func applyRules(w []byte, rules []suffixRule, minMeasure int) []byte {
+	for _, r := range rules {
+		if hasSuffix(w, r.suffix) {
+			if measure(w[:len(w)-len(r.suffix)]) > minMeasure {
+				return replaceSuffix(w, r.suffix, r.replacement)
+			}
+			return w
+		}
+	}
+	return w
+}
+
+func step1a(w []byte) []byte {
+	switch {
+	case hasSuffix(w, "sses"):
+		return replaceSuffix(w, "sses", "ss")
+	case hasSuffix(w, "ies"):
+		return replaceSuffix(w, "ies", "i")
+	case hasSuffix(w, "ss"):
+		return w
+	case hasSuffix(w, "s"):
+		return replaceSuffix(w, "s", "")
+	}
+	return w
+}
+
+func step1b(w []byte) []byte {
+	if hasSuffix(w, "eed") {
+		if measure(w[:len(w)-3]) > 0 {
+			return replaceSuffix(w, "eed", "ee")
+		}
+		return w
+	}
+
+	var stem []byte
+	switch {
+	case hasSuffix(w, "ed") && containsVowel(w[:len(w)-2]):
+		stem = w[:len(w)-2]
+	case hasSuffix(w, "ing") && containsVowel(w[:len(w)-3]):
+		stem = w[:len(w)-3]
+	default:
+		return w
+	}
+
+	switch {
+	case hasSuffix(stem, "at"), hasSuffix(stem, "bl"), hasSuffix(stem, "iz"):
+		return append(stem, 'e')
+	case endsDoubleConsonant(stem):
+		switch stem[len(stem)-1] {
+		case 'l', 's', 'z':
+			return stem
+		}
+		return stem[:len(stem)-1]
+	case measure(stem) == 1 && endsCVC(stem):
+		return append(stem, 'e')
+	}
+	return stem
+}
+
+func step1c(w []byte) []byte {
+	if hasSuffix(w, "y") && containsVowel(w[:len(w)-1]) {
+		return replaceSuffix(w, "y", "i")
+	}
+	return w
+}
+
+var step2Rules = []suffixRule{
+	{"ational", "ate"},
+	{"tional", "tion"},
+	{"enci", "ence"},
+	{"anci", "ance"},
+	{"izer", "ize"},
+	{"abli", "able"},
+	{"alli", "al"},
+	{"entli", "ent"},
+	{"eli", "e"},
+	{"ousli", "ous"},
+	{"ization", "ize"},
+	{"ation", "ate"},
+	{"ator", "ate"},
+	{"alism", "al"},
+	{"iveness", "ive"},
+	{"fulness", "ful"},
+	{"ousness", "ous"},
+	{"aliti", "al"},
+	{"iviti", "ive"},
+	{"biliti", "ble"},
+}
+
+func step2(w []byte) []byte {
+	return applyRules(w, step2Rules, 0)
+}
+
+var step3Rules = []suffixRule{
+	{"icate", "ic"},
+	{"ative", ""},
+	{"alize", "al"},
+	{"iciti", "ic"},
+	{"ical", "ic"},
+	{"ful", ""},
+	{"ness", ""},
+}
+
+func step3(w []byte) []byte {
+	return applyRules(w, step3Rules, 0)
+}
+
+var step4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement",
+	"ment", "ent", "ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+func step4(w []byte) []byte {
+	// Pick the longest listed suffix the word ends with.
+	match := ""
+	for _, suffix := range step4Suffixes {
+		if hasSuffix(w, suffix) && len(suffix) > len(match) {
+			match = suffix
+		}
+	}
+	if match == "" {
+		return w
+	}
+
+	stem := w[:len(w)-len(match)]
+	if measure(stem) <= 1 {
+		return w
+	}
+	if match == "ion" {
+		if len(stem) == 0 || (stem[len(stem)-1] != 's' && stem[len(stem)-1] != 't') {
+			return w
+		}
+	}
+	return stem
+}
+
+func step5(w []byte) []byte {
+	if hasSuffix(w, "e") {
+		stem := w[:len(w)-1]
+		m := measure(stem)
+		if m > 1 || (m == 1 && !endsCVC(stem)) {
+			w = stem
+		}
+	}
+	if measure(w) > 1 && endsDoubleConsonant(w) && w[len(w)-1] == 'l' {
+		w = w[:len(w)-1]
+	}
+	return w
+}
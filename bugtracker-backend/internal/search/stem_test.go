@@ -0,0 +1,50 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		word     string
+		expected string
+	}{
+		{"caresses", "caress"},
+		{"ponies", "poni"},
+		{"cats", "cat"},
+		{"feed", "feed"},
+		{"agreed", "agre"},
+		{"plastered", "plaster"},
+		{"motoring", "motor"},
+		{"sing", "sing"},
+		{"hopping", "hop"},
+		{"falling", "fall"},
+		{"filing", "file"},
+		{"happy", "happi"},
+		{"relational", "relat"},
+		{"conditional", "condit"},
+		{"generalizations", "gener"},
+		{"hopefulness", "hope"},
+		{"electrical", "electr"},
+		{"adjustment", "adjust"},
+		{"connection", "connect"},
+		{"connected", "connect"},
+		{"connecting", "connect"},
+		{"probate", "probat"},
+		{"rate", "rate"},
+		{"controlling", "control"},
+		{"crashes", "crash"},
+		{"crashed", "crash"},
+		{"is", "is"},
+		{"404", "404"},
+		{"café", "café"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Stem(tt.word))
+		})
+	}
+}
@@ -1,10 +1,12 @@
 package testutil
 
 import (
+	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -12,10 +14,12 @@ import (
 var (
 	testDBPaths = make(map[string]struct{})
 	mu          sync.Mutex
+	pathSeq     atomic.Int64
 )
 
 func GetTestDBPath() string {
-	path := filepath.Join(os.TempDir(), "test_"+time.Now().Format("20060102150405.000")+".db")
+	name := fmt.Sprintf("test_%s_%d.db", time.Now().Format("20060102150405.000"), pathSeq.Add(1))
+	path := filepath.Join(os.TempDir(), name)
 	mu.Lock()
 	testDBPaths[path] = struct{}{}
 	mu.Unlock()
@@ -31,28 +35,12 @@ func CleanupTestDB() error {
 		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 			lastErr = err
 		}
+		// SQLite keeps its write-ahead log alongside the database file.
+		os.Remove(path + "-wal")
+		os.Remove(path + "-shm")
 		delete(testDBPaths, path)
 	}
 
-	projectDirs := []string{
-		".",
-		"internal/db",
-		"internal/handlers",
-		"cmd/bugtracker",
-	}
-
-	for _, dir := range projectDirs {
-		matches, err := filepath.Glob(filepath.Join(dir, "*.db"))
-		if err != nil {
-			continue
-		}
-		for _, match := range matches {
-			if err := os.Remove(match); err != nil && !os.IsNotExist(err) {
-				lastErr = err
-			}
-		}
-	}
-
 	return lastErr
 }
 
@@ -0,0 +1,239 @@
+// Package workflow defines the statuses a bug moves through, which moves
+// between them are allowed and which statuses count as resolved.
+package workflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"bugtracker-backend/internal/models"
+)
+
+// Errors returned when a status change is invalid regardless of the
+// transition rules.
+var (
+	// ErrUnknownStatus is returned for a status that is not part of the
+	// workflow.
+	ErrUnknownStatus = errors.New("unknown status")
+	// ErrResolutionRequired is returned when a bug enters a resolved status
+	// without a resolution.
+	ErrResolutionRequired = errors.New("resolution is required")
+	// ErrInvalidResolution is returned for a resolution outside
+	// models.Resolutions, or one given for an unresolved status.
+	ErrInvalidResolution = errors.New("invalid resolution")
+)
+
+// State is one status of a workflow.
+type State struct {
+	Name string `json:"name"`
+	// Resolved marks statuses in which work on the bug has finished.
+	Resolved bool `json:"resolved"`
+	// Transitions lists the statuses a bug may move to from this one.
+	Transitions []string `json:"transitions"`
+}
+
+// Workflow is a validated set of states. Use New, Load or Default to build
+// one.
+type Workflow struct {
+	Initial string  `json:"initial"`
+	States  []State `json:"states"`
+
+	byName map[string]*State
+}
+
+// TransitionError reports a move between two known statuses that the
+// workflow does not allow.
+type TransitionError struct {
+	From    string
+	To      string
+	Allowed []string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot move bug from %q to %q", e.From, e.To)
+}
+
+// Default returns the built-in workflow: bugs start Open, may be worked on
+// and resolved, and resolved or closed bugs may be reopened.
+func Default() *Workflow {
+	w, err := New("Open", []State{
+		{Name: "Open", Transitions: []string{"In Progress", "Resolved", "Closed"}},
+		{Name: "In Progress", Transitions: []string{"Open", "Resolved", "Closed"}},
+		{Name: "Resolved", Resolved: true, Transitions: []string{"Open", "Closed"}},
+		{Name: "Closed", Resolved: true, Transitions: []string{"Open"}},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return w
+}
+
+// Load reads a workflow from a JSON file of the form
+//
+//	{"initial": "Open", "states": [{"name": "Open", "transitions": ["Closed"]}, ...]}
+func Load(path string) (*Workflow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow: %w", err)
+	}
+	var w Workflow
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow %s: %w", path, err)
+	}
+	wf, err := New(w.Initial, w.States)
+	if err != nil {
+		return nil, fmt.Errorf("invalid workflow %s: %w", path, err)
+	}
+	return wf, nil
+}
+
+// LoadOrDefault loads the workflow at path, or returns the built-in one when
+// path is empty.
+func LoadOrDefault(path string) (*Workflow, error) {
+	if path == "" {
+		return Default(), nil
+	}
+	return Load(path)
+}
+
+// New checks that states form a consistent workflow: names are unique and
+// non-empty, and the initial status and every transition target exist.
+func New(initial string, states []State) (*Workflow, error) {
+	if len(states) == 0 {
+		return nil, errors.New("no states defined")
+	}
+
+	w := &Workflow{Initial: initial, States: states, byName: make(map[string]*State)}
+	for i := range w.States {
+		s := &w.States[i]
+		if s.Name == "" {
+			return nil, fmt.Errorf("state %d has no name", i+1)
+		}
+		if _, ok := w.byName[s.Name]; ok {
+			return nil, fmt.Errorf("state %q defined twice", s.Name)
+		}
+		w.byName[s.Name] = s
+	}
+
+	if _, ok := w.byName[initial]; !ok {
+		return nil, fmt.Errorf("initial state %q is not defined", initial)
+	}
+	for _, s := range w.States {
+		for _, to := range s.Transitions {
+			if _, ok := w.byName[to]; !ok {
+				return nil, fmt.Errorf("state %q has a transition to undefined state %q", s.Name, to)
+			}
+		}
+	}
+	return w, nil
+}
+
+// State returns the named state.
+func (w *Workflow) State(name string) (State, bool) {
+	s, ok := w.byName[name]
+	if !ok {
+		return State{}, false
+	}
+	return *s, true
+}
+
+// HasStatus reports whether status is part of the workflow.
+func (w *Workflow) HasStatus(status string) bool {
+	_, ok := w.byName[status]
+	return ok
+}
+
+// IsResolved reports whether status is a resolved state.
+func (w *Workflow) IsResolved(status string) bool {
+	s, ok := w.byName[status]
+	return ok && s.Resolved
+}
+
+// Transitions returns the statuses a bug in status from may move to. A bug
+// whose status is not part of the workflow, for example one stored before
+// the workflow was configured, may move to any state.
+func (w *Workflow) Transitions(from string) []string {
+	if s, ok := w.byName[from]; ok {
+		return s.Transitions
+	}
+	names := make([]string, len(w.States))
+	for i, s := range w.States {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// CheckTransition returns an error wrapping ErrUnknownStatus if to is not a
+// known status, or a *TransitionError if the move from from to to is not
+// allowed. Keeping the current status is always allowed.
+func (w *Workflow) CheckTransition(from, to string) error {
+	if from == to {
+		return nil
+	}
+	if _, ok := w.byName[to]; !ok {
+		return fmt.Errorf("%w %q", ErrUnknownStatus, to)
+	}
+	allowed := w.Transitions(from)
+	for _, s := range allowed {
+		if s == to {
+			return nil
+		}
+	}
+	return &TransitionError{From: from, To: to, Allowed: allowed}
+}
+
+// Apply moves bug to status, keeping its resolution fields consistent:
+//
+//   - entering a resolved status requires a resolution and stamps
+//     ResolvedAt; moving between resolved statuses may keep the existing
+//     resolution by passing an empty one
+//   - leaving a resolved status clears the resolution and ResolvedAt and
+//     counts a reopen
+//   - an unchanged status with no resolution leaves the bug as it is
+//
+// bug is only modified if the change is allowed.
+func (w *Workflow) Apply(bug *models.Bug, status, resolution string, now time.Time) error {
+	if err := w.CheckTransition(bug.Status, status); err != nil {
+		return err
+	}
+	if status == bug.Status && resolution == "" {
+		// Nothing changes, even for resolved bugs stored without a
+		// resolution before one was required.
+		return nil
+	}
+
+	wasResolved := w.IsResolved(bug.Status)
+	if !w.IsResolved(status) {
+		if resolution != "" {
+			return fmt.Errorf("%w: %q is not a resolved status", ErrInvalidResolution, status)
+		}
+		if wasResolved {
+			bug.Resolution = ""
+			bug.ResolvedAt = nil
+			bug.ReopenCount++
+		}
+		bug.Status = status
+		return nil
+	}
+
+	if resolution == "" {
+		resolution = bug.Resolution
+	}
+	if resolution == "" {
+		return fmt.Errorf("%w for status %q", ErrResolutionRequired, status)
+	}
+	if !models.IsValidResolution(resolution) {
+		return fmt.Errorf("%w %q", ErrInvalidResolution, resolution)
+	}
+
+	if !wasResolved || bug.ResolvedAt == nil {
+		resolvedAt := now
+		bug.ResolvedAt = &resolvedAt
+	}
+	bug.Status = status
+	bug.Resolution = resolution
+	return nil
+}
@@ -0,0 +1,155 @@
+package workflow
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bugtracker-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckTransition(t *testing.T) {
+	w := Default()
+
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		wantErr error
+	}{
+		{"Allowed", "Open", "In Progress", nil},
+		{"Reopen", "Closed", "Open", nil},
+		{"Unchanged", "Closed", "Closed", nil},
+		{"Not allowed", "Closed", "In Progress", &TransitionError{}},
+		{"Unknown target", "Open", "Done", ErrUnknownStatus},
+		{"Unknown source", "Triaged", "Closed", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := w.CheckTransition(tt.from, tt.to)
+			switch want := tt.wantErr.(type) {
+			case nil:
+				assert.NoError(t, err)
+			case *TransitionError:
+				var terr *TransitionError
+				if assert.True(t, errors.As(err, &terr)) {
+					assert.Equal(t, tt.from, terr.From)
+					assert.Equal(t, tt.to, terr.To)
+					assert.Equal(t, []string{"Open"}, terr.Allowed)
+				}
+			default:
+				assert.ErrorIs(t, err, want)
+			}
+		})
+	}
+}
+
+func TestIsResolved(t *testing.T) {
+	w := Default()
+	assert.True(t, w.IsResolved("Closed"))
+	assert.True(t, w.IsResolved("Resolved"))
+	assert.False(t, w.IsResolved("Open"))
+	assert.False(t, w.IsResolved("Unknown"))
+}
+
+func TestNewRejectsInconsistentWorkflows(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial string
+		states  []State
+		errMsg  string
+	}{
+		{"No states", "Open", nil, "no states defined"},
+		{"Missing initial", "New", []State{{Name: "Open"}}, `initial state "New" is not defined`},
+		{"Duplicate", "Open", []State{{Name: "Open"}, {Name: "Open"}}, `state "Open" defined twice`},
+		{"Unnamed", "Open", []State{{Name: "Open"}, {}}, "state 2 has no name"},
+		{
+			"Undefined target", "Open",
+			[]State{{Name: "Open", Transitions: []string{"Done"}}},
+			`state "Open" has a transition to undefined state "Done"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.initial, tt.states)
+			assert.EqualError(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "workflow.json")
+	err := os.WriteFile(path, []byte(`{
+		"initial": "New",
+		"states": [
+			{"name": "New", "transitions": ["Done"]},
+			{"name": "Done", "resolved": true, "transitions": ["New"]}
+		]
+	}`), 0o644)
+	assert.NoError(t, err)
+
+	w, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "New", w.Initial)
+	assert.True(t, w.IsResolved("Done"))
+	assert.Equal(t, []string{"Done"}, w.Transitions("New"))
+
+	bad := filepath.Join(dir, "bad.json")
+	assert.NoError(t, os.WriteFile(bad, []byte(`{"initial": "New", "states": []}`), 0o644))
+	_, err = Load(bad)
+	assert.ErrorContains(t, err, "no states defined")
+
+	_, err = Load(filepath.Join(dir, "missing.json"))
+	assert.ErrorContains(t, err, "failed to read workflow")
+}
+
+func TestApply(t *testing.T) {
+	w := Default()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+
+	bug := &models.Bug{Status: "Open"}
+
+	err := w.Apply(bug, "Resolved", "", now)
+	assert.ErrorIs(t, err, ErrResolutionRequired)
+	assert.Equal(t, "Open", bug.Status)
+
+	err = w.Apply(bug, "Resolved", "Fixed It", now)
+	assert.ErrorIs(t, err, ErrInvalidResolution)
+
+	err = w.Apply(bug, "In Progress", models.ResolutionFixed, now)
+	assert.ErrorIs(t, err, ErrInvalidResolution)
+
+	assert.NoError(t, w.Apply(bug, "Resolved", models.ResolutionFixed, now))
+	assert.Equal(t, "Resolved", bug.Status)
+	assert.Equal(t, models.ResolutionFixed, bug.Resolution)
+	assert.Equal(t, now, *bug.ResolvedAt)
+
+	// Moving between resolved statuses keeps the resolution and time.
+	assert.NoError(t, w.Apply(bug, "Closed", "", later))
+	assert.Equal(t, models.ResolutionFixed, bug.Resolution)
+	assert.Equal(t, now, *bug.ResolvedAt)
+
+	assert.NoError(t, w.Apply(bug, "Open", "", later))
+	assert.Equal(t, "Open", bug.Status)
+	assert.Empty(t, bug.Resolution)
+	assert.Nil(t, bug.ResolvedAt)
+	assert.Equal(t, 1, bug.ReopenCount)
+
+	assert.NoError(t, w.Apply(bug, "Closed", models.ResolutionDuplicate, later))
+	assert.Equal(t, later, *bug.ResolvedAt)
+	assert.NoError(t, w.Apply(bug, "Open", "", later))
+	assert.Equal(t, 2, bug.ReopenCount)
+
+	legacy := &models.Bug{Status: "Closed"}
+	assert.NoError(t, w.Apply(legacy, "Closed", "", now))
+	assert.Empty(t, legacy.Resolution)
+}
@@ -0,0 +1,82 @@
+import {
+  authHeaders,
+  clearSession,
+  getSession,
+  login,
+  logout,
+  saveSession,
+} from "@/api/auth";
+
+global.fetch = jest.fn();
+
+describe("Auth API", () => {
+  const session = {
+    token: "secret",
+    expires_at: new Date(Date.now() + 60000).toISOString(),
+    user: { id: 1, username: "alice", role: "reporter" },
+  };
+
+  beforeEach(() => {
+    jest.clearAllMocks();
+    clearSession();
+  });
+
+  it("should log in and keep the session", async () => {
+    (global.fetch as jest.Mock).mockResolvedValueOnce({
+      ok: true,
+      status: 200,
+      json: async () => session,
+    });
+
+    const result = await login("alice", "correct horse");
+    expect(global.fetch).toHaveBeenCalledWith(
+      "http://localhost:8080/api/auth/login",
+      {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ username: "alice", password: "correct horse" }),
+      }
+    );
+    expect(result).toEqual(session);
+    expect(getSession()).toEqual(session);
+    expect(authHeaders()).toEqual({ Authorization: "Bearer secret" });
+  });
+
+  it("should reject a wrong password", async () => {
+    (global.fetch as jest.Mock).mockResolvedValueOnce({
+      ok: false,
+      status: 401,
+      json: async () => ({ error: "invalid username or password" }),
+    });
+
+    await expect(login("alice", "wrong")).rejects.toThrow(
+      "Wrong username or password"
+    );
+    expect(getSession()).toBeNull();
+  });
+
+  it("should send no token without a session", () => {
+    expect(authHeaders()).toEqual({});
+  });
+
+  it("should forget an expired session", () => {
+    saveSession({ ...session, expires_at: new Date(0).toISOString() });
+    expect(getSession()).toBeNull();
+    expect(authHeaders()).toEqual({});
+  });
+
+  it("should end the session on logout", async () => {
+    saveSession(session);
+    (global.fetch as jest.Mock).mockResolvedValueOnce({ ok: true, status: 204 });
+
+    await logout();
+    expect(global.fetch).toHaveBeenCalledWith(
+      "http://localhost:8080/api/auth/logout",
+      {
+        method: "POST",
+        headers: { Authorization: "Bearer secret" },
+      }
+    );
+    expect(getSession()).toBeNull();
+  });
+});
@@ -0,0 +1,84 @@
+const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8080";
+const API_PATH = "/api";
+
+// SESSION_KEY is where the session from the last login is kept.
+const SESSION_KEY = "bugtracker.session";
+
+export interface User {
+  id: number;
+  username: string;
+  display_name?: string;
+  role: string;
+}
+
+export interface Session {
+  token: string;
+  expires_at: string;
+  user: User;
+}
+
+export const getSession = (): Session | null => {
+  if (typeof window === "undefined") return null;
+  const stored = window.localStorage.getItem(SESSION_KEY);
+  if (!stored) return null;
+  try {
+    const session: Session = JSON.parse(stored);
+    if (new Date(session.expires_at) > new Date()) {
+      return session;
+    }
+  } catch (error) {
+    console.error("Discarding unreadable session:", error);
+  }
+  clearSession();
+  return null;
+};
+
+export const saveSession = (session: Session) => {
+  window.localStorage.setItem(SESSION_KEY, JSON.stringify(session));
+};
+
+export const clearSession = () => {
+  window.localStorage.removeItem(SESSION_KEY);
+};
+
+// authHeaders returns the Authorization header every write to the API needs,
+// or no headers when nobody is logged in.
+export const authHeaders = (): Record<string, string> => {
+  const session = getSession();
+  return session ? { Authorization: `Bearer ${session.token}` } : {};
+};
+
+export const login = async (
+  username: string,
+  password: string
+): Promise<Session> => {
+  const response = await fetch(`${API_BASE_URL}${API_PATH}/auth/login`, {
+    method: "POST",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    body: JSON.stringify({ username, password }),
+  });
+  if (response.status === 401) {
+    throw new Error("Wrong username or password");
+  }
+  if (!response.ok) {
+    throw new Error("Failed to log in");
+  }
+  const session: Session = await response.json();
+  saveSession(session);
+  return session;
+};
+
+export const logout = async () => {
+  try {
+    await fetch(`${API_BASE_URL}${API_PATH}/auth/logout`, {
+      method: "POST",
+      headers: authHeaders(),
+    });
+  } catch (error) {
+    console.error("Error logging out:", error);
+  } finally {
+    clearSession();
+  }
+};
@@ -1,4 +1,5 @@
 import { createBug, getBugs, updateBug, deleteBug } from "@/api/bugs";
+import { clearSession, saveSession } from "@/api/auth";
 
 global.fetch = jest.fn();
 
@@ -7,6 +8,7 @@ describe("Bug API", () => {
 
   beforeEach(() => {
     jest.clearAllMocks();
+    clearSession();
     consoleErrorSpy = jest.spyOn(console, "error").mockImplementation(() => {});
   });
 
@@ -28,6 +30,7 @@ describe("Bug API", () => {
 
       (global.fetch as jest.Mock).mockResolvedValueOnce({
         ok: true,
+        headers: { get: () => null },
         json: async () => mockBugs,
       });
 
@@ -38,6 +41,26 @@ describe("Bug API", () => {
       expect(result).toEqual(mockBugs);
     });
 
+    it("should follow the next cursor to fetch every page", async () => {
+      const page = (ids: number[], next: string | null) => ({
+        ok: true,
+        headers: {
+          get: (name: string) => (name === "X-Next-Cursor" ? next : null),
+        },
+        json: async () => ids.map((id) => ({ id, title: `Bug ${id}` })),
+      });
+      (global.fetch as jest.Mock)
+        .mockResolvedValueOnce(page([1, 2], "2"))
+        .mockResolvedValueOnce(page([3], null));
+
+      const result = await getBugs();
+      expect(global.fetch).toHaveBeenCalledTimes(2);
+      expect(global.fetch).toHaveBeenLastCalledWith(
+        "http://localhost:8080/api/bugs?cursor=2"
+      );
+      expect(result.map((bug) => bug.id)).toEqual([1, 2, 3]);
+    });
+
     it("should handle network errors when fetching bugs", async () => {
       global.fetch = jest.fn().mockRejectedValue(new Error("Network error"));
 
@@ -47,6 +70,7 @@ describe("Bug API", () => {
     it("should handle invalid JSON responses when fetching bugs", async () => {
       (global.fetch as jest.Mock).mockResolvedValueOnce({
         ok: true,
+        headers: { get: () => null },
         json: async () => {
           throw new Error("Invalid JSON");
         },
@@ -88,6 +112,31 @@ describe("Bug API", () => {
       expect(result).toEqual(mockResponse);
     });
 
+    it("should send the session token when logged in", async () => {
+      saveSession({
+        token: "secret",
+        expires_at: new Date(Date.now() + 60000).toISOString(),
+        user: { id: 1, username: "alice", role: "reporter" },
+      });
+      (global.fetch as jest.Mock).mockResolvedValueOnce({
+        ok: true,
+        json: async () => ({ id: 1, ...mockBug }),
+      });
+
+      await createBug(mockBug);
+      expect(global.fetch).toHaveBeenCalledWith(
+        "http://localhost:8080/api/bugs",
+        {
+          method: "POST",
+          headers: {
+            "Content-Type": "application/json",
+            Authorization: "Bearer secret",
+          },
+          body: JSON.stringify(mockBug),
+        }
+      );
+    });
+
     it("should handle API errors when creating a bug", async () => {
       (global.fetch as jest.Mock).mockResolvedValueOnce({
         ok: false,
@@ -183,6 +232,7 @@ describe("Bug API", () => {
         "http://localhost:8080/api/bugs/1",
         {
           method: "DELETE",
+          headers: {},
         }
       );
     });
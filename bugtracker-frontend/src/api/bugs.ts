@@ -1,4 +1,5 @@
 import { Bug } from "../types/bug";
+import { authHeaders } from "./auth";
 
 const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8080";
 const API_PATH = "/api";
@@ -13,18 +14,26 @@ export const getBugs = async (): Promise<Bug[]> => {
       fullUrl: url,
     });
 
-    const response = await fetch(url);
-    console.log("API Response:", {
-      status: response.status,
-      ok: response.ok,
-      statusText: response.statusText,
-    });
+    // The API returns bugs a page at a time; follow X-Next-Cursor to the end.
+    const bugs: Bug[] = [];
+    let cursor: string | null = null;
+    do {
+      const response: Response = await fetch(
+        cursor ? `${url}?cursor=${encodeURIComponent(cursor)}` : url
+      );
+      console.log("API Response:", {
+        status: response.status,
+        ok: response.ok,
+        statusText: response.statusText,
+      });
 
-    if (!response.ok) {
-      throw new Error("Failed to fetch bugs");
-    }
-    const data = await response.json();
-    return data;
+      if (!response.ok) {
+        throw new Error("Failed to fetch bugs");
+      }
+      bugs.push(...(await response.json()));
+      cursor = response.headers.get("X-Next-Cursor");
+    } while (cursor);
+    return bugs;
   } catch (error: unknown) {
     console.error("Detailed error fetching bugs:", {
       error,
@@ -41,6 +50,7 @@ export const createBug = async (bugData: Omit<Bug, "id">) => {
       method: "POST",
       headers: {
         "Content-Type": "application/json",
+        ...authHeaders(),
       },
       body: JSON.stringify(bugData),
     });
@@ -60,6 +70,7 @@ export const updateBug = async (id: string, bugData: Partial<Bug>) => {
       method: "PUT",
       headers: {
         "Content-Type": "application/json",
+        ...authHeaders(),
       },
       body: JSON.stringify(bugData),
     });
@@ -77,6 +88,7 @@ export const deleteBug = async (id: string) => {
   try {
     const response = await fetch(`${API_BASE_URL}${API_PATH}/bugs/${id}`, {
       method: "DELETE",
+      headers: authHeaders(),
     });
     if (!response.ok) {
       throw new Error("Failed to delete bug");
@@ -9,6 +9,7 @@ import userEvent from "@testing-library/user-event";
 import { useRouter } from "next/router";
 import BugList from "./BugList";
 import { getBugs, createBug, updateBug, deleteBug } from "../api/bugs";
+import { getSession, logout } from "../api/auth";
 import { APP_VERSION } from "../config/app";
 
 jest.mock("next/router", () => ({
@@ -16,6 +17,7 @@ jest.mock("next/router", () => ({
 }));
 
 jest.mock("../api/bugs");
+jest.mock("../api/auth");
 
 jest.mock("next/link", () => ({
   __esModule: true,
@@ -255,4 +257,31 @@ describe("BugList", () => {
 
     expect(header).toContainElement(versionElement);
   });
+
+  describe("Session", () => {
+    it("links to the login page when nobody is logged in", async () => {
+      (getSession as jest.Mock).mockReturnValue(null);
+      await renderAndWaitForData();
+
+      expect(screen.getByText("Log In")).toHaveAttribute("href", "/login");
+    });
+
+    it("shows the logged in user and logs them out", async () => {
+      (getSession as jest.Mock).mockReturnValue({
+        token: "secret",
+        expires_at: "2030-01-01T00:00:00Z",
+        user: { id: 1, username: "alice", role: "reporter" },
+      });
+      (logout as jest.Mock).mockResolvedValue(undefined);
+      await renderAndWaitForData();
+
+      expect(screen.getByText("alice")).toBeInTheDocument();
+      fireEvent.click(screen.getByText("Log Out"));
+
+      await waitFor(() => {
+        expect(logout).toHaveBeenCalled();
+        expect(screen.getByText("Log In")).toBeInTheDocument();
+      });
+    });
+  });
 });
@@ -1,5 +1,6 @@
 import React, { useEffect, useState } from "react";
 import { getBugs, createBug, updateBug, deleteBug } from "../api/bugs";
+import { getSession, logout, Session } from "../api/auth";
 import AddBugModal from "./AddBugModal";
 import { Bug } from "../types/bug";
 import Link from "next/link";
@@ -20,6 +21,11 @@ export default function BugList() {
   const [selectedBug, setSelectedBug] = useState<Bug | null>(null);
   const [isDeleteModalOpen, setIsDeleteModalOpen] = useState(false);
   const [bugToDelete, setBugToDelete] = useState<Bug | null>(null);
+  const [session, setSession] = useState<Session | null>(null);
+
+  useEffect(() => {
+    setSession(getSession());
+  }, []);
 
   useEffect(() => {
     const fetchBugs = async () => {
@@ -55,6 +61,11 @@ export default function BugList() {
     }
   };
 
+  const handleLogout = async () => {
+    await logout();
+    setSession(null);
+  };
+
   const handleEditClick = (bug: Bug) => {
     setSelectedBug(bug);
     setIsEditModalOpen(true);
@@ -119,7 +130,24 @@ export default function BugList() {
               Bug Tracker Pro
             </h1>
           </div>
-          <span className="text-gray-600">v{APP_VERSION}</span>
+          <div className="flex items-center gap-4">
+            {session ? (
+              <>
+                <span className="text-gray-600">{session.user.username}</span>
+                <button
+                  onClick={handleLogout}
+                  className="text-blue-500 hover:text-blue-700"
+                >
+                  Log Out
+                </button>
+              </>
+            ) : (
+              <Link href="/login" className="text-blue-500 hover:text-blue-700">
+                Log In
+              </Link>
+            )}
+            <span className="text-gray-600">v{APP_VERSION}</span>
+          </div>
         </div>
       </nav>
 
@@ -43,7 +43,6 @@ describe("CommentSection", () => {
       <CommentSection bugId={1} comments={[]} onCommentAdded={onCommentAdded} />
     );
 
-    await userEvent.type(screen.getByLabelText("Your Name"), "Jane Smith");
     await userEvent.type(
       screen.getByLabelText("Comment"),
       "This is a new comment"
@@ -59,7 +58,6 @@ describe("CommentSection", () => {
             "Content-Type": "application/json",
           },
           body: JSON.stringify({
-            author: "Jane Smith",
             content: "This is a new comment",
           }),
         }
@@ -81,7 +79,6 @@ describe("CommentSection", () => {
       <CommentSection bugId={1} comments={[]} onCommentAdded={jest.fn()} />
     );
 
-    await userEvent.type(screen.getByLabelText("Your Name"), "Jane Smith");
     await userEvent.type(
       screen.getByLabelText("Comment"),
       "This is a new comment"
@@ -102,7 +99,14 @@ describe("CommentSection", () => {
     expect(screen.getByText("No comments yet.")).toBeInTheDocument();
   });
 
-  it("should disable the submit button when form fields are empty", () => {
+  it("should not ask for the author's name", () => {
+    render(
+      <CommentSection bugId={1} comments={[]} onCommentAdded={jest.fn()} />
+    );
+    expect(screen.queryByLabelText("Your Name")).not.toBeInTheDocument();
+  });
+
+  it("should disable the submit button when the comment is empty", () => {
     render(
       <CommentSection bugId={1} comments={[]} onCommentAdded={jest.fn()} />
     );
@@ -1,6 +1,7 @@
 import { useState } from "react";
 import { Comment } from "@/types/comment";
 import { API_BASE_URL } from "@/config";
+import { authHeaders } from "@/api/auth";
 
 interface CommentSectionProps {
   bugId: number;
@@ -13,7 +14,6 @@ export default function CommentSection({
   comments = [],
   onCommentAdded,
 }: CommentSectionProps) {
-  const [author, setAuthor] = useState("");
   const [content, setContent] = useState("");
   const [isSubmitting, setIsSubmitting] = useState(false);
 
@@ -28,8 +28,9 @@ export default function CommentSection({
           method: "POST",
           headers: {
             "Content-Type": "application/json",
+            ...authHeaders(),
           },
-          body: JSON.stringify({ author, content }),
+          body: JSON.stringify({ content }),
         }
       );
 
@@ -37,7 +38,6 @@ export default function CommentSection({
         throw new Error("Failed to add comment");
       }
 
-      setAuthor("");
       setContent("");
       onCommentAdded();
     } catch (error) {
@@ -57,23 +57,6 @@ export default function CommentSection({
           onSubmit={handleSubmit}
           className="space-y-4"
         >
-          <div>
-            <label
-              htmlFor="author"
-              className="block text-sm font-medium text-gray-700"
-            >
-              Your Name
-            </label>
-            <input
-              data-testid="comment-author"
-              type="text"
-              id="author"
-              value={author}
-              onChange={(e) => setAuthor(e.target.value)}
-              className="mt-1 block w-full rounded-md border-gray-300 shadow-sm focus:border-blue-500 focus:ring-blue-500"
-              required
-            />
-          </div>
           <div>
             <label
               htmlFor="content"
@@ -93,7 +76,7 @@ export default function CommentSection({
           </div>
           <button
             type="submit"
-            disabled={isSubmitting || !author || !content}
+            disabled={isSubmitting || !content}
             className="bg-blue-500 text-white px-4 py-2 rounded hover:bg-blue-600 disabled:opacity-50"
           >
             {isSubmitting ? "Adding..." : "Add Comment"}
@@ -81,4 +81,19 @@ describe('EditBugModal', () => {
         fireEvent.change(statusSelect, { target: { value: 'In Progress' } });
         expect(screen.getByDisplayValue('In Progress')).toBeInTheDocument();
     });
+
+    test('asks for a resolution when the status is Resolved', () => {
+        render(<EditBugModal {...mockProps} />);
+        expect(screen.queryByText('Resolution')).not.toBeInTheDocument();
+
+        fireEvent.change(screen.getByDisplayValue('Open'), { target: { value: 'Resolved' } });
+        const resolutionSelect = screen.getByDisplayValue('Select a resolution');
+        fireEvent.change(resolutionSelect, { target: { value: 'Duplicate' } });
+
+        fireEvent.submit(screen.getByTestId('edit-bug-form'));
+        expect(mockProps.onSubmit).toHaveBeenCalledWith(mockBug.id, expect.objectContaining({
+            status: 'Resolved',
+            resolution: 'Duplicate'
+        }));
+    });
 });
@@ -1,5 +1,13 @@
 import { useState, useEffect } from "react";
-import { Bug, Priority } from "@/types/bug";
+import { Bug, Priority, Resolution } from "@/types/bug";
+
+const RESOLUTIONS: Resolution[] = [
+  "Fixed",
+  "Won't Fix",
+  "Duplicate",
+  "Cannot Reproduce",
+  "Works As Designed",
+];
 
 interface EditBugModalProps {
   isOpen: boolean;
@@ -28,6 +36,7 @@ export default function EditBugModal({
         description: bug.description,
         priority: bug.priority,
         status: bug.status,
+        resolution: bug.resolution,
       });
     }
   }, [bug]);
@@ -42,7 +51,12 @@ export default function EditBugModal({
           data-testid="edit-bug-form"
           onSubmit={(e) => {
             e.preventDefault();
-            onSubmit(bug.id, formData);
+            // The API only accepts a resolution for resolved bugs.
+            const { resolution, ...rest } = formData;
+            onSubmit(
+              bug.id,
+              formData.status === "Resolved" ? { ...rest, resolution } : rest
+            );
             onClose();
           }}
         >
@@ -98,6 +112,35 @@ export default function EditBugModal({
             </select>
           </div>
 
+          {formData.status === "Resolved" && (
+            <div className="mb-4">
+              <label className="block text-gray-700 text-sm font-bold mb-2">
+                Resolution
+              </label>
+              <select
+                name="resolution"
+                className="shadow border rounded w-full py-2 px-3 text-gray-700"
+                value={formData.resolution ?? ""}
+                onChange={(e) =>
+                  setFormData({
+                    ...formData,
+                    resolution: e.target.value as Resolution,
+                  })
+                }
+                required
+              >
+                <option value="" disabled>
+                  Select a resolution
+                </option>
+                {RESOLUTIONS.map((resolution) => (
+                  <option key={resolution} value={resolution}>
+                    {resolution}
+                  </option>
+                ))}
+              </select>
+            </div>
+          )}
+
           <div className="mb-4">
             <label className="block text-gray-700 text-sm font-bold mb-2">
               Priority
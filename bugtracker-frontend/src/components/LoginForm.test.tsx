@@ -0,0 +1,55 @@
+import { render, screen, fireEvent, waitFor } from "@testing-library/react";
+import userEvent from "@testing-library/user-event";
+import LoginForm from "./LoginForm";
+import { login } from "@/api/auth";
+
+jest.mock("@/api/auth");
+
+describe("LoginForm", () => {
+  const session = {
+    token: "secret",
+    expires_at: "2030-01-01T00:00:00Z",
+    user: { id: 1, username: "alice", role: "reporter" },
+  };
+
+  beforeEach(() => {
+    jest.clearAllMocks();
+  });
+
+  it("should log in with the entered credentials", async () => {
+    (login as jest.Mock).mockResolvedValueOnce(session);
+    const onLoggedIn = jest.fn();
+    render(<LoginForm onLoggedIn={onLoggedIn} />);
+
+    await userEvent.type(screen.getByLabelText("Username"), "alice");
+    await userEvent.type(screen.getByLabelText("Password"), "correct horse");
+    fireEvent.click(screen.getByRole("button", { name: "Log In" }));
+
+    await waitFor(() => {
+      expect(login).toHaveBeenCalledWith("alice", "correct horse");
+      expect(onLoggedIn).toHaveBeenCalledWith(session);
+    });
+  });
+
+  it("should show why logging in failed", async () => {
+    (login as jest.Mock).mockRejectedValueOnce(
+      new Error("Wrong username or password")
+    );
+    const onLoggedIn = jest.fn();
+    render(<LoginForm onLoggedIn={onLoggedIn} />);
+
+    await userEvent.type(screen.getByLabelText("Username"), "alice");
+    await userEvent.type(screen.getByLabelText("Password"), "wrong");
+    fireEvent.click(screen.getByRole("button", { name: "Log In" }));
+
+    expect(await screen.findByRole("alert")).toHaveTextContent(
+      "Wrong username or password"
+    );
+    expect(onLoggedIn).not.toHaveBeenCalled();
+  });
+
+  it("should disable the submit button until both fields are filled", () => {
+    render(<LoginForm onLoggedIn={jest.fn()} />);
+    expect(screen.getByRole("button", { name: "Log In" })).toBeDisabled();
+  });
+});
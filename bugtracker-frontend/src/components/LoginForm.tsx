@@ -0,0 +1,89 @@
+import { useState } from "react";
+import { login, Session } from "@/api/auth";
+
+interface LoginFormProps {
+  onLoggedIn: (session: Session) => void;
+}
+
+export default function LoginForm({ onLoggedIn }: LoginFormProps) {
+  const [username, setUsername] = useState("");
+  const [password, setPassword] = useState("");
+  const [error, setError] = useState<string | null>(null);
+  const [isSubmitting, setIsSubmitting] = useState(false);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setIsSubmitting(true);
+    setError(null);
+
+    try {
+      const session = await login(username, password);
+      onLoggedIn(session);
+    } catch (error) {
+      setError((error as Error).message);
+      setPassword("");
+    } finally {
+      setIsSubmitting(false);
+    }
+  };
+
+  return (
+    <form
+      data-testid="login-form"
+      onSubmit={handleSubmit}
+      className="bg-white rounded-lg shadow-md p-6 w-full max-w-md"
+    >
+      <h2 className="text-xl font-bold mb-4">Log In</h2>
+      {error && (
+        <p role="alert" className="mb-4 text-red-500">
+          {error}
+        </p>
+      )}
+      <div className="mb-4">
+        <label
+          htmlFor="username"
+          className="block text-gray-700 text-sm font-bold mb-2"
+        >
+          Username
+        </label>
+        <input
+          id="username"
+          name="username"
+          type="text"
+          autoComplete="username"
+          className="shadow appearance-none border rounded w-full py-2 px-3 text-gray-700"
+          value={username}
+          onChange={(e) => setUsername(e.target.value)}
+          required
+        />
+      </div>
+      <div className="mb-4">
+        <label
+          htmlFor="password"
+          className="block text-gray-700 text-sm font-bold mb-2"
+        >
+          Password
+        </label>
+        <input
+          id="password"
+          name="password"
+          type="password"
+          autoComplete="current-password"
+          className="shadow appearance-none border rounded w-full py-2 px-3 text-gray-700"
+          value={password}
+          onChange={(e) => setPassword(e.target.value)}
+          required
+        />
+      </div>
+      <div className="flex justify-end">
+        <button
+          type="submit"
+          disabled={isSubmitting || !username || !password}
+          className="bg-blue-500 hover:bg-blue-600 text-white px-4 py-2 rounded disabled:opacity-50"
+        >
+          {isSubmitting ? "Logging in..." : "Log In"}
+        </button>
+      </div>
+    </form>
+  );
+}
@@ -22,11 +22,20 @@ export default function BugDetail() {
 
   const fetchComments = useCallback(async () => {
     try {
-      const response = await fetch(`${API_BASE_URL}/api/bugs/${id}/comments`);
-      if (!response.ok) {
-        throw new Error("Failed to fetch comments");
-      }
-      const data = await response.json();
+      // Comments come a page at a time; follow X-Next-Cursor to the end.
+      const url = `${API_BASE_URL}/api/bugs/${id}/comments`;
+      const data: Comment[] = [];
+      let cursor: string | null = null;
+      do {
+        const response: Response = await fetch(
+          cursor ? `${url}?cursor=${encodeURIComponent(cursor)}` : url
+        );
+        if (!response.ok) {
+          throw new Error("Failed to fetch comments");
+        }
+        data.push(...(await response.json()));
+        cursor = response.headers.get("X-Next-Cursor");
+      } while (cursor);
       setComments(data);
     } catch (error) {
       console.error("Error fetching comments:", error);
@@ -0,0 +1,23 @@
+import Link from "next/link";
+import { useRouter } from "next/router";
+import LoginForm from "@/components/LoginForm";
+
+export default function Login() {
+  const router = useRouter();
+
+  return (
+    <div className="min-h-screen bg-gray-100">
+      <nav className="bg-white shadow-lg">
+        <div className="max-w-7xl mx-auto px-4 py-4">
+          <Link href="/" className="text-blue-500 hover:text-blue-700">
+            ← Back to Bug List
+          </Link>
+        </div>
+      </nav>
+
+      <main className="flex justify-center px-4 py-8">
+        <LoginForm onLoggedIn={() => router.push("/")} />
+      </main>
+    </div>
+  );
+}
@@ -1,11 +1,25 @@
 export type Priority = 'Low' | 'Medium' | 'High';
 
+export type Resolution =
+  | 'Fixed'
+  | "Won't Fix"
+  | 'Duplicate'
+  | 'Cannot Reproduce'
+  | 'Works As Designed';
+
 export interface Bug {
   id: number;
   title: string;
   description: string;
+  description_html?: string;
+  reporter?: string;
   status: 'Open' | 'In Progress' | 'Resolved';
   priority: Priority;
+  resolution?: Resolution;
+  resolved_at?: string;
+  reopen_count?: number;
+  version?: number;
+  comment_count?: number;
 }
 
 export interface BugActions {
@@ -3,10 +3,16 @@ export interface Comment {
     bugId: string;
     author: string;
     content: string;
+    contentHtml?: string;
     createdAt: string;
+    parentId?: number;
+    depth?: number;
+    updatedAt?: string;
+    edited?: boolean;
+    deletedAt?: string;
+    deletedBy?: string;
 }
 
 export interface CreateCommentRequest {
-    author: string;
     content: string;
 } 
\ No newline at end of file
@@ -1,6 +1,14 @@
 import { test, expect } from "@playwright/test";
 
+// Writes need a signed-in user; moving a bug to "In Progress" needs the
+// developer role or higher.
+const username = process.env.API_TEST_USERNAME || "e2e";
+const password = process.env.API_TEST_PASSWORD || "e2e-password";
+
 let testBugId: number;
+let token: string;
+
+const auth = () => ({ Authorization: `Bearer ${token}` });
 
 test("Call Health Check", async ({ request }) => {
   console.log("Starting test...");
@@ -14,6 +22,28 @@ test("Call Health Check", async ({ request }) => {
   );
 });
 
+test("Log in", async ({ request }) => {
+  const response = await request.post("auth/login", {
+    data: { username, password },
+  });
+
+  expect(response.ok()).toBeTruthy();
+  const session = await response.json();
+  token = session.token;
+
+  expect(session).toMatchObject({
+    token: expect.any(String),
+    user: { username },
+  });
+});
+
+test("Writing without logging in is refused", async ({ request }) => {
+  const response = await request.post("bugs", {
+    data: { title: `Anonymous Bug ${Date.now()}` },
+  });
+  expect(response.status()).toBe(401);
+});
+
 test("Create a bug", async ({ request }) => {
   const timestamp = Date.now();
   const newBug = {
@@ -25,6 +55,7 @@ test("Create a bug", async ({ request }) => {
 
   const response = await request.post("bugs", {
     data: newBug,
+    headers: auth(),
   });
 
   expect(response.ok()).toBeTruthy();
@@ -38,6 +69,7 @@ test("Create a bug", async ({ request }) => {
     description: newBug.description,
     status: newBug.status,
     priority: newBug.priority,
+    reporter: username,
     created_at: expect.any(String),
     updated_at: expect.any(String),
   });
@@ -54,6 +86,7 @@ test("Update a bug", async ({ request }) => {
 
   const response = await request.put(`bugs/${testBugId}`, {
     data: updatedBug,
+    headers: auth(),
   });
 
   expect(response.ok()).toBeTruthy();
@@ -89,7 +122,9 @@ test("Get a specific bug", async ({ request }) => {
 });
 
 test("Delete a bug", async ({ request }) => {
-  const deleteResponse = await request.delete(`bugs/${testBugId}`);
+  const deleteResponse = await request.delete(`bugs/${testBugId}`, {
+    headers: auth(),
+  });
   expect(deleteResponse.ok()).toBeTruthy();
 
   const getResponse = await request.get(`bugs/${testBugId}`);
@@ -1,5 +1,21 @@
 import { test, expect } from "@playwright/test";
 
+// The user the backend is started with by docker-compose.e2e.yml. Deleting
+// bugs needs the developer role or higher.
+const username = process.env.E2E_USERNAME || "e2e";
+const password = process.env.E2E_PASSWORD || "e2e-password";
+
+test.beforeEach(async ({ page }) => {
+  await page.goto("/login");
+  await page.fill('input[name="username"]', username);
+  await page.fill('input[name="password"]', password);
+  await page.click('button:text("Log In")');
+  await expect(page.locator(`nav >> text=${username}`)).toBeVisible({
+    timeout: 60000,
+  });
+  console.log(`Logged in as ${username}`);
+});
+
 test("Bug creation and deletion flow", async ({ page }) => {
   page.on("console", (msg) => {
     console.log(`Browser console [${msg.type()}]: ${msg.text()}`);
@@ -89,12 +105,14 @@ test("Adding a comment to a bug", async ({ page }) => {
     '[data-testid="comment-content"]',
     `Test comment ${timestamp}`
   );
-  await page.fill('[data-testid="comment-author"]', `Test User ${timestamp}`);
   await page.click('button:text("Add Comment")');
 
   await expect(page.locator(`p:text('Test comment ${timestamp}')`)).toBeVisible(
     { timeout: 10000 }
   );
+  await expect(
+    page.locator(`span:text('${username}')`).first()
+  ).toBeVisible();
 });
 
 test("Editing a bug", async ({ page }) => {
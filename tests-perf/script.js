@@ -12,7 +12,23 @@ export const options = {
   },
 };
 
-export default function () {
+// setup logs in once; creating bugs needs a signed-in user.
+export function setup() {
+  const loginRes = http.post(
+    "http://localhost:8080/api/auth/login",
+    JSON.stringify({
+      username: __ENV.PERF_USERNAME || "e2e",
+      password: __ENV.PERF_PASSWORD || "e2e-password",
+    }),
+    { headers: { "Content-Type": "application/json" } }
+  );
+  check(loginRes, {
+    "login status is 200": (r) => r.status === 200,
+  });
+  return { token: JSON.parse(loginRes.body).token };
+}
+
+export default function (data) {
   // Health check
   const healthRes = http.get("http://localhost:8080/api/health");
   check(healthRes, {
@@ -27,7 +43,10 @@ export default function () {
     status: "Open",
   });
 
-  const headers = { "Content-Type": "application/json" };
+  const headers = {
+    "Content-Type": "application/json",
+    Authorization: `Bearer ${data.token}`,
+  };
 
   const createBugRes = http.post("http://localhost:8080/api/bugs", payload, {
     headers,